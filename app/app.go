@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"medical-system/container"
+	infraauth "medical-system/infrastructure/auth"
+	"medical-system/infrastructure/database"
+	"medical-system/infrastructure/metrics"
+	authmiddleware "medical-system/middleware"
+	"medical-system/routes"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// New assembles the HTTP application on the services of container. It
+// refuses to build on a database whose schema is not current; the caller
+// starts the returned server, or serves it in tests.
+func New(container *container.Container) (*echo.Echo, error) {
+	cfg, err := container.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the configuration: %w", err)
+	}
+
+	// Refuse to serve on a schema the code does not match
+	db, err := container.GetDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the migrations: %w", err)
+	}
+	if err := migrator.CheckCurrent(context.Background()); err != nil {
+		return nil, fmt.Errorf("database schema is not current, run `medical-system migrate up`: %w", err)
+	}
+
+	// Initialize Echo server
+	e := echo.New()
+
+	validator, err := container.GetValidator()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up request validation: %w", err)
+	}
+	e.Validator = validator
+
+	// The client IP keys the login throttle and is recorded in the audit
+	// log, so forwarding headers are only believed from trusted proxies
+	e.IPExtractor = clientIPExtractor(cfg.Server.TrustedProxies)
+
+	// Initialize tenant middleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	if err := container.DigContainer().Invoke(func(tm *authmiddleware.TenantMiddleware) {
+		tenantMiddleware = tm
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get tenant middleware: %w", err)
+	}
+
+	tracer, err := container.GetTracing()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	// Middleware
+	e.Use(middleware.RequestID())
+	e.Use(tracer.Middleware(cfg.Tracing.ServiceName, func(c echo.Context) bool {
+		return operationalPaths[c.Request().URL.Path]
+	}))
+	if cfg.Metrics.Enabled {
+		var m *metrics.Metrics
+		if err := container.DigContainer().Invoke(func(mm *metrics.Metrics) {
+			m = mm
+		}); err != nil {
+			return nil, fmt.Errorf("failed to get metrics: %w", err)
+		}
+		e.Use(m.Middleware())
+		e.GET("/metrics", echo.WrapHandler(m.Handler()))
+	}
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+	e.Use(authmiddleware.Transaction())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{AllowOrigins: cfg.CORS.AllowedOrigins}))
+
+	// Setup routes
+	routes.SetupAuthRoutes(e, container)
+	routes.SetupTenantRoutes(e, container)
+	routes.SetupRoleRoutes(e, container)
+	routes.SetupPatientRoutes(e, container)
+	routes.SetupAppointmentRoutes(e, container)
+	routes.SetupEncounterRoutes(e, container)
+	routes.SetupAuditRoutes(e, container)
+
+	// Initialize auth middleware
+	var authMiddleware *authmiddleware.AuthMiddleware
+	if err := container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware) {
+		authMiddleware = am
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get auth middleware: %w", err)
+	}
+
+	// Protected routes with JWT
+	api := e.Group("/api/protected")
+	api.Use(authMiddleware.JWTMiddleware())
+	api.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+	api.Use(tenantMiddleware.TenantValidator()) // Ensure tenant is valid
+	api.GET("/profile", func(c echo.Context) error {
+		userID := c.Get("user_id").(string)
+		role := c.Get("role").(string)
+		tenantID := c.Get("tenant_id").(string)
+
+		// Get tenant info if available
+		var tenantName string
+		if tenant, ok := authmiddleware.GetTenantFromContext(c); ok {
+			tenantName = tenant.Name
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message":     "Profile accessed successfully with JWT",
+			"user_id":     userID,
+			"role":        role,
+			"tenant_id":   tenantID,
+			"tenant_name": tenantName,
+		})
+	}, authMiddleware.RBACMiddleware("profile", "read"))
+
+	// Liveness and readiness probes
+	var enforcer *infraauth.CasbinEnforcer
+	if err := container.DigContainer().Invoke(func(ce *infraauth.CasbinEnforcer) {
+		enforcer = ce
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get casbin enforcer: %w", err)
+	}
+	registerProbes(e, db, enforcer)
+
+	return e, nil
+}
+
+// clientIPExtractor reads the client IP from X-Forwarded-For behind the
+// trusted proxies, and from the connection when there are none. The private
+// ranges Echo trusts by default are not trusted unless configured.
+func clientIPExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		// Checked by the configuration
+		_, ipRange, _ := net.ParseCIDR(proxy)
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
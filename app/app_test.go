@@ -0,0 +1,1934 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"medical-system/app"
+	"medical-system/container"
+	"medical-system/domain/entities"
+	"medical-system/domain/services"
+	infraauth "medical-system/infrastructure/auth"
+	"medical-system/infrastructure/database"
+	authmiddleware "medical-system/middleware"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+)
+
+const testPassword = "correct-horse-battery"
+
+// newTestApp assembles the application on a throwaway SQLite database with
+// the migrations applied
+func newTestApp(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	e, _ := newTestAppWithContainer(t)
+	return e
+}
+
+// newTestAppWithContainer is newTestApp for tests that also need the
+// services behind the application. The decorators replace services, see
+// dig.Decorate.
+func newTestAppWithContainer(t *testing.T, decorators ...interface{}) (*echo.Echo, *container.Container) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_PATH", filepath.Join(dir, "test.db"))
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("MAIL_OUTBOX_DIR", filepath.Join(dir, "mail"))
+
+	c := container.NewContainer()
+	for _, decorator := range decorators {
+		if err := c.DigContainer().Decorate(decorator); err != nil {
+			t.Fatalf("decorate container: %v", err)
+		}
+	}
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	e, err := app.New(c)
+	if err != nil {
+		t.Fatalf("assemble app: %v", err)
+	}
+	e.Logger.SetOutput(bytes.NewBuffer(nil))
+	return e, c
+}
+
+type response struct {
+	status int
+	body   map[string]interface{}
+	raw    []byte
+}
+
+// call sends a JSON request to e, authenticated with token when set
+func call(t *testing.T, e *echo.Echo, method, path, token string, body interface{}, headers ...string) response {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	res := response{status: rec.Code, raw: rec.Body.Bytes()}
+	_ = json.Unmarshal(res.raw, &res.body)
+	return res
+}
+
+func expectStatus(t *testing.T, res response, status int) {
+	t.Helper()
+	if res.status != status {
+		t.Fatalf("status = %d, want %d: %s", res.status, status, res.raw)
+	}
+}
+
+// registerTenant onboards a clinic through the link mailed to it. Its first
+// admin signs in as <slug>@clinic.test with testPassword.
+func registerTenant(t *testing.T, e *echo.Echo, slug string) string {
+	t.Helper()
+
+	res := call(t, e, http.MethodPost, "/api/tenants/register", "", map[string]string{
+		"name":  "Clinic " + slug,
+		"email": slug + "@clinic.test",
+		"slug":  slug,
+		"plan":  "basic",
+	})
+	expectStatus(t, res, http.StatusAccepted)
+
+	res = completeRegistration(t, e, registrationToken(t, slug+"@clinic.test"), testPassword)
+	expectStatus(t, res, http.StatusCreated)
+	return res.body["id"].(string)
+}
+
+func completeRegistration(t *testing.T, e *echo.Echo, token, password string) response {
+	t.Helper()
+
+	return call(t, e, http.MethodPost, "/api/tenants/register/complete", "", map[string]string{
+		"token":      token,
+		"first_name": "Clinic",
+		"last_name":  "Admin",
+		"password":   password,
+	})
+}
+
+// lastMail reads the last mail sent to email from the outbox
+func lastMail(t *testing.T, email string) string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(os.Getenv("MAIL_OUTBOX_DIR"), "*-"+email+".eml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no mail to %s", email)
+	}
+	sort.Strings(files)
+	content, err := os.ReadFile(files[len(files)-1])
+	if err != nil {
+		t.Fatalf("read mail: %v", err)
+	}
+	return string(content)
+}
+
+// registrationToken reads the token of the last registration link mailed to
+// email from the outbox
+func registrationToken(t *testing.T, email string) string {
+	t.Helper()
+
+	content := lastMail(t, email)
+	match := regexp.MustCompile(`token=([^\s&]+)`).FindStringSubmatch(content)
+	if match == nil {
+		t.Fatalf("no registration link in %s", content)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatalf("decode registration token: %v", err)
+	}
+	return token
+}
+
+// grantSuperAdmin makes the user with the email a platform super admin, as
+// the super-admin command does
+func grantSuperAdmin(t *testing.T, c *container.Container, email string) {
+	t.Helper()
+
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	var userID string
+	if err := db.Raw("SELECT id FROM users WHERE email = ?", email).Scan(&userID).Error; err != nil || userID == "" {
+		t.Fatalf("find user %s: %v", email, err)
+	}
+	if err := c.DigContainer().Invoke(func(enforcer *infraauth.CasbinEnforcer) error {
+		return enforcer.GrantSuperAdmin(userID)
+	}); err != nil {
+		t.Fatalf("grant super admin: %v", err)
+	}
+}
+
+func registerUser(t *testing.T, e *echo.Echo, tenantID, email, role string) response {
+	t.Helper()
+
+	return call(t, e, http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email":      email,
+		"password":   testPassword,
+		"tenant_id":  tenantID,
+		"role":       role,
+		"first_name": "Test",
+		"last_name":  "User",
+	})
+}
+
+// login returns the access token of a newly registered user
+func login(t *testing.T, e *echo.Echo, tenantID, email, role string) string {
+	t.Helper()
+
+	expectStatus(t, registerUser(t, e, tenantID, email, role), http.StatusCreated)
+	return signIn(t, e, tenantID, email)
+}
+
+// loginAdmin returns the access token of the admin who registered the
+// tenant, see registerTenant
+func loginAdmin(t *testing.T, e *echo.Echo, tenantID, slug string) string {
+	t.Helper()
+
+	return signIn(t, e, tenantID, slug+"@clinic.test")
+}
+
+func signIn(t *testing.T, e *echo.Echo, tenantID, email string) string {
+	t.Helper()
+
+	res := call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     email,
+		"password":  testPassword,
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusOK)
+	return res.body["access_token"].(string)
+}
+
+func TestTenantRegistration(t *testing.T) {
+	e := newTestApp(t)
+
+	tenantID := registerTenant(t, e, "north")
+	if tenantID == "" {
+		t.Fatal("registration returned no tenant ID")
+	}
+
+	res := call(t, e, http.MethodPost, "/api/tenants/register", "", map[string]string{
+		"name":  "Another clinic",
+		"email": "another@clinic.test",
+		"slug":  "north",
+		"plan":  "basic",
+	})
+	expectStatus(t, res, http.StatusBadRequest)
+}
+
+func TestTenantOnboarding(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("get database: %v", err)
+	}
+
+	res := call(t, e, http.MethodPost, "/api/tenants/register", "", map[string]string{
+		"name":  "Clinic north",
+		"email": "north@clinic.test",
+		"slug":  "North Clinic",
+		"plan":  "basic",
+	})
+	expectStatus(t, res, http.StatusBadRequest)
+
+	res = call(t, e, http.MethodPost, "/api/tenants/register", "", map[string]string{
+		"name":  "Clinic north",
+		"email": "north@clinic.test",
+		"slug":  "north",
+		"plan":  "basic",
+	})
+	expectStatus(t, res, http.StatusAccepted)
+	token := registrationToken(t, "north@clinic.test")
+
+	// Nothing is stored before the email is verified
+	var tenants int64
+	db.Raw("SELECT COUNT(*) FROM tenants").Scan(&tenants)
+	if tenants != 0 {
+		t.Fatalf("%d tenants stored before verification", tenants)
+	}
+
+	res = call(t, e, http.MethodGet, "/api/tenants/register/verify?token="+url.QueryEscape(token), "", nil)
+	expectStatus(t, res, http.StatusOK)
+	if res.body["slug"] != "north" || res.body["email"] != "north@clinic.test" {
+		t.Errorf("registration = %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodGet, "/api/tenants/register/verify?token="+url.QueryEscape(token+"x"), "", nil), http.StatusBadRequest)
+	expectStatus(t, completeRegistration(t, e, token+"x", testPassword), http.StatusBadRequest)
+	expectStatus(t, completeRegistration(t, e, token, "short"), http.StatusBadRequest)
+
+	// A failure past the policies rolls the tenant and its policies back
+	var policies int64
+	db.Raw("SELECT COUNT(*) FROM casbin_rule").Scan(&policies)
+	failUsers := func(tx *gorm.DB) {
+		if tx.Statement.Table == "users" {
+			tx.AddError(errors.New("users are unavailable"))
+		}
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_users", failUsers); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	expectStatus(t, completeRegistration(t, e, token, testPassword), http.StatusBadRequest)
+	db.Callback().Create().Remove("test:fail_users")
+
+	var after int64
+	db.Raw("SELECT COUNT(*) FROM tenants").Scan(&tenants)
+	db.Raw("SELECT COUNT(*) FROM casbin_rule").Scan(&after)
+	if tenants != 0 || after != policies {
+		t.Fatalf("failed registration left %d tenants and %d policies, had %d", tenants, after, policies)
+	}
+
+	res = completeRegistration(t, e, token, testPassword)
+	expectStatus(t, res, http.StatusCreated)
+	tenantID := res.body["id"].(string)
+	if admin, _ := res.body["admin"].(map[string]interface{}); admin["email"] != "north@clinic.test" || admin["role"] != "admin" {
+		t.Errorf("admin = %v", res.body["admin"])
+	}
+
+	// A link creates a single tenant
+	expectStatus(t, completeRegistration(t, e, token, testPassword), http.StatusBadRequest)
+
+	// The first admin holds the admin role of the tenant at once
+	adminToken := loginAdmin(t, e, tenantID, "north")
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients", adminToken, nil), http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/tenant-admin/roles", adminToken, nil), http.StatusOK)
+
+	// Access tokens are no registration links
+	expectStatus(t, completeRegistration(t, e, adminToken, testPassword), http.StatusBadRequest)
+}
+
+func TestRequestValidation(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	tenantID := registerTenant(t, e, "north")
+	adminToken := loginAdmin(t, e, tenantID, "north")
+	grantSuperAdmin(t, c, "north@clinic.test")
+
+	// Public registration cannot grant privileged roles
+	res := registerUser(t, e, tenantID, "intruder@north.test", "admin")
+	expectStatus(t, res, http.StatusBadRequest)
+	if fields, _ := res.body["fields"].(map[string]interface{}); fields["role"] != "role must be one of [user]" {
+		t.Errorf("fields = %s", res.raw)
+	}
+
+	res = call(t, e, http.MethodPost, "/api/tenants/register", "", map[string]string{
+		"name":  "Clinic south",
+		"email": "not-an-email",
+		"slug":  "south",
+		"plan":  "platinum",
+	})
+	expectStatus(t, res, http.StatusBadRequest)
+	if fields, _ := res.body["fields"].(map[string]interface{}); fields["email"] != "email must be a valid email address" || fields["plan"] == nil {
+		t.Errorf("fields = %s", res.raw)
+	}
+
+	// Messages follow the language of the tenant
+	settings := map[string]interface{}{
+		"allow_user_registration": true,
+		"max_users":               5,
+		"timezone":                "Europe/Madrid",
+		"language":                "xx",
+	}
+	expectStatus(t, call(t, e, http.MethodPut, "/api/admin/tenants/"+tenantID+"/settings", adminToken, settings), http.StatusBadRequest)
+	settings["language"] = "es"
+	expectStatus(t, call(t, e, http.MethodPut, "/api/admin/tenants/"+tenantID+"/settings", adminToken, settings), http.StatusOK)
+
+	res = call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "doctor",
+		"password":  testPassword,
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusBadRequest)
+	if fields, _ := res.body["fields"].(map[string]interface{}); fields["email"] != "email debe ser una dirección de correo electrónico válida" {
+		t.Errorf("fields = %s", res.raw)
+	}
+}
+
+func TestUserRegistrationLimit(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+
+	// The basic plan allows five users, the admin who registered the
+	// tenant is the first
+	for i := 2; i <= 5; i++ {
+		expectStatus(t, registerUser(t, e, tenantID, fmt.Sprintf("user%d@north.test", i), "user"), http.StatusCreated)
+	}
+	res := registerUser(t, e, tenantID, "user6@north.test", "user")
+	expectStatus(t, res, http.StatusBadRequest)
+	if res.body["error"] != "tenant has reached maximum user limit" {
+		t.Errorf("error = %v, want the user limit", res.body["error"])
+	}
+
+	// Other tenants have limits of their own
+	otherID := registerTenant(t, e, "south")
+	expectStatus(t, registerUser(t, e, otherID, "user6@north.test", "user"), http.StatusCreated)
+}
+
+func TestLoginAndProfile(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	expectStatus(t, registerUser(t, e, tenantID, "doctor@north.test", "user"), http.StatusCreated)
+
+	res := call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "doctor@north.test",
+		"password":  "wrong-password-entirely",
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusUnauthorized)
+
+	res = call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "doctor@north.test",
+		"password":  testPassword,
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusOK)
+	token, _ := res.body["access_token"].(string)
+	if token == "" {
+		t.Fatalf("login returned no access token: %s", res.raw)
+	}
+
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", "", nil), http.StatusUnauthorized)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", "not-a-token", nil), http.StatusUnauthorized)
+
+	res = call(t, e, http.MethodGet, "/api/protected/profile", token, nil)
+	expectStatus(t, res, http.StatusOK)
+	if res.body["tenant_id"] != tenantID || res.body["tenant_name"] != "Clinic north" || res.body["role"] != "user" {
+		t.Errorf("profile = %s", res.raw)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	tenantID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	expectStatus(t, registerUser(t, e, tenantID, "doctor@north.test", "user"), http.StatusCreated)
+
+	res := call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "doctor@north.test",
+		"password":  testPassword,
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusOK)
+	first := res.body["refresh_token"].(string)
+
+	res = call(t, e, http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": first})
+	expectStatus(t, res, http.StatusOK)
+	second, _ := res.body["refresh_token"].(string)
+	accessToken, _ := res.body["access_token"].(string)
+	if second == "" || second == first || accessToken == "" {
+		t.Fatalf("refresh did not rotate the token: %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", accessToken, nil), http.StatusOK)
+
+	// The token names its tenant, the session is looked up there alone
+	if !strings.HasPrefix(second, tenantID+".") {
+		t.Errorf("refresh token %q does not name tenant %s", second, tenantID)
+	}
+	moved := southID + strings.TrimPrefix(second, tenantID)
+	expectStatus(t, call(t, e, http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": moved}), http.StatusUnauthorized)
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	var scopes int64
+	db.Raw("SELECT COUNT(*) FROM audit_events WHERE action = 'system.scope'").Scan(&scopes)
+	if scopes != 0 {
+		t.Errorf("refreshing left the tenant %d times", scopes)
+	}
+
+	// Replaying the rotated token revokes the session, current token included
+	expectStatus(t, call(t, e, http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": first}), http.StatusUnauthorized)
+	expectStatus(t, call(t, e, http.MethodPost, "/api/auth/refresh", "", map[string]string{"refresh_token": second}), http.StatusUnauthorized)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", accessToken, nil), http.StatusUnauthorized)
+}
+
+func TestPasswordReset(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	accessToken := login(t, e, tenantID, "doctor@north.test", "user")
+
+	res := call(t, e, http.MethodPost, "/api/auth/password/forgot", "", map[string]string{
+		"email":     "doctor@north.test",
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusAccepted)
+	match := regexp.MustCompile(`new password: (\S+)`).FindStringSubmatch(lastMail(t, "doctor@north.test"))
+	if match == nil {
+		t.Fatal("no reset token in the mail")
+	}
+	token := match[1]
+
+	// A rejected password leaves the token usable
+	res = call(t, e, http.MethodPost, "/api/auth/password/reset", "", map[string]string{"token": token, "new_password": "short"})
+	expectStatus(t, res, http.StatusBadRequest)
+
+	newPassword := "another-horse-battery"
+	res = call(t, e, http.MethodPost, "/api/auth/password/reset", "", map[string]string{"token": token, "new_password": newPassword})
+	expectStatus(t, res, http.StatusOK)
+	res = call(t, e, http.MethodPost, "/api/auth/password/reset", "", map[string]string{"token": token, "new_password": "yet-another-password"})
+	expectStatus(t, res, http.StatusBadRequest)
+
+	// The reset signs the user out everywhere
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", accessToken, nil), http.StatusUnauthorized)
+
+	for password, status := range map[string]int{
+		testPassword:           http.StatusUnauthorized,
+		"yet-another-password": http.StatusUnauthorized,
+		newPassword:            http.StatusOK,
+	} {
+		res = call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email":     "doctor@north.test",
+			"password":  password,
+			"tenant_id": tenantID,
+		})
+		expectStatus(t, res, status)
+	}
+}
+
+func TestPasswordResetThrottle(t *testing.T) {
+	// Unknown accounts are throttled alike, refusals reveal nothing
+	for _, email := range []string{"doctor@north.test", "nobody@north.test"} {
+		t.Run(email, func(t *testing.T) {
+			e := newTestApp(t)
+			tenantID := registerTenant(t, e, "north")
+			expectStatus(t, registerUser(t, e, tenantID, "doctor@north.test", "user"), http.StatusCreated)
+			forgot := func() response {
+				return call(t, e, http.MethodPost, "/api/auth/password/forgot", "", map[string]string{
+					"email":     email,
+					"tenant_id": tenantID,
+				})
+			}
+
+			for i := 0; i < 3; i++ {
+				expectStatus(t, forgot(), http.StatusAccepted)
+			}
+			res := forgot()
+			expectStatus(t, res, http.StatusTooManyRequests)
+			if strings.Contains(string(res.raw), "locked") {
+				t.Errorf("refusal mentions a lockout: %s", res.raw)
+			}
+
+			// Logins are counted apart
+			signIn(t, e, tenantID, "doctor@north.test")
+		})
+	}
+}
+
+func TestChangePasswordKeepsCurrentSession(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	current := login(t, e, tenantID, "doctor@north.test", "user")
+	other := signIn(t, e, tenantID, "doctor@north.test")
+
+	res := call(t, e, http.MethodPut, "/api/protected/password", current, map[string]string{
+		"current_password": testPassword,
+		"new_password":     "another-horse-battery",
+	})
+	expectStatus(t, res, http.StatusOK)
+
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", current, nil), http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", other, nil), http.StatusUnauthorized)
+}
+
+// totpCode is the code an authenticator app shows for secret at the given
+// time, see RFC 6238
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode TOTP secret: %v", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff%1000000)
+}
+
+func TestMFALogin(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("get database: %v", err)
+	}
+	tenantID := registerTenant(t, e, "north")
+	token := login(t, e, tenantID, "doctor@north.test", "user")
+
+	res := call(t, e, http.MethodPost, "/api/protected/mfa/enroll", token, nil)
+	expectStatus(t, res, http.StatusOK)
+	secret := res.body["secret"].(string)
+
+	var stored string
+	db.Raw("SELECT mfa_secret FROM users WHERE email = 'doctor@north.test'").Scan(&stored)
+	if stored == secret || !strings.HasPrefix(stored, "v1:") {
+		t.Errorf("the TOTP secret is stored unencrypted: %q", stored)
+	}
+
+	now := time.Now()
+	res = call(t, e, http.MethodPost, "/api/protected/mfa/activate", token, map[string]string{"code": totpCode(t, secret, now)})
+	expectStatus(t, res, http.StatusOK)
+	recoveryCodes := res.body["recovery_codes"].([]interface{})
+
+	challenge := func() string {
+		res := call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email":     "doctor@north.test",
+			"password":  testPassword,
+			"tenant_id": tenantID,
+		})
+		expectStatus(t, res, http.StatusOK)
+		if res.body["mfa_required"] != true {
+			t.Fatalf("login without second factor: %s", res.raw)
+		}
+		return res.body["mfa_token"].(string)
+	}
+	verify := func(mfaToken string, fields ...string) response {
+		body := map[string]string{"mfa_token": mfaToken}
+		for i := 0; i+1 < len(fields); i += 2 {
+			body[fields[i]] = fields[i+1]
+		}
+		return call(t, e, http.MethodPost, "/api/auth/mfa/verify", "", body)
+	}
+
+	// The code used for the activation cannot be used again
+	mfaToken := challenge()
+	expectStatus(t, verify(mfaToken, "code", totpCode(t, secret, now)), http.StatusUnauthorized)
+	expectStatus(t, verify(mfaToken, "code", totpCode(t, secret, now.Add(30*time.Second))), http.StatusOK)
+
+	// Nor can the challenge that completed a login
+	expectStatus(t, verify(mfaToken, "recovery_code", recoveryCodes[0].(string)), http.StatusUnauthorized)
+	expectStatus(t, verify(challenge(), "recovery_code", recoveryCodes[1].(string)), http.StatusOK)
+}
+
+func TestLoginThrottle(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("get database: %v", err)
+	}
+	tenantID := registerTenant(t, e, "north")
+	expectStatus(t, registerUser(t, e, tenantID, "doctor@north.test", "user"), http.StatusCreated)
+
+	// Clients cannot choose the IP the throttle and the audit log see
+	res := call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "nurse@north.test",
+		"password":  testPassword,
+		"tenant_id": tenantID,
+	}, echo.HeaderXForwardedFor, "203.0.113.7", echo.HeaderXRealIP, "203.0.113.8")
+	expectStatus(t, res, http.StatusUnauthorized)
+
+	var direct, forged, throttled int64
+	db.Raw("SELECT COUNT(*) FROM audit_events WHERE action = 'auth.login' AND ip_address = '192.0.2.1'").Scan(&direct)
+	db.Raw("SELECT COUNT(*) FROM audit_events WHERE ip_address LIKE '203.0.113.%'").Scan(&forged)
+	db.Raw("SELECT COUNT(*) FROM ip_login_throttles WHERE ip_address LIKE '203.0.113.%'").Scan(&throttled)
+	if direct == 0 || forged != 0 || throttled != 0 {
+		t.Errorf("forwarding headers were trusted: %d audit events from the connection, %d forged, %d throttles", direct, forged, throttled)
+	}
+
+	// Parallel guesses are counted before the password is checked, so they
+	// cannot all slip past the progressive delay
+	statuses := make(chan int, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(statuses); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+				"email":     "doctor@north.test",
+				"password":  fmt.Sprintf("wrong-password-%d", i),
+				"tenant_id": tenantID,
+			}).status
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	checked := 0
+	for status := range statuses {
+		switch status {
+		case http.StatusUnauthorized:
+			checked++
+		case http.StatusTooManyRequests:
+		default:
+			t.Errorf("status = %d, want 401 or 429", status)
+		}
+	}
+	if checked == 0 || checked > 3 {
+		t.Errorf("%d parallel guesses were checked, want at most the 3 free attempts", checked)
+	}
+
+	res = call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "doctor@north.test",
+		"password":  testPassword,
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusTooManyRequests)
+}
+
+func TestIPThrottleSpansTenants(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("get database: %v", err)
+	}
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	attempt := func(tenantID, slug, password string) response {
+		return call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+			"email":     slug + "@clinic.test",
+			"password":  password,
+			"tenant_id": tenantID,
+		})
+	}
+
+	expectStatus(t, attempt(northID, "north", "wrong-password-entirely"), http.StatusUnauthorized)
+	expectStatus(t, attempt(southID, "south", "wrong-password-entirely"), http.StatusUnauthorized)
+
+	// One counter for the address, one per account
+	var ipCounters []entities.IPLoginThrottle
+	if err := db.Find(&ipCounters).Error; err != nil {
+		t.Fatalf("read IP counters: %v", err)
+	}
+	if len(ipCounters) != 1 || ipCounters[0].IPAddress != "192.0.2.1" || ipCounters[0].FailedCount != 2 {
+		t.Errorf("IP counters = %+v, want one for 192.0.2.1 with 2 failures", ipCounters)
+	}
+	var accountCounters int64
+	db.Raw("SELECT COUNT(*) FROM login_throttles WHERE scope = 'login'").Scan(&accountCounters)
+	if accountCounters != 2 {
+		t.Errorf("%d account counters, want one per tenant", accountCounters)
+	}
+
+	// An address locked out at one clinic is locked out at all of them
+	if err := db.Exec("UPDATE ip_login_throttles SET locked_until = ? WHERE ip_address = '192.0.2.1'", time.Now().Add(time.Minute)).Error; err != nil {
+		t.Fatalf("lock the address out: %v", err)
+	}
+	expectStatus(t, attempt(northID, "north", testPassword), http.StatusTooManyRequests)
+	expectStatus(t, attempt(southID, "south", testPassword), http.StatusTooManyRequests)
+}
+
+func TestAdminTenantRoutes(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	tenantID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+
+	expectStatus(t, call(t, e, http.MethodGet, "/api/admin/tenants", "", nil), http.StatusUnauthorized)
+
+	userToken := login(t, e, tenantID, "nurse@north.test", "user")
+	expectStatus(t, call(t, e, http.MethodGet, "/api/admin/tenants", userToken, nil), http.StatusForbidden)
+
+	// A clinic's admin manages no tenants, not even the other clinics
+	southToken := loginAdmin(t, e, southID, "south")
+	expectStatus(t, call(t, e, http.MethodGet, "/api/admin/tenants", southToken, nil), http.StatusForbidden)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/admin/tenants/"+tenantID+"/settings", southToken, nil), http.StatusForbidden)
+	expectStatus(t, call(t, e, http.MethodPut, "/api/admin/tenants/"+tenantID+"/status", southToken, map[string]bool{"is_active": false}), http.StatusForbidden)
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/admin/tenants/"+tenantID, southToken, nil), http.StatusForbidden)
+
+	adminToken := loginAdmin(t, e, tenantID, "north")
+	expectStatus(t, call(t, e, http.MethodGet, "/api/admin/tenants", adminToken, nil), http.StatusForbidden)
+	grantSuperAdmin(t, c, "north@clinic.test")
+
+	res := call(t, e, http.MethodGet, "/api/admin/tenants", adminToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	var tenants []map[string]interface{}
+	if err := json.Unmarshal(res.raw, &tenants); err != nil || len(tenants) != 2 {
+		t.Errorf("tenants = %s", res.raw)
+	}
+
+	res = call(t, e, http.MethodGet, "/api/admin/tenants/"+southID+"/settings", adminToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if res.body["tenant_id"] != southID {
+		t.Errorf("settings = %s", res.raw)
+	}
+}
+
+func TestDeleteTenant(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	superToken := loginAdmin(t, e, northID, "north")
+	grantSuperAdmin(t, c, "north@clinic.test")
+
+	southToken := loginAdmin(t, e, southID, "south")
+	res := call(t, e, http.MethodPost, "/api/protected/patients", southToken, map[string]string{
+		"first_name":    "Ada",
+		"last_name":     "South",
+		"date_of_birth": "1990-01-02",
+		"sex":           "female",
+	})
+	expectStatus(t, res, http.StatusCreated)
+	patientPath := "/api/protected/patients/" + res.body["id"].(string)
+
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/admin/tenants/"+southID, superToken, nil), http.StatusOK)
+
+	// The clinic is closed to its users, whose sessions are gone
+	expectStatus(t, call(t, e, http.MethodGet, patientPath, southToken, nil), http.StatusUnauthorized)
+	res = call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "south@clinic.test",
+		"password":  testPassword,
+		"tenant_id": southID,
+	})
+	expectStatus(t, res, http.StatusForbidden)
+
+	// Its records are retained and it opens again as it was
+	res = call(t, e, http.MethodGet, patientPath, superToken, nil, "X-Act-As-Tenant", southID, "X-Act-As-Reason", "ticket 42")
+	expectStatus(t, res, http.StatusOK)
+
+	expectStatus(t, call(t, e, http.MethodPut, "/api/admin/tenants/"+southID+"/status", superToken, map[string]bool{"is_active": true}), http.StatusOK)
+	southToken = loginAdmin(t, e, southID, "south")
+	expectStatus(t, call(t, e, http.MethodGet, patientPath, southToken, nil), http.StatusOK)
+}
+
+func TestRBACEnforcement(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	adminToken := loginAdmin(t, e, tenantID, "north")
+
+	res := registerUser(t, e, tenantID, "doctor@north.test", "user")
+	expectStatus(t, res, http.StatusCreated)
+	doctorID := res.body["user"].(map[string]interface{})["id"].(string)
+	doctorToken := signIn(t, e, tenantID, "doctor@north.test")
+
+	res = call(t, e, http.MethodPost, "/api/protected/patients", doctorToken, map[string]string{
+		"first_name":    "Ada",
+		"last_name":     "North",
+		"date_of_birth": "1990-01-02",
+		"sex":           "female",
+	})
+	expectStatus(t, res, http.StatusCreated)
+	patientID := res.body["id"].(string)
+	schedules := "/api/protected/providers/" + doctorID + "/schedules"
+	schedule := map[string]interface{}{"weekday": 1, "start_time": "08:00", "end_time": "12:00"}
+
+	// The user role reads schedules and keeps patients, admins also manage
+	// schedules and archive patients
+	expectStatus(t, call(t, e, http.MethodGet, schedules, doctorToken, nil), http.StatusOK)
+	res = call(t, e, http.MethodPost, schedules, doctorToken, schedule)
+	expectStatus(t, res, http.StatusForbidden)
+	if res.body["resource"] != "schedule" || res.body["action"] != "write" {
+		t.Errorf("denial = %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/protected/patients/"+patientID, doctorToken, nil), http.StatusForbidden)
+
+	expectStatus(t, call(t, e, http.MethodPost, schedules, adminToken, schedule), http.StatusCreated)
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/protected/patients/"+patientID, adminToken, nil), http.StatusOK)
+
+	// Without any role nothing is allowed, not even the own profile
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/tenant-admin/users/"+doctorID+"/roles/user", adminToken, nil), http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients", doctorToken, nil), http.StatusForbidden)
+	expectStatus(t, call(t, e, http.MethodPut, "/api/protected/profile", doctorToken, map[string]string{"first_name": "Ada"}), http.StatusForbidden)
+}
+
+func TestRoleChangesApplyAtOnce(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	adminToken := loginAdmin(t, e, tenantID, "north")
+
+	res := registerUser(t, e, tenantID, "doctor@north.test", "user")
+	expectStatus(t, res, http.StatusCreated)
+	doctorID := res.body["user"].(map[string]interface{})["id"].(string)
+	doctorToken := signIn(t, e, tenantID, "doctor@north.test")
+	expectStatus(t, call(t, e, http.MethodGet, "/api/tenant-admin/roles", doctorToken, nil), http.StatusForbidden)
+
+	// The token issued before the grant carries the old role
+	res = call(t, e, http.MethodPost, "/api/tenant-admin/users/"+doctorID+"/roles", adminToken, map[string]string{"role": "admin"})
+	expectStatus(t, res, http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/tenant-admin/roles", doctorToken, nil), http.StatusOK)
+
+	res = call(t, e, http.MethodDelete, "/api/tenant-admin/users/"+doctorID+"/roles/admin", adminToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/tenant-admin/roles", doctorToken, nil), http.StatusForbidden)
+
+	// Signing in again does not give back a removed role
+	res = call(t, e, http.MethodDelete, "/api/tenant-admin/users/"+doctorID+"/roles/user", adminToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	signIn(t, e, tenantID, "doctor@north.test")
+	res = call(t, e, http.MethodGet, "/api/tenant-admin/users/"+doctorID+"/roles", adminToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if roles, _ := res.body["roles"].([]interface{}); len(roles) != 0 {
+		t.Errorf("roles after signing in again = %v, want none", roles)
+	}
+}
+
+func TestRoleManagement(t *testing.T) {
+	e := newTestApp(t)
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	northToken := loginAdmin(t, e, northID, "north")
+	southToken := loginAdmin(t, e, southID, "south")
+
+	res := registerUser(t, e, northID, "doctor@north.test", "user")
+	expectStatus(t, res, http.StatusCreated)
+	doctorID := res.body["user"].(map[string]interface{})["id"].(string)
+	doctorToken := signIn(t, e, northID, "doctor@north.test")
+	schedules := "/api/protected/providers/" + doctorID + "/schedules"
+	addSchedule := func(weekday int) response {
+		return call(t, e, http.MethodPost, schedules, doctorToken, map[string]interface{}{"weekday": weekday, "start_time": "08:00", "end_time": "12:00"})
+	}
+	roleNames := func(token string) []string {
+		t.Helper()
+		res := call(t, e, http.MethodGet, "/api/tenant-admin/roles", token, nil)
+		expectStatus(t, res, http.StatusOK)
+		var roles []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(res.raw, &roles); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, role := range roles {
+			names = append(names, role.Name)
+		}
+		return names
+	}
+
+	res = call(t, e, http.MethodPost, "/api/tenant-admin/roles", northToken, map[string]string{"name": "scheduler", "description": "Keeps the rota"})
+	expectStatus(t, res, http.StatusCreated)
+	if res.body["is_system"] != false {
+		t.Errorf("created role = %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodPost, "/api/tenant-admin/roles", northToken, map[string]string{"name": "scheduler"}), http.StatusConflict)
+	expectStatus(t, call(t, e, http.MethodPost, "/api/tenant-admin/roles", northToken, map[string]string{"name": " "}), http.StatusBadRequest)
+
+	res = call(t, e, http.MethodPost, "/api/tenant-admin/roles/scheduler/permissions", northToken, map[string]string{"resource": "schedule", "action": "write"})
+	expectStatus(t, res, http.StatusOK)
+	if permissions, _ := res.body["permissions"].([]interface{}); len(permissions) != 1 {
+		t.Errorf("permissions = %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodPost, "/api/tenant-admin/roles/scheduler/permissions", northToken, map[string]string{"resource": "schedule"}), http.StatusBadRequest)
+	expectStatus(t, call(t, e, http.MethodPost, "/api/tenant-admin/roles/nurse/permissions", northToken, map[string]string{"resource": "schedule", "action": "write"}), http.StatusNotFound)
+
+	// The permissions of a custom role take effect as soon as it is assigned
+	expectStatus(t, addSchedule(1), http.StatusForbidden)
+	res = call(t, e, http.MethodPost, "/api/tenant-admin/users/"+doctorID+"/roles", northToken, map[string]string{"role": "scheduler"})
+	expectStatus(t, res, http.StatusOK)
+	if roles, _ := res.body["roles"].([]interface{}); len(roles) != 2 {
+		t.Errorf("roles = %s", res.raw)
+	}
+	expectStatus(t, addSchedule(1), http.StatusCreated)
+	expectStatus(t, call(t, e, http.MethodPost, "/api/tenant-admin/users/"+doctorID+"/roles", northToken, map[string]string{"role": "nurse"}), http.StatusNotFound)
+
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/tenant-admin/roles/scheduler/permissions/schedule/write", northToken, nil), http.StatusOK)
+	expectStatus(t, addSchedule(2), http.StatusForbidden)
+
+	// Roles belong to their tenant
+	for _, name := range roleNames(southToken) {
+		if name == "scheduler" {
+			t.Error("a role of north is listed in south")
+		}
+	}
+	expectStatus(t, call(t, e, http.MethodGet, "/api/tenant-admin/users/"+doctorID+"/roles", southToken, nil), http.StatusNotFound)
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/tenant-admin/roles/scheduler", southToken, nil), http.StatusNotFound)
+
+	// Deleting a role also takes it from its users, built-in roles stay
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/tenant-admin/roles/user", northToken, nil), http.StatusBadRequest)
+	expectStatus(t, call(t, e, http.MethodDelete, "/api/tenant-admin/roles/scheduler", northToken, nil), http.StatusOK)
+	res = call(t, e, http.MethodGet, "/api/tenant-admin/users/"+doctorID+"/roles", northToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if roles, _ := res.body["roles"].([]interface{}); len(roles) != 1 || roles[0] != "user" {
+		t.Errorf("roles after deleting scheduler = %s", res.raw)
+	}
+	for _, name := range roleNames(northToken) {
+		if name == "scheduler" {
+			t.Error("a deleted role is listed")
+		}
+	}
+}
+
+func TestPatientSearch(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	token := loginAdmin(t, e, tenantID, "north")
+
+	for _, name := range []string{"Ada", "Grace"} {
+		res := call(t, e, http.MethodPost, "/api/protected/patients", token, map[string]string{
+			"first_name":    name,
+			"last_name":     "North",
+			"date_of_birth": "1990-01-02",
+			"sex":           "female",
+		})
+		expectStatus(t, res, http.StatusCreated)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "q=ada", want: 1},
+		{query: "q=%25", want: 0},
+		{query: "q=_", want: 0},
+		{query: "q=a_a", want: 0},
+		{query: "date_of_birth=1990-01-02", want: 2},
+		{query: "date_of_birth=1990-01-03", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			res := call(t, e, http.MethodGet, "/api/protected/patients?"+tt.query, token, nil)
+			expectStatus(t, res, http.StatusOK)
+			if patients, _ := res.body["patients"].([]interface{}); len(patients) != tt.want {
+				t.Errorf("search found %d patients, want %d: %s", len(patients), tt.want, res.raw)
+			}
+		})
+	}
+
+	// The same name and date of birth is flagged as a duplicate
+	res := call(t, e, http.MethodPost, "/api/protected/patients", token, map[string]string{
+		"first_name":    "ada",
+		"last_name":     "north",
+		"date_of_birth": "1990-01-02",
+		"sex":           "female",
+	})
+	expectStatus(t, res, http.StatusConflict)
+}
+
+func TestAppointmentOverlap(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	token := loginAdmin(t, e, tenantID, "north")
+
+	day := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour)
+	at := func(hour, minute int) string {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute).Format(time.RFC3339)
+	}
+	created := func(res response) string {
+		t.Helper()
+		expectStatus(t, res, http.StatusCreated)
+		return res.body["id"].(string)
+	}
+
+	var providers []string
+	for _, email := range []string{"ada@north.test", "grace@north.test"} {
+		res := registerUser(t, e, tenantID, email, "user")
+		expectStatus(t, res, http.StatusCreated)
+		providerID := res.body["user"].(map[string]interface{})["id"].(string)
+		created(call(t, e, http.MethodPost, "/api/protected/providers/"+providerID+"/schedules", token, map[string]interface{}{
+			"weekday":    int(day.Weekday()),
+			"start_time": "08:00",
+			"end_time":   "18:00",
+		}))
+		providers = append(providers, providerID)
+	}
+	var rooms []string
+	for _, name := range []string{"Room 1", "Room 2"} {
+		rooms = append(rooms, created(call(t, e, http.MethodPost, "/api/protected/rooms", token, map[string]string{"name": name})))
+	}
+	patientID := created(call(t, e, http.MethodPost, "/api/protected/patients", token, map[string]string{
+		"first_name":    "Ada",
+		"last_name":     "North",
+		"date_of_birth": "1990-01-02",
+		"sex":           "female",
+	}))
+
+	book := func(provider, room int, startAt, endAt string) response {
+		return call(t, e, http.MethodPost, "/api/protected/appointments", token, map[string]string{
+			"patient_id":  patientID,
+			"provider_id": providers[provider],
+			"room_id":     rooms[room],
+			"start_at":    startAt,
+			"end_at":      endAt,
+		})
+	}
+
+	// Provider 0 is booked in room 0 from 10:00 to 11:00
+	created(book(0, 0, at(10, 0), at(11, 0)))
+	cancelled := created(book(0, 0, at(14, 0), at(15, 0)))
+	res := call(t, e, http.MethodPost, "/api/protected/appointments/"+cancelled+"/status", token, map[string]string{"status": "cancelled"})
+	expectStatus(t, res, http.StatusOK)
+
+	tests := []struct {
+		name           string
+		provider, room int
+		start, end     string
+		want           int
+	}{
+		{name: "same provider in another room", provider: 0, room: 1, start: at(10, 30), end: at(11, 30), want: http.StatusConflict},
+		{name: "another provider in the same room", provider: 1, room: 0, start: at(9, 30), end: at(10, 30), want: http.StatusConflict},
+		{name: "within the booking", provider: 1, room: 0, start: at(10, 15), end: at(10, 45), want: http.StatusConflict},
+		{name: "around the booking", provider: 0, room: 1, start: at(9, 0), end: at(12, 0), want: http.StatusConflict},
+		{name: "another provider in another room", provider: 1, room: 1, start: at(10, 0), end: at(11, 0), want: http.StatusCreated},
+		{name: "ending as the booking starts", provider: 0, room: 0, start: at(9, 0), end: at(10, 0), want: http.StatusCreated},
+		{name: "starting as the booking ends", provider: 0, room: 0, start: at(11, 0), end: at(12, 0), want: http.StatusCreated},
+		{name: "in place of a cancelled booking", provider: 0, room: 0, start: at(14, 0), end: at(15, 0), want: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, book(tt.provider, tt.room, tt.start, tt.end), tt.want)
+		})
+	}
+}
+
+func TestAppointmentSchedule(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	tenantID := registerTenant(t, e, "north")
+	token := loginAdmin(t, e, tenantID, "north")
+	grantSuperAdmin(t, c, "north@clinic.test")
+
+	created := func(res response) string {
+		t.Helper()
+		expectStatus(t, res, http.StatusCreated)
+		return res.body["id"].(string)
+	}
+	var providers []string
+	for _, email := range []string{"ada@north.test", "grace@north.test"} {
+		res := registerUser(t, e, tenantID, email, "user")
+		expectStatus(t, res, http.StatusCreated)
+		providers = append(providers, res.body["user"].(map[string]interface{})["id"].(string))
+	}
+	var rooms []string
+	for _, name := range []string{"Room 1", "Room 2"} {
+		rooms = append(rooms, created(call(t, e, http.MethodPost, "/api/protected/rooms", token, map[string]string{"name": name})))
+	}
+	patientID := created(call(t, e, http.MethodPost, "/api/protected/patients", token, map[string]string{
+		"first_name":    "Ada",
+		"last_name":     "North",
+		"date_of_birth": "1990-01-02",
+		"sex":           "female",
+	}))
+
+	expectStatus(t, call(t, e, http.MethodPut, "/api/admin/tenants/"+tenantID+"/settings", token, map[string]interface{}{
+		"allow_user_registration": true,
+		"max_users":               10,
+		"timezone":                "Europe/Berlin",
+		"language":                "en",
+	}), http.StatusOK)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(day time.Time, hour, minute int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, berlin)
+	}
+	addSchedule := func(provider int, weekday time.Weekday, start, end string, room *string) {
+		t.Helper()
+		created(call(t, e, http.MethodPost, "/api/protected/providers/"+providers[provider]+"/schedules", token, map[string]interface{}{
+			"weekday":      int(weekday),
+			"start_time":   start,
+			"end_time":     end,
+			"slot_minutes": 60,
+			"room_id":      room,
+		}))
+	}
+	slots := func(day time.Time) []time.Time {
+		t.Helper()
+		res := call(t, e, http.MethodGet, "/api/protected/providers/"+providers[0]+"/slots?date="+day.Format("2006-01-02"), token, nil)
+		expectStatus(t, res, http.StatusOK)
+		var starts []time.Time
+		for _, slot := range res.body["slots"].([]interface{}) {
+			start, err := time.Parse(time.RFC3339, slot.(map[string]interface{})["start_at"].(string))
+			if err != nil {
+				t.Fatal(err)
+			}
+			starts = append(starts, start)
+		}
+		return starts
+	}
+	book := func(provider int, start, end time.Time) response {
+		return call(t, e, http.MethodPost, "/api/protected/appointments", token, map[string]string{
+			"patient_id":  patientID,
+			"provider_id": providers[provider],
+			"start_at":    start.Format(time.RFC3339),
+			"end_at":      end.Format(time.RFC3339),
+		})
+	}
+	reschedule := func(id string, start, end time.Time) response {
+		return call(t, e, http.MethodPut, "/api/protected/appointments/"+id+"/schedule", token, map[string]string{
+			"start_at": start.Format(time.RFC3339),
+			"end_at":   end.Format(time.RFC3339),
+		})
+	}
+
+	t.Run("daylight saving changes", func(t *testing.T) {
+		// The next days that are an hour shorter and an hour longer in Berlin
+		var short, long time.Time
+		today := time.Now().In(berlin)
+		for i := 1; i <= 400 && (short.IsZero() || long.IsZero()); i++ {
+			day := time.Date(today.Year(), today.Month(), today.Day()+i, 0, 0, 0, 0, berlin)
+			switch day.AddDate(0, 0, 1).Sub(day) {
+			case 23 * time.Hour:
+				short = day
+			case 25 * time.Hour:
+				long = day
+			}
+		}
+		if short.IsZero() || long.IsZero() {
+			t.Fatal("no daylight saving change found within a year")
+		}
+
+		// Working hours from 01:00 to 04:00 span the change on either day
+		addSchedule(0, short.Weekday(), "01:00", "04:00", nil)
+		if long.Weekday() != short.Weekday() {
+			addSchedule(0, long.Weekday(), "01:00", "04:00", nil)
+		}
+		for _, tt := range []struct {
+			day  time.Time
+			want int
+		}{
+			{day: short, want: 2},
+			{day: long, want: 4},
+		} {
+			starts := slots(tt.day)
+			if len(starts) != tt.want {
+				t.Fatalf("%s: %d slots, want %d", tt.day.Format("2006-01-02"), len(starts), tt.want)
+			}
+			for i, start := range starts {
+				if want := at(tt.day, 1, 0).Add(time.Duration(i) * time.Hour); !start.Equal(want) {
+					t.Errorf("%s: slot %d starts at %s, want %s", tt.day.Format("2006-01-02"), i, start, want)
+				}
+			}
+			if end := starts[len(starts)-1].Add(time.Hour); !end.Equal(at(tt.day, 4, 0)) {
+				t.Errorf("%s: the last slot ends at %s, want 04:00", tt.day.Format("2006-01-02"), end.In(berlin))
+			}
+		}
+	})
+
+	// Provider 0 works in room 0 one day and in room 1 the next, provider 1
+	// works in room 1 the next day
+	day := at(time.Now().In(berlin).AddDate(0, 0, 7), 0, 0)
+	nextDay := day.AddDate(0, 0, 1)
+	addSchedule(0, day.Weekday(), "08:00", "12:00", &rooms[0])
+	addSchedule(0, nextDay.Weekday(), "08:00", "12:00", &rooms[1])
+	addSchedule(1, nextDay.Weekday(), "08:00", "12:00", &rooms[1])
+
+	t.Run("clinic-wide exception", func(t *testing.T) {
+		created(call(t, e, http.MethodPost, "/api/protected/schedule-exceptions", token, map[string]string{
+			"date":       day.Format("2006-01-02"),
+			"start_time": "09:00",
+			"end_time":   "10:00",
+			"reason":     "Fire drill",
+		}))
+
+		starts := slots(day)
+		if len(starts) != 3 {
+			t.Fatalf("%d slots, want 3", len(starts))
+		}
+		for _, start := range starts {
+			if start.Equal(at(day, 9, 0)) {
+				t.Error("the slot blocked for the whole clinic is offered")
+			}
+		}
+		expectStatus(t, book(0, at(day, 9, 30), at(day, 10, 30)), http.StatusUnprocessableEntity)
+	})
+
+	t.Run("ending after working hours", func(t *testing.T) {
+		expectStatus(t, book(0, at(day, 11, 30), at(day, 12, 30)), http.StatusUnprocessableEntity)
+		expectStatus(t, book(0, at(day, 7, 30), at(day, 8, 30)), http.StatusUnprocessableEntity)
+	})
+
+	t.Run("rescheduling into another room", func(t *testing.T) {
+		res := book(0, at(day, 10, 0), at(day, 11, 0))
+		id := created(res)
+		if res.body["room_id"] != rooms[0] {
+			t.Fatalf("booked in room %v, want the room of the template", res.body["room_id"])
+		}
+		created(book(1, at(nextDay, 10, 0), at(nextDay, 11, 0)))
+
+		// The room of the new window is taken by provider 1
+		expectStatus(t, reschedule(id, at(nextDay, 10, 0), at(nextDay, 11, 0)), http.StatusConflict)
+
+		res = reschedule(id, at(nextDay, 11, 0), at(nextDay, 12, 0))
+		expectStatus(t, res, http.StatusOK)
+		if res.body["room_id"] != rooms[1] {
+			t.Errorf("rescheduled into room %v, want the room of the new template", res.body["room_id"])
+		}
+	})
+}
+
+func TestCrossTenantDenial(t *testing.T) {
+	e := newTestApp(t)
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	northToken := loginAdmin(t, e, northID, "north")
+	southToken := loginAdmin(t, e, southID, "south")
+
+	res := call(t, e, http.MethodPost, "/api/protected/patients", northToken, map[string]string{
+		"first_name":    "Ada",
+		"last_name":     "North",
+		"date_of_birth": "1990-01-02",
+		"sex":           "female",
+	})
+	expectStatus(t, res, http.StatusCreated)
+	patientID := res.body["id"].(string)
+
+	// Another tenant's records cannot be read by ID or found by search,
+	// and naming the other tenant is refused outright
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients/"+patientID, southToken, nil), http.StatusNotFound)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients/"+patientID, southToken, nil, "X-Tenant-ID", northID), http.StatusForbidden)
+
+	res = call(t, e, http.MethodGet, "/api/protected/patients?q=ada", southToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if patients, _ := res.body["patients"].([]interface{}); len(patients) != 0 {
+		t.Errorf("search returned patients of another tenant: %s", res.raw)
+	}
+
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients/"+patientID, northToken, nil), http.StatusOK)
+
+	// Credentials only work in the tenant of the user
+	res = call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "north@clinic.test",
+		"password":  testPassword,
+		"tenant_id": southID,
+	})
+	expectStatus(t, res, http.StatusUnauthorized)
+}
+
+func TestProbes(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	expectStatus(t, call(t, e, http.MethodGet, "/livez", "", nil), http.StatusOK)
+	res := call(t, e, http.MethodGet, "/readyz", "", nil)
+	expectStatus(t, res, http.StatusOK)
+	if checks, _ := res.body["checks"].(map[string]interface{}); checks["database"] != "ok" || checks["policies"] != "ok" {
+		t.Errorf("readiness = %s", res.raw)
+	}
+
+	// Liveness does not depend on the database, readiness does
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	expectStatus(t, call(t, e, http.MethodGet, "/livez", "", nil), http.StatusOK)
+	res = call(t, e, http.MethodGet, "/readyz", "", nil)
+	expectStatus(t, res, http.StatusServiceUnavailable)
+	if res.body["status"] != "unavailable" {
+		t.Errorf("readiness = %s", res.raw)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	res := call(t, e, http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":     "nobody@north.test",
+		"password":  testPassword,
+		"tenant_id": tenantID,
+	})
+	expectStatus(t, res, http.StatusUnauthorized)
+
+	res = call(t, e, http.MethodGet, "/metrics", "", nil)
+	expectStatus(t, res, http.StatusOK)
+	for _, want := range []string{
+		`medical_system_http_requests_total{method="POST",route="/api/tenants/register",status="202"} 1`,
+		`medical_system_http_requests_total{method="POST",route="/api/tenants/register/complete",status="201"} 1`,
+		`medical_system_http_requests_total{method="POST",route="/api/auth/login",status="401"} 1`,
+		`medical_system_tenant_registrations_total{plan="basic"} 1`,
+		`medical_system_auth_logins_total{outcome="failed",step="login"} 1`,
+		`medical_system_db_query_duration_seconds_count{operation="create"}`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+	} {
+		if !strings.Contains(string(res.raw), want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	traces := filepath.Join(t.TempDir(), "traces.jsonl")
+	t.Setenv("TRACING_EXPORTER", "file")
+	t.Setenv("TRACING_FILE", traces)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	e, c := newTestAppWithContainer(t)
+	registerTenant(t, e, "north")
+
+	tracer, err := c.GetTracing()
+	if err != nil {
+		t.Fatalf("get tracing: %v", err)
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("flush traces: %v", err)
+	}
+
+	content, err := os.ReadFile(traces)
+	if err != nil {
+		t.Fatalf("read traces: %v", err)
+	}
+	traceOf, names := map[string]string{}, map[string]map[string]bool{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for decoder.More() {
+		var span struct {
+			Name        string
+			SpanContext struct{ TraceID string }
+		}
+		if err := decoder.Decode(&span); err != nil {
+			t.Fatalf("decode span: %v", err)
+		}
+		traceOf[span.Name] = span.SpanContext.TraceID
+		if names[span.SpanContext.TraceID] == nil {
+			names[span.SpanContext.TraceID] = map[string]bool{}
+		}
+		names[span.SpanContext.TraceID][span.Name] = true
+	}
+
+	// The request, the services and the statements form one trace
+	traceID := traceOf["POST /api/tenants/register/complete"]
+	if traceID == "" {
+		t.Fatalf("no request span in %v", names)
+	}
+	for _, name := range []string{"TenantApplicationService.CompleteRegistration", "TenantService.CreateTenant", "gorm.Create", "gorm.Query"} {
+		if !names[traceID][name] {
+			t.Errorf("trace of the request lacks span %s: %v", name, names[traceID])
+		}
+	}
+}
+
+func TestTenantStatusChangesApplyAtOnce(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	adminToken := loginAdmin(t, e, northID, "north")
+	grantSuperAdmin(t, c, "north@clinic.test")
+	southToken := login(t, e, southID, "nurse@south.test", "user")
+
+	// Resolved once, then served from the cache
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", southToken, nil), http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", southToken, nil), http.StatusOK)
+
+	res := call(t, e, http.MethodPut, "/api/admin/tenants/"+southID+"/status", adminToken, map[string]bool{"is_active": false})
+	expectStatus(t, res, http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", southToken, nil), http.StatusForbidden)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients", southToken, nil), http.StatusForbidden)
+
+	res = call(t, e, http.MethodPut, "/api/admin/tenants/"+southID+"/status", adminToken, map[string]bool{"is_active": true})
+	expectStatus(t, res, http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", southToken, nil), http.StatusOK)
+}
+
+// txtRecords answers DNS TXT lookups from memory
+type txtRecords map[string][]string
+
+func (r txtRecords) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if records, ok := r[name]; ok {
+		return records, nil
+	}
+	return nil, fmt.Errorf("lookup %s: no such host", name)
+}
+
+func TestCustomDomains(t *testing.T) {
+	dns := txtRecords{}
+	e, c := newTestAppWithContainer(t, func(services.TXTResolver) services.TXTResolver { return dns })
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	northToken := loginAdmin(t, e, northID, "north")
+	adminToken := loginAdmin(t, e, southID, "south")
+
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	if err := c.DigContainer().Invoke(func(tm *authmiddleware.TenantMiddleware) { tenantMiddleware = tm }); err != nil {
+		t.Fatalf("get tenant middleware: %v", err)
+	}
+	identify := echo.New()
+	identify.Use(tenantMiddleware.TenantIdentifier())
+	identify.GET("/", func(c echo.Context) error {
+		tenantID, _ := authmiddleware.GetTenantIDFromContext(c)
+		return c.String(http.StatusOK, tenantID)
+	})
+	tenantOf := func(host string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		identify.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	domainsPath := "/api/tenant-admin/domains"
+	nurseToken := login(t, e, southID, "nurse@south.test", "user")
+	expectStatus(t, call(t, e, http.MethodGet, domainsPath, nurseToken, nil), http.StatusForbidden)
+	expectStatus(t, call(t, e, http.MethodPost, domainsPath, adminToken, map[string]string{"domain": "not a domain"}), http.StatusBadRequest)
+
+	res := call(t, e, http.MethodPost, domainsPath, adminToken, map[string]string{"domain": "Portal.South-Clinic.test"})
+	expectStatus(t, res, http.StatusCreated)
+	domainID, _ := res.body["id"].(string)
+	record, _ := res.body["verification_record"].(string)
+	value, _ := res.body["verification_value"].(string)
+	if res.body["domain"] != "portal.south-clinic.test" || record != "_medical-system.portal.south-clinic.test" || value == "" {
+		t.Fatalf("domain = %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodPost, domainsPath, adminToken, map[string]string{"domain": "portal.south-clinic.test"}), http.StatusConflict)
+
+	// Unverified domains route nowhere
+	verifyPath := domainsPath + "/" + domainID + "/verify"
+	expectStatus(t, call(t, e, http.MethodPost, verifyPath, adminToken, nil), http.StatusUnprocessableEntity)
+	if got := tenantOf("portal.south-clinic.test"); got == southID {
+		t.Errorf("unverified domain resolved to its tenant")
+	}
+
+	dns[record] = []string{"v=spf1 -all", value}
+	res = call(t, e, http.MethodPost, verifyPath, adminToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if res.body["verified"] != true {
+		t.Errorf("verify = %s", res.raw)
+	}
+	if got := tenantOf("Portal.South-Clinic.test:8443"); got != southID {
+		t.Errorf("verified domain resolved to %q, want %q", got, southID)
+	}
+
+	// Once verified, no other tenant may claim the domain, see it or remove it
+	res = call(t, e, http.MethodPost, domainsPath, northToken, map[string]string{"domain": "portal.south-clinic.test"})
+	expectStatus(t, res, http.StatusConflict)
+	res = call(t, e, http.MethodGet, domainsPath, northToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if string(bytes.TrimSpace(res.raw)) != "[]" {
+		t.Errorf("north lists the domains of south: %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodDelete, domainsPath+"/"+domainID, northToken, nil), http.StatusNotFound)
+
+	res = call(t, e, http.MethodGet, domainsPath, adminToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	var domains []map[string]interface{}
+	if err := json.Unmarshal(res.raw, &domains); err != nil || len(domains) != 1 || domains[0]["id"] != domainID {
+		t.Errorf("domains = %s", res.raw)
+	}
+
+	expectStatus(t, call(t, e, http.MethodDelete, domainsPath+"/"+domainID, adminToken, nil), http.StatusOK)
+	if got := tenantOf("portal.south-clinic.test"); got == southID {
+		t.Errorf("removed domain still resolves to its tenant")
+	}
+	expectStatus(t, call(t, e, http.MethodDelete, domainsPath+"/"+domainID, adminToken, nil), http.StatusNotFound)
+}
+
+func TestTenantMustMatchToken(t *testing.T) {
+	e := newTestApp(t)
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	nurseToken := login(t, e, southID, "nurse@south.test", "user")
+
+	// Naming the token's own tenant, by ID or slug, is fine
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", nurseToken, nil, "X-Tenant-ID", southID), http.StatusOK)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile?tenant_id=south", nurseToken, nil), http.StatusOK)
+
+	for name, res := range map[string]response{
+		"header":         call(t, e, http.MethodGet, "/api/protected/profile", nurseToken, nil, "X-Tenant-ID", northID),
+		"header slug":    call(t, e, http.MethodGet, "/api/protected/patients", nurseToken, nil, "X-Tenant-ID", "north"),
+		"query":          call(t, e, http.MethodGet, "/api/protected/appointments?tenant_id="+northID, nurseToken, nil),
+		"unknown tenant": call(t, e, http.MethodGet, "/api/protected/profile", nurseToken, nil, "X-Tenant-ID", "nowhere"),
+		"act as":         call(t, e, http.MethodGet, "/api/protected/profile", nurseToken, nil, "X-Act-As-Tenant", northID, "X-Act-As-Reason", "curious"),
+	} {
+		if res.status != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want 403: %s", name, res.status, res.raw)
+		}
+	}
+}
+
+func TestActAsTenant(t *testing.T) {
+	e, c := newTestAppWithContainer(t)
+	northID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	superToken := loginAdmin(t, e, northID, "north")
+	southToken := loginAdmin(t, e, southID, "south")
+
+	res := call(t, e, http.MethodPost, "/api/protected/patients", southToken, map[string]string{
+		"first_name":    "Ada",
+		"last_name":     "South",
+		"date_of_birth": "1990-01-02",
+		"sex":           "female",
+	})
+	expectStatus(t, res, http.StatusCreated)
+	patientPath := "/api/protected/patients/" + res.body["id"].(string)
+
+	// Being a clinic's admin is not enough
+	expectStatus(t, call(t, e, http.MethodGet, patientPath, superToken, nil, "X-Act-As-Tenant", "south", "X-Act-As-Reason", "ticket 42"), http.StatusForbidden)
+	grantSuperAdmin(t, c, "north@clinic.test")
+
+	expectStatus(t, call(t, e, http.MethodGet, patientPath, superToken, nil), http.StatusNotFound)
+	expectStatus(t, call(t, e, http.MethodGet, patientPath, superToken, nil, "X-Act-As-Tenant", "south"), http.StatusBadRequest)
+	expectStatus(t, call(t, e, http.MethodGet, patientPath, superToken, nil, "X-Act-As-Tenant", "nowhere", "X-Act-As-Reason", "ticket 42"), http.StatusNotFound)
+
+	res = call(t, e, http.MethodGet, patientPath, superToken, nil, "X-Act-As-Tenant", "south", "X-Act-As-Reason", "ticket 42")
+	expectStatus(t, res, http.StatusOK)
+	if res.body["last_name"] != "South" {
+		t.Errorf("patient = %s", res.raw)
+	}
+
+	// Other sources must name the tenant acted for
+	expectStatus(t, call(t, e, http.MethodGet, patientPath, superToken, nil, "X-Act-As-Tenant", "south", "X-Act-As-Reason", "ticket 42", "X-Tenant-ID", northID), http.StatusForbidden)
+
+	db, err := c.GetDatabase()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	var entries int64
+	if err := db.Raw("SELECT COUNT(*) FROM audit_events WHERE action = 'system.scope' AND details = ?", "act as tenant "+southID+": ticket 42").Scan(&entries).Error; err != nil {
+		t.Fatalf("count audit events: %v", err)
+	}
+	if entries == 0 {
+		t.Errorf("acting as the tenant was not audited")
+	}
+}
+
+func TestAuditChain(t *testing.T) {
+	// auditedTenant onboards a clinic and records a few events in its chain
+	auditedTenant := func(t *testing.T) (*echo.Echo, *gorm.DB, string, string) {
+		t.Helper()
+
+		e, c := newTestAppWithContainer(t)
+		tenantID := registerTenant(t, e, "south")
+		token := loginAdmin(t, e, tenantID, "south")
+		for _, name := range []string{"Ada", "Grace", "Edsger"} {
+			res := call(t, e, http.MethodPost, "/api/protected/patients", token, map[string]string{
+				"first_name":    name,
+				"last_name":     "South",
+				"date_of_birth": "1990-01-02",
+				"sex":           "female",
+			})
+			expectStatus(t, res, http.StatusCreated)
+		}
+
+		db, err := c.GetDatabase()
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		return e, db, tenantID, token
+	}
+	verify := func(t *testing.T, e *echo.Echo, token string) response {
+		t.Helper()
+
+		res := call(t, e, http.MethodGet, "/api/tenant-admin/audit-events/verify", token, nil)
+		expectStatus(t, res, http.StatusOK)
+		return res
+	}
+	exec := func(t *testing.T, db *gorm.DB, query string, args ...interface{}) {
+		t.Helper()
+
+		if err := db.Exec(query, args...).Error; err != nil {
+			t.Fatalf("tamper with the audit log: %v", err)
+		}
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		e, _, _, token := auditedTenant(t)
+
+		res := verify(t, e, token)
+		if res.body["valid"] != true || res.body["events"].(float64) < 4 {
+			t.Errorf("verification = %s", res.raw)
+		}
+	})
+
+	tampering := []struct {
+		name     string
+		tamper   func(t *testing.T, db *gorm.DB, tenantID string, last int64)
+		brokenAt func(last int64) int64
+	}{
+		{
+			name: "modified",
+			tamper: func(t *testing.T, db *gorm.DB, tenantID string, last int64) {
+				exec(t, db, "UPDATE audit_events SET details = 'nothing happened' WHERE tenant_id = ? AND sequence = 2", tenantID)
+			},
+			brokenAt: func(int64) int64 { return 2 },
+		},
+		{
+			name: "deleted",
+			tamper: func(t *testing.T, db *gorm.DB, tenantID string, last int64) {
+				exec(t, db, "DELETE FROM audit_events WHERE tenant_id = ? AND sequence = 2", tenantID)
+			},
+			brokenAt: func(int64) int64 { return 3 },
+		},
+		{
+			name: "reordered",
+			tamper: func(t *testing.T, db *gorm.DB, tenantID string, last int64) {
+				exec(t, db, "UPDATE audit_events SET sequence = -1 WHERE tenant_id = ? AND sequence = 2", tenantID)
+				exec(t, db, "UPDATE audit_events SET sequence = 2 WHERE tenant_id = ? AND sequence = 3", tenantID)
+				exec(t, db, "UPDATE audit_events SET sequence = 3 WHERE tenant_id = ? AND sequence = -1", tenantID)
+			},
+			brokenAt: func(int64) int64 { return 2 },
+		},
+		{
+			name: "cut short",
+			tamper: func(t *testing.T, db *gorm.DB, tenantID string, last int64) {
+				exec(t, db, "DELETE FROM audit_events WHERE tenant_id = ? AND sequence >= ?", tenantID, last-1)
+			},
+			brokenAt: func(last int64) int64 { return last - 1 },
+		},
+	}
+	for _, tc := range tampering {
+		t.Run(tc.name, func(t *testing.T) {
+			e, db, tenantID, token := auditedTenant(t)
+
+			var last int64
+			if err := db.Raw("SELECT MAX(sequence) FROM audit_events WHERE tenant_id = ?", tenantID).Scan(&last).Error; err != nil {
+				t.Fatalf("read the audit log: %v", err)
+			}
+			tc.tamper(t, db, tenantID, last)
+
+			res := verify(t, e, token)
+			if res.body["valid"] != false || res.body["broken_at"] != float64(tc.brokenAt(last)) {
+				t.Errorf("verification = %s, want broken at %d", res.raw, tc.brokenAt(last))
+			}
+		})
+	}
+
+	t.Run("export", func(t *testing.T) {
+		e, _, _, token := auditedTenant(t)
+
+		res := call(t, e, http.MethodGet, "/api/tenant-admin/audit-events/export?resource_type=patient", token, nil)
+		expectStatus(t, res, http.StatusOK)
+		rows, err := csv.NewReader(bytes.NewReader(res.raw)).ReadAll()
+		if err != nil {
+			t.Fatalf("read export: %v", err)
+		}
+		if len(rows) != 4 {
+			t.Fatalf("export has %d rows, want a header and 3 events: %s", len(rows), res.raw)
+		}
+		if strings.Join(rows[0], ",") != "sequence,occurred_at,actor_id,actor_role,action,resource_type,resource_id,outcome,status_code,method,path,ip_address,request_id,details,prev_hash,hash" {
+			t.Errorf("header = %v", rows[0])
+		}
+		for _, row := range rows[1:] {
+			if row[5] != "patient" || row[7] != "success" || row[15] == "" {
+				t.Errorf("row = %v", row)
+			}
+		}
+	})
+
+	t.Run("act as tenant", func(t *testing.T) {
+		e, c := newTestAppWithContainer(t)
+		northID := registerTenant(t, e, "north")
+		southID := registerTenant(t, e, "south")
+		grantSuperAdmin(t, c, "north@clinic.test")
+		superToken := loginAdmin(t, e, northID, "north")
+		southToken := loginAdmin(t, e, southID, "south")
+
+		expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients", superToken, nil, "X-Act-As-Tenant", "south", "X-Act-As-Reason", "ticket 42"), http.StatusOK)
+
+		// The clinic sees who reached its data, in its own chain
+		res := call(t, e, http.MethodGet, "/api/tenant-admin/audit-events?action=system.scope", southToken, nil)
+		expectStatus(t, res, http.StatusOK)
+		if res.body["total"] != float64(1) {
+			t.Fatalf("events = %s", res.raw)
+		}
+		event := res.body["events"].([]interface{})[0].(map[string]interface{})
+		if event["details"] != "act as tenant "+southID+": ticket 42" || event["resource_id"] != southID {
+			t.Errorf("event = %v", event)
+		}
+		if res := verify(t, e, southToken); res.body["valid"] != true {
+			t.Errorf("verification = %s", res.raw)
+		}
+	})
+}
+
+func TestEncounterSigning(t *testing.T) {
+	e := newTestApp(t)
+	tenantID := registerTenant(t, e, "north")
+	southID := registerTenant(t, e, "south")
+	adminToken := loginAdmin(t, e, tenantID, "north")
+	southToken := loginAdmin(t, e, southID, "south")
+
+	var userIDs []string
+	var tokens []string
+	for _, email := range []string{"ada@north.test", "grace@north.test"} {
+		res := registerUser(t, e, tenantID, email, "user")
+		expectStatus(t, res, http.StatusCreated)
+		userIDs = append(userIDs, res.body["user"].(map[string]interface{})["id"].(string))
+		tokens = append(tokens, signIn(t, e, tenantID, email))
+	}
+	adaID, graceID := userIDs[0], userIDs[1]
+	adaToken, graceToken := tokens[0], tokens[1]
+
+	res := call(t, e, http.MethodPost, "/api/protected/patients", adminToken, map[string]string{
+		"first_name":    "Edsger",
+		"last_name":     "North",
+		"date_of_birth": "1990-01-02",
+		"sex":           "male",
+	})
+	expectStatus(t, res, http.StatusCreated)
+	patientID := res.body["id"].(string)
+
+	notes := func(plan string) map[string]interface{} {
+		return map[string]interface{}{
+			"patient_id": patientID,
+			"notes": map[string]string{
+				"subjective": "Headache for three days",
+				"assessment": "Tension headache",
+				"plan":       plan,
+			},
+		}
+	}
+	res = call(t, e, http.MethodPost, "/api/protected/encounters", adaToken, notes("Rest"))
+	expectStatus(t, res, http.StatusCreated)
+	if res.body["provider_id"] != adaID || res.body["status"] != "draft" {
+		t.Fatalf("encounter = %s", res.raw)
+	}
+	encounterPath := "/api/protected/encounters/" + res.body["id"].(string)
+
+	// A draft can be edited, only its provider signs it
+	res = call(t, e, http.MethodPut, encounterPath, adaToken, notes("Ibuprofen 400 mg"))
+	expectStatus(t, res, http.StatusOK)
+	if res.body["notes"].(map[string]interface{})["plan"] != "Ibuprofen 400 mg" {
+		t.Errorf("draft = %s", res.raw)
+	}
+	expectStatus(t, call(t, e, http.MethodPost, encounterPath+"/sign", graceToken, nil), http.StatusForbidden)
+	res = call(t, e, http.MethodPost, encounterPath+"/sign", adaToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if res.body["status"] != "signed" || res.body["signed_by"] != adaID || res.body["signed_at"] == nil {
+		t.Errorf("signed encounter = %s", res.raw)
+	}
+
+	// Signed notes are final
+	expectStatus(t, call(t, e, http.MethodPut, encounterPath, adaToken, notes("Nothing")), http.StatusConflict)
+	expectStatus(t, call(t, e, http.MethodPost, encounterPath+"/sign", adaToken, nil), http.StatusConflict)
+	expectStatus(t, call(t, e, http.MethodPost, encounterPath+"/amendments", graceToken, map[string]string{
+		"section": "plan",
+		"text":    "Paracetamol 1 g",
+	}), http.StatusBadRequest)
+
+	// Amendments keep the text they replace, who changed it and when
+	for _, amendment := range []struct {
+		token, text string
+	}{
+		{graceToken, "Paracetamol 1 g"},
+		{adaToken, "Paracetamol 500 mg"},
+	} {
+		res = call(t, e, http.MethodPost, encounterPath+"/amendments", amendment.token, map[string]string{
+			"section": "plan",
+			"text":    amendment.text,
+			"reason":  "Allergic to ibuprofen",
+		})
+		expectStatus(t, res, http.StatusCreated)
+	}
+
+	res = call(t, e, http.MethodGet, encounterPath, adaToken, nil)
+	expectStatus(t, res, http.StatusOK)
+	if plan := res.body["notes"].(map[string]interface{})["plan"]; plan != "Paracetamol 500 mg" {
+		t.Errorf("current plan = %v", plan)
+	}
+	if plan := res.body["signed_notes"].(map[string]interface{})["plan"]; plan != "Ibuprofen 400 mg" {
+		t.Errorf("signed plan = %v", plan)
+	}
+	amendments := res.body["amendments"].([]interface{})
+	if len(amendments) != 2 {
+		t.Fatalf("amendments = %v", amendments)
+	}
+	for i, want := range []struct {
+		original, amended, by string
+	}{
+		{"Ibuprofen 400 mg", "Paracetamol 1 g", graceID},
+		{"Paracetamol 1 g", "Paracetamol 500 mg", adaID},
+	} {
+		amendment := amendments[i].(map[string]interface{})
+		if amendment["original_text"] != want.original || amendment["amended_text"] != want.amended || amendment["amended_by"] != want.by {
+			t.Errorf("amendment %d = %v", i, amendment)
+		}
+		if at, err := time.Parse(time.RFC3339Nano, amendment["amended_at"].(string)); err != nil || time.Since(at) > time.Minute {
+			t.Errorf("amendment %d made at %v", i, amendment["amended_at"])
+		}
+	}
+
+	// Another clinic cannot read the encounter
+	expectStatus(t, call(t, e, http.MethodGet, encounterPath, southToken, nil), http.StatusNotFound)
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/patients/"+patientID+"/encounters", southToken, nil), http.StatusNotFound)
+	expectStatus(t, call(t, e, http.MethodPost, encounterPath+"/amendments", southToken, map[string]string{
+		"section": "plan",
+		"text":    "Nothing",
+		"reason":  "Wrong clinic",
+	}), http.StatusNotFound)
+}
+
+func TestJWKS(t *testing.T) {
+	expectStatus(t, call(t, newTestApp(t), http.MethodGet, "/.well-known/jwks.json", "", nil), http.StatusNotFound)
+
+	keysDir := t.TempDir()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(keysDir, "2026-06.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := newTestAppWithContainer(t, func(infraauth.TokenGenerator) (infraauth.TokenGenerator, error) {
+		return infraauth.NewKeySetGenerator(keysDir, "", nil)
+	})
+
+	res := call(t, e, http.MethodGet, "/.well-known/jwks.json", "", nil)
+	expectStatus(t, res, http.StatusOK)
+	var set infraauth.JSONWebKeySet
+	if err := json.Unmarshal(res.raw, &set); err != nil || len(set.Keys) != 1 {
+		t.Fatalf("JWKS = %s", res.raw)
+	}
+	published := set.Keys[0]
+	if published.KeyID != "2026-06" || published.KeyType != "OKP" || published.Algorithm != "EdDSA" {
+		t.Errorf("key = %+v", published)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(published.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens issued by the application verify with the published key alone
+	tenantID := registerTenant(t, e, "north")
+	token := loginAdmin(t, e, tenantID, "north")
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["kid"] != published.KeyID {
+			return nil, fmt.Errorf("kid = %v", token.Header["kid"])
+		}
+		return ed25519.PublicKey(x), nil
+	})
+	if err != nil || !parsed.Valid {
+		t.Errorf("verify with the published key: %v", err)
+	}
+	expectStatus(t, call(t, e, http.MethodGet, "/api/protected/profile", token, nil), http.StatusOK)
+}
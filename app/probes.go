@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	infraauth "medical-system/infrastructure/auth"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// probeTimeout bounds each readiness check, well below the usual probe
+// timeout of orchestrators
+const probeTimeout = 2 * time.Second
+
+// operationalPaths skip tracing: neither probes nor scrapes are worth a
+// trace
+var operationalPaths = map[string]bool{
+	"/livez":   true,
+	"/readyz":  true,
+	"/health":  true,
+	"/metrics": true,
+}
+
+// registerProbes adds the liveness and readiness endpoints. Liveness only
+// reports that the process serves requests; readiness also checks the
+// database and the Casbin policy store, so traffic stops while they are down.
+func registerProbes(e *echo.Echo, db *gorm.DB, enforcer *infraauth.CasbinEnforcer) {
+	live := func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+	}
+	e.GET("/livez", live)
+	// Kept for existing monitors
+	e.GET("/health", live)
+
+	checks := map[string]func(context.Context) error{
+		"database": func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+		"policies": enforcer.Ping,
+	}
+
+	e.GET("/readyz", func(c echo.Context) error {
+		status, results := http.StatusOK, make(map[string]string, len(checks))
+		for name, check := range checks {
+			// Outside of the request transaction, which a failed check
+			// would abort
+			ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+			err := check(ctx)
+			cancel()
+
+			results[name] = "ok"
+			if err != nil {
+				status, results[name] = http.StatusServiceUnavailable, err.Error()
+			}
+		}
+
+		body := map[string]interface{}{"status": "ok", "checks": results}
+		if status != http.StatusOK {
+			body["status"] = "unavailable"
+		}
+		return c.JSON(status, body)
+	})
+}
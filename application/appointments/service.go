@@ -0,0 +1,465 @@
+package appointments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/domain/services"
+)
+
+// dateLayout is the format of calendar days in requests
+const dateLayout = "2006-01-02"
+
+var ErrInvalidDate = errors.New("dates must use the YYYY-MM-DD format")
+
+type AppointmentApplicationService struct {
+	appointmentService services.AppointmentService
+	scheduleService    services.ScheduleService
+}
+
+func NewAppointmentApplicationService(appointmentService services.AppointmentService, scheduleService services.ScheduleService) *AppointmentApplicationService {
+	return &AppointmentApplicationService{
+		appointmentService: appointmentService,
+		scheduleService:    scheduleService,
+	}
+}
+
+type CreateRoomRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RoomResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsActive    bool   `json:"is_active"`
+}
+
+// ScheduleRequest adds a weekly working-hours template. Weekday is 0 for
+// Sunday through 6 for Saturday; times are "HH:MM" in the tenant's timezone.
+type ScheduleRequest struct {
+	Weekday     int     `json:"weekday"`
+	StartTime   string  `json:"start_time"`
+	EndTime     string  `json:"end_time"`
+	SlotMinutes int     `json:"slot_minutes"`
+	RoomID      *string `json:"room_id"`
+}
+
+type ScheduleResponse struct {
+	ID          string  `json:"id"`
+	ProviderID  string  `json:"provider_id"`
+	Weekday     int     `json:"weekday"`
+	StartTime   string  `json:"start_time"`
+	EndTime     string  `json:"end_time"`
+	SlotMinutes int     `json:"slot_minutes"`
+	RoomID      *string `json:"room_id"`
+}
+
+// ScheduleExceptionRequest blocks time for a provider, or for the whole clinic
+// when ProviderID is empty. Without start and end times the whole days from
+// Date to EndDate (inclusive, defaults to Date) are blocked.
+type ScheduleExceptionRequest struct {
+	ProviderID string `json:"provider_id"`
+	Date       string `json:"date"`
+	EndDate    string `json:"end_date"`
+	StartTime  string `json:"start_time"`
+	EndTime    string `json:"end_time"`
+	Reason     string `json:"reason"`
+}
+
+type ListScheduleExceptionsRequest struct {
+	ProviderID string `query:"provider_id"`
+	From       string `query:"from"`
+	To         string `query:"to"`
+}
+
+type ScheduleExceptionResponse struct {
+	ID         string    `json:"id"`
+	ProviderID string    `json:"provider_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Reason     string    `json:"reason"`
+}
+
+type SlotResponse struct {
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+	RoomID  *string   `json:"room_id"`
+}
+
+type AvailableSlotsResponse struct {
+	ProviderID string         `json:"provider_id"`
+	Date       string         `json:"date"`
+	Timezone   string         `json:"timezone"`
+	Slots      []SlotResponse `json:"slots"`
+}
+
+// BookAppointmentRequest books a visit. Either EndAt or DurationMinutes must
+// be given; times are RFC 3339 timestamps.
+type BookAppointmentRequest struct {
+	PatientID       string    `json:"patient_id"`
+	ProviderID      string    `json:"provider_id"`
+	RoomID          *string   `json:"room_id"`
+	StartAt         time.Time `json:"start_at"`
+	EndAt           time.Time `json:"end_at"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Reason          string    `json:"reason"`
+	Notes           string    `json:"notes"`
+}
+
+type RescheduleAppointmentRequest struct {
+	StartAt         time.Time `json:"start_at"`
+	EndAt           time.Time `json:"end_at"`
+	DurationMinutes int       `json:"duration_minutes"`
+}
+
+type ChangeStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// ListAppointmentsRequest filters appointments. From and To are calendar
+// days in the tenant's timezone, both inclusive.
+type ListAppointmentsRequest struct {
+	ProviderID string `query:"provider_id"`
+	PatientID  string `query:"patient_id"`
+	RoomID     string `query:"room_id"`
+	Status     string `query:"status"`
+	From       string `query:"from"`
+	To         string `query:"to"`
+}
+
+type AppointmentResponse struct {
+	ID                 string    `json:"id"`
+	PatientID          string    `json:"patient_id"`
+	ProviderID         string    `json:"provider_id"`
+	RoomID             *string   `json:"room_id"`
+	StartAt            time.Time `json:"start_at"`
+	EndAt              time.Time `json:"end_at"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason"`
+	Notes              string    `json:"notes"`
+	CancellationReason string    `json:"cancellation_reason,omitempty"`
+	BookedBy           string    `json:"booked_by"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+func (s *AppointmentApplicationService) CreateRoom(ctx context.Context, req CreateRoomRequest) (*RoomResponse, error) {
+	room := &entities.Room{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := s.scheduleService.CreateRoom(ctx, room); err != nil {
+		return nil, err
+	}
+	return toRoomResponse(room), nil
+}
+
+func (s *AppointmentApplicationService) ListRooms(ctx context.Context) ([]RoomResponse, error) {
+	rooms, err := s.scheduleService.ListRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]RoomResponse, 0, len(rooms))
+	for _, room := range rooms {
+		response = append(response, *toRoomResponse(room))
+	}
+	return response, nil
+}
+
+func (s *AppointmentApplicationService) AddSchedule(ctx context.Context, providerID string, req ScheduleRequest) (*ScheduleResponse, error) {
+	schedule := &entities.ProviderSchedule{
+		ProviderID:  providerID,
+		Weekday:     time.Weekday(req.Weekday),
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		SlotMinutes: req.SlotMinutes,
+		RoomID:      req.RoomID,
+	}
+	if err := s.scheduleService.AddSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+func (s *AppointmentApplicationService) ListSchedules(ctx context.Context, providerID string) ([]ScheduleResponse, error) {
+	schedules, err := s.scheduleService.ListSchedules(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]ScheduleResponse, 0, len(schedules))
+	for _, schedule := range schedules {
+		response = append(response, *toScheduleResponse(schedule))
+	}
+	return response, nil
+}
+
+func (s *AppointmentApplicationService) DeleteSchedule(ctx context.Context, id string) error {
+	return s.scheduleService.DeleteSchedule(ctx, id)
+}
+
+func (s *AppointmentApplicationService) AddException(ctx context.Context, req ScheduleExceptionRequest) (*ScheduleExceptionResponse, error) {
+	location := s.scheduleService.Location(ctx)
+
+	startDay, err := parseDay(req.Date, location)
+	if err != nil {
+		return nil, err
+	}
+	endDay := startDay
+	if req.EndDate != "" {
+		if endDay, err = parseDay(req.EndDate, location); err != nil {
+			return nil, err
+		}
+	}
+
+	startsAt, endsAt := startDay, endDay.AddDate(0, 0, 1)
+	if req.StartTime != "" || req.EndTime != "" {
+		if startsAt, err = atTime(startDay, req.StartTime); err != nil {
+			return nil, err
+		}
+		if endsAt, err = atTime(endDay, req.EndTime); err != nil {
+			return nil, err
+		}
+	}
+
+	exception := &entities.ScheduleException{
+		ProviderID: req.ProviderID,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		Reason:     req.Reason,
+	}
+	if err := s.scheduleService.AddException(ctx, exception); err != nil {
+		return nil, err
+	}
+	return toScheduleExceptionResponse(exception, location), nil
+}
+
+func (s *AppointmentApplicationService) ListExceptions(ctx context.Context, req ListScheduleExceptionsRequest) ([]ScheduleExceptionResponse, error) {
+	location := s.scheduleService.Location(ctx)
+	from, to, err := dayRange(req.From, req.To, location)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions, err := s.scheduleService.ListExceptions(ctx, req.ProviderID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]ScheduleExceptionResponse, 0, len(exceptions))
+	for _, exception := range exceptions {
+		response = append(response, *toScheduleExceptionResponse(exception, location))
+	}
+	return response, nil
+}
+
+func (s *AppointmentApplicationService) DeleteException(ctx context.Context, id string) error {
+	return s.scheduleService.DeleteException(ctx, id)
+}
+
+func (s *AppointmentApplicationService) AvailableSlots(ctx context.Context, providerID, date string) (*AvailableSlotsResponse, error) {
+	location := s.scheduleService.Location(ctx)
+	day, err := parseDay(date, location)
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := s.scheduleService.AvailableSlots(ctx, providerID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AvailableSlotsResponse{
+		ProviderID: providerID,
+		Date:       day.Format(dateLayout),
+		Timezone:   location.String(),
+		Slots:      make([]SlotResponse, 0, len(slots)),
+	}
+	for _, slot := range slots {
+		response.Slots = append(response.Slots, SlotResponse{StartAt: slot.Start, EndAt: slot.End, RoomID: slot.RoomID})
+	}
+	return response, nil
+}
+
+func (s *AppointmentApplicationService) BookAppointment(ctx context.Context, bookedBy string, req BookAppointmentRequest) (*AppointmentResponse, error) {
+	appointment := &entities.Appointment{
+		PatientID:  req.PatientID,
+		ProviderID: req.ProviderID,
+		RoomID:     req.RoomID,
+		StartAt:    req.StartAt,
+		EndAt:      appointmentEnd(req.StartAt, req.EndAt, req.DurationMinutes),
+		Reason:     req.Reason,
+		Notes:      req.Notes,
+		BookedBy:   bookedBy,
+	}
+	if err := s.appointmentService.BookAppointment(ctx, appointment); err != nil {
+		return nil, err
+	}
+	return s.toAppointmentResponse(ctx, appointment), nil
+}
+
+func (s *AppointmentApplicationService) GetAppointment(ctx context.Context, id string) (*AppointmentResponse, error) {
+	appointment, err := s.appointmentService.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toAppointmentResponse(ctx, appointment), nil
+}
+
+func (s *AppointmentApplicationService) ListAppointments(ctx context.Context, req ListAppointmentsRequest) ([]AppointmentResponse, error) {
+	criteria := repositories.AppointmentSearchCriteria{
+		ProviderID: req.ProviderID,
+		PatientID:  req.PatientID,
+		RoomID:     req.RoomID,
+		Status:     entities.AppointmentStatus(req.Status),
+	}
+
+	location := s.scheduleService.Location(ctx)
+	if req.From != "" {
+		from, err := parseDay(req.From, location)
+		if err != nil {
+			return nil, err
+		}
+		criteria.From = &from
+	}
+	if req.To != "" {
+		to, err := parseDay(req.To, location)
+		if err != nil {
+			return nil, err
+		}
+		to = to.AddDate(0, 0, 1)
+		criteria.To = &to
+	}
+
+	appointments, err := s.appointmentService.ListAppointments(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]AppointmentResponse, 0, len(appointments))
+	for _, appointment := range appointments {
+		response = append(response, *s.toAppointmentResponse(ctx, appointment))
+	}
+	return response, nil
+}
+
+func (s *AppointmentApplicationService) RescheduleAppointment(ctx context.Context, id string, req RescheduleAppointmentRequest) (*AppointmentResponse, error) {
+	appointment, err := s.appointmentService.RescheduleAppointment(ctx, id, req.StartAt, appointmentEnd(req.StartAt, req.EndAt, req.DurationMinutes))
+	if err != nil {
+		return nil, err
+	}
+	return s.toAppointmentResponse(ctx, appointment), nil
+}
+
+func (s *AppointmentApplicationService) ChangeStatus(ctx context.Context, id string, req ChangeStatusRequest) (*AppointmentResponse, error) {
+	appointment, err := s.appointmentService.ChangeStatus(ctx, id, entities.AppointmentStatus(req.Status), req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return s.toAppointmentResponse(ctx, appointment), nil
+}
+
+// toAppointmentResponse presents the appointment times in the tenant's timezone
+func (s *AppointmentApplicationService) toAppointmentResponse(ctx context.Context, appointment *entities.Appointment) *AppointmentResponse {
+	location := s.scheduleService.Location(ctx)
+	return &AppointmentResponse{
+		ID:                 appointment.ID,
+		PatientID:          appointment.PatientID,
+		ProviderID:         appointment.ProviderID,
+		RoomID:             appointment.RoomID,
+		StartAt:            appointment.StartAt.In(location),
+		EndAt:              appointment.EndAt.In(location),
+		Status:             string(appointment.Status),
+		Reason:             appointment.Reason,
+		Notes:              appointment.Notes,
+		CancellationReason: appointment.CancellationReason,
+		BookedBy:           appointment.BookedBy,
+		CreatedAt:          appointment.CreatedAt,
+		UpdatedAt:          appointment.UpdatedAt,
+	}
+}
+
+func toRoomResponse(room *entities.Room) *RoomResponse {
+	return &RoomResponse{
+		ID:          room.ID,
+		Name:        room.Name,
+		Description: room.Description,
+		IsActive:    room.IsActive,
+	}
+}
+
+func toScheduleResponse(schedule *entities.ProviderSchedule) *ScheduleResponse {
+	return &ScheduleResponse{
+		ID:          schedule.ID,
+		ProviderID:  schedule.ProviderID,
+		Weekday:     int(schedule.Weekday),
+		StartTime:   schedule.StartTime,
+		EndTime:     schedule.EndTime,
+		SlotMinutes: schedule.SlotMinutes,
+		RoomID:      schedule.RoomID,
+	}
+}
+
+func toScheduleExceptionResponse(exception *entities.ScheduleException, location *time.Location) *ScheduleExceptionResponse {
+	return &ScheduleExceptionResponse{
+		ID:         exception.ID,
+		ProviderID: exception.ProviderID,
+		StartsAt:   exception.StartsAt.In(location),
+		EndsAt:     exception.EndsAt.In(location),
+		Reason:     exception.Reason,
+	}
+}
+
+func appointmentEnd(start, end time.Time, durationMinutes int) time.Time {
+	if end.IsZero() && durationMinutes > 0 {
+		return start.Add(time.Duration(durationMinutes) * time.Minute)
+	}
+	return end
+}
+
+// parseDay returns midnight of a "YYYY-MM-DD" day in the given location
+func parseDay(value string, location *time.Location) (time.Time, error) {
+	day, err := time.ParseInLocation(dateLayout, value, location)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	return day, nil
+}
+
+// atTime returns the instant a "HH:MM" wall-clock time is reached on day
+func atTime(day time.Time, clock string) (time.Time, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, errors.New("times must use the HH:MM format")
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), parsed.Hour(), parsed.Minute(), 0, 0, day.Location()), nil
+}
+
+// dayRange converts an inclusive range of days, defaulting to the next 30 days
+func dayRange(fromValue, toValue string, location *time.Location) (time.Time, time.Time, error) {
+	now := time.Now().In(location)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	if fromValue != "" {
+		var err error
+		if from, err = parseDay(fromValue, location); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	to := from.AddDate(0, 0, 30)
+	if toValue != "" {
+		day, err := parseDay(toValue, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = day.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
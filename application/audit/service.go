@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/domain/services"
+)
+
+// Page size limits for audit queries and exports
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	exportBatchSize = 1000
+	maxExportRows   = 100000
+)
+
+var ErrInvalidTime = errors.New("from and to must be RFC 3339 timestamps or YYYY-MM-DD dates")
+
+type AuditApplicationService struct {
+	auditService services.AuditService
+}
+
+func NewAuditApplicationService(auditService services.AuditService) *AuditApplicationService {
+	return &AuditApplicationService{auditService: auditService}
+}
+
+type SearchAuditEventsRequest struct {
+	ActorID      string `query:"actor_id"`
+	Action       string `query:"action"`
+	ResourceType string `query:"resource_type"`
+	ResourceID   string `query:"resource_id"`
+	Outcome      string `query:"outcome"`
+	RequestID    string `query:"request_id"`
+	From         string `query:"from"`
+	To           string `query:"to"`
+	Page         int    `query:"page"`
+	Limit        int    `query:"limit"`
+}
+
+type AuditEventListResponse struct {
+	Events []*entities.AuditEvent `json:"events"`
+	Total  int64                  `json:"total"`
+	Page   int                    `json:"page"`
+	Limit  int                    `json:"limit"`
+}
+
+// auditCSVHeader lists the exported columns, hashes included so an export
+// can be checked against the database later
+var auditCSVHeader = []string{
+	"sequence", "occurred_at", "actor_id", "actor_role", "action", "resource_type", "resource_id",
+	"outcome", "status_code", "method", "path", "ip_address", "request_id", "details", "prev_hash", "hash",
+}
+
+func (s *AuditApplicationService) SearchEvents(ctx context.Context, req SearchAuditEventsRequest) (*AuditEventListResponse, error) {
+	if req.Limit <= 0 {
+		req.Limit = defaultPageSize
+	}
+	if req.Limit > maxPageSize {
+		req.Limit = maxPageSize
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
+	criteria, err := req.criteria()
+	if err != nil {
+		return nil, err
+	}
+	criteria.Limit = req.Limit
+	criteria.Offset = (req.Page - 1) * req.Limit
+
+	events, total, err := s.auditService.Search(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditEventListResponse{
+		Events: events,
+		Total:  total,
+		Page:   req.Page,
+		Limit:  req.Limit,
+	}, nil
+}
+
+// ExportCSV writes the matching events to w, newest first. Events recorded
+// after the export started are left out so paging stays stable.
+func (s *AuditApplicationService) ExportCSV(ctx context.Context, req SearchAuditEventsRequest, w io.Writer) error {
+	criteria, err := req.criteria()
+	if err != nil {
+		return err
+	}
+	if criteria.To == nil {
+		now := time.Now()
+		criteria.To = &now
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(auditCSVHeader); err != nil {
+		return err
+	}
+
+	criteria.Limit = exportBatchSize
+	for criteria.Offset = 0; criteria.Offset < maxExportRows; criteria.Offset += exportBatchSize {
+		events, _, err := s.auditService.Search(ctx, criteria)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := writer.Write(auditCSVRow(event)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+
+		if len(events) < exportBatchSize {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *AuditApplicationService) Verify(ctx context.Context) (*services.AuditVerification, error) {
+	return s.auditService.Verify(ctx)
+}
+
+func (s *AuditApplicationService) VerifyAll(ctx context.Context) ([]*services.AuditVerification, error) {
+	return s.auditService.VerifyAll(ctx)
+}
+
+func (req SearchAuditEventsRequest) criteria() (repositories.AuditSearchCriteria, error) {
+	criteria := repositories.AuditSearchCriteria{
+		ActorID:      req.ActorID,
+		Action:       req.Action,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Outcome:      req.Outcome,
+		RequestID:    req.RequestID,
+	}
+
+	var err error
+	if criteria.From, err = parseTime(req.From, false); err != nil {
+		return criteria, err
+	}
+	if criteria.To, err = parseTime(req.To, true); err != nil {
+		return criteria, err
+	}
+	return criteria, nil
+}
+
+// parseTime accepts a timestamp or a UTC date. A date used as an upper bound
+// includes the whole day.
+func parseTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, ErrInvalidTime
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return &day, nil
+}
+
+func auditCSVRow(event *entities.AuditEvent) []string {
+	return []string{
+		strconv.FormatInt(event.Sequence, 10),
+		event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		event.ActorID,
+		event.ActorRole,
+		event.Action,
+		event.ResourceType,
+		event.ResourceID,
+		event.Outcome,
+		strconv.Itoa(event.StatusCode),
+		event.Method,
+		event.Path,
+		event.IPAddress,
+		event.RequestID,
+		event.Details,
+		event.PrevHash,
+		event.Hash,
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/services"
+)
+
+// recordLogin writes the outcome of a login step to the audit log and the
+// login metrics. Lockouts and disabled accounts are recorded as denied, every
+// other error as a failure.
+func (s *AuthApplicationService) recordLogin(ctx context.Context, action string, actor services.AuditActor, email string, user *entities.User, response *LoginResponse, err error) {
+	var resourceID string
+	if user != nil {
+		resourceID = user.ID
+		actor.UserID = user.ID
+		actor.Role = user.Role
+	}
+
+	outcome, metricOutcome := entities.AuditOutcomeSuccess, services.LoginSucceeded
+	details := fmt.Sprintf("email=%s", email)
+
+	var lockout *services.LockoutError
+	switch {
+	case errors.As(err, &lockout), errors.Is(err, services.ErrAccountDisabled):
+		outcome, metricOutcome = entities.AuditOutcomeDenied, services.LoginDenied
+		details += " error=" + err.Error()
+	case err != nil:
+		outcome, metricOutcome = entities.AuditOutcomeFailure, services.LoginFailed
+		details += " error=" + err.Error()
+	case response != nil && response.MFARequired:
+		metricOutcome = services.LoginMFARequired
+		details += " second factor required"
+	}
+	s.metrics.LoginAttempt(strings.TrimPrefix(action, "auth."), metricOutcome)
+
+	event := actor.Event(action, "user", resourceID, outcome)
+	event.Details = details
+	s.audit.Record(ctx, event)
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/services"
+	"medical-system/domain/tenancy"
+	"medical-system/infrastructure/auth"
+)
+
+var ErrInvalidMFAChallenge = errors.New("invalid or expired MFA challenge")
+
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recovery_code"`
+
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+	RequestID string `json:"-"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFAActivateResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Message       string   `json:"message"`
+}
+
+// BeginMFAEnrollment starts TOTP enrollment for a logged-in user
+func (s *AuthApplicationService) BeginMFAEnrollment(ctx context.Context, userID string) (*services.MFAEnrollment, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.BeginMFAEnrollment")
+	defer span.End()
+
+	return s.mfaService.BeginEnrollment(ctx, userID)
+}
+
+// BeginMFAEnrollmentWithChallenge starts TOTP enrollment for a user whose
+// tenant enforces MFA and who therefore cannot obtain an access token yet
+func (s *AuthApplicationService) BeginMFAEnrollmentWithChallenge(ctx context.Context, req MFAChallengeRequest) (*services.MFAEnrollment, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.BeginMFAEnrollmentWithChallenge")
+	defer span.End()
+
+	challenge, err := s.parseMFAChallenge(ctx, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.mfaService.BeginEnrollment(tenancy.WithTenant(ctx, challenge.user.TenantID), challenge.user.ID)
+}
+
+func (s *AuthApplicationService) ActivateMFA(ctx context.Context, userID string, req MFACodeRequest) (*MFAActivateResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.ActivateMFA")
+	defer span.End()
+
+	recoveryCodes, err := s.mfaService.ActivateEnrollment(ctx, userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAActivateResponse{
+		RecoveryCodes: recoveryCodes,
+		Message:       "Multi-factor authentication enabled, store the recovery codes in a safe place",
+	}, nil
+}
+
+func (s *AuthApplicationService) DisableMFA(ctx context.Context, userID string, req MFACodeRequest) error {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.DisableMFA")
+	defer span.End()
+
+	return s.mfaService.Disable(ctx, userID, req.Code)
+}
+
+// VerifyMFA completes a login: it exchanges the MFA challenge token and a
+// TOTP or recovery code for an access token. Users completing a pending
+// enrollment receive their recovery codes in the response.
+func (s *AuthApplicationService) VerifyMFA(ctx context.Context, req MFAVerifyRequest) (*LoginResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.VerifyMFA")
+	defer span.End()
+
+	challenge, err := s.parseMFAChallenge(ctx, req.MFAToken)
+	if err != nil {
+		// Without a valid challenge there is no tenant to attribute the attempt to
+		return nil, err
+	}
+	user := challenge.user
+
+	ctx = tenancy.WithTenant(ctx, user.TenantID)
+	response, err := s.verifyMFA(ctx, challenge, req)
+
+	actor := services.AuditActor{
+		TenantID:  user.TenantID,
+		IPAddress: req.IPAddress,
+		RequestID: req.RequestID,
+	}
+	s.recordLogin(ctx, "auth.mfa_verify", actor, user.Email, user, response, err)
+
+	return response, err
+}
+
+func (s *AuthApplicationService) verifyMFA(ctx context.Context, challenge *mfaChallenge, req MFAVerifyRequest) (*LoginResponse, error) {
+	user := challenge.user
+	if err := s.throttle.Attempt(ctx, user.Email, req.IPAddress); err != nil {
+		return nil, err
+	}
+
+	var err error
+	var recoveryCodes []string
+	switch {
+	case !user.MFAEnabled:
+		recoveryCodes, err = s.mfaService.ActivateEnrollment(ctx, user.ID, req.Code)
+	case req.RecoveryCode != "":
+		err = s.mfaService.VerifyRecoveryCode(ctx, user.ID, req.RecoveryCode)
+	default:
+		err = s.mfaService.VerifyCode(ctx, user.ID, req.Code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A challenge completes one login only
+	claimed, err := s.usedTokenRepo.Claim(ctx, challenge.token)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err := s.usedTokenRepo.DeleteExpired(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.throttle.RecordSuccess(ctx, user.Email, req.IPAddress); err != nil {
+		return nil, err
+	}
+
+	response, err := s.startSession(ctx, user, req.UserAgent, req.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+	response.RecoveryCodes = recoveryCodes
+	return response, nil
+}
+
+// mfaChallenge is a valid MFA challenge token
+type mfaChallenge struct {
+	user *entities.User
+	// token is recorded as used once the challenge completes a login
+	token *entities.UsedToken
+}
+
+// parseMFAChallenge returns the challenge with the user it was issued to,
+// looked up in the tenant named by the challenge
+func (s *AuthApplicationService) parseMFAChallenge(ctx context.Context, token string) (*mfaChallenge, error) {
+	claims, err := s.tokenGen.ValidateToken(token)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	if tokenType, _ := (*claims)["typ"].(string); tokenType != auth.TokenTypeMFAChallenge {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	challengeID, _ := (*claims)["jti"].(string)
+	expiresAt, _ := (*claims)["exp"].(float64)
+	if challengeID == "" {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	userID, _ := (*claims)["user_id"].(string)
+	tenantID, _ := (*claims)["tenant_id"].(string)
+
+	user, err := s.userRepo.FindByID(tenancy.WithTenant(ctx, tenantID), userID)
+	if err != nil || !user.IsActive {
+		return nil, ErrInvalidMFAChallenge
+	}
+	return &mfaChallenge{
+		user: user,
+		token: &entities.UsedToken{
+			ID:        challengeID,
+			TenantID:  user.TenantID,
+			ExpiresAt: time.Unix(int64(expiresAt), 0),
+		},
+	}, nil
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+
+	"medical-system/domain/tenancy"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+type PasswordResetRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	TenantID string `json:"tenant_id" validate:"required,uuid"`
+
+	// Filled in by the handler
+	IPAddress string `json:"-"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+// ChangePassword keeps the session the change is made from signed in
+func (s *AuthApplicationService) ChangePassword(ctx context.Context, userID, sessionID string, req ChangePasswordRequest) error {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.ChangePassword")
+	defer span.End()
+
+	return s.authService.ChangePassword(ctx, userID, sessionID, req.CurrentPassword, req.NewPassword)
+}
+
+func (s *AuthApplicationService) RequestPasswordReset(ctx context.Context, req PasswordResetRequest) error {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.RequestPasswordReset")
+	defer span.End()
+
+	ctx = tenancy.WithTenant(ctx, req.TenantID)
+	if err := s.throttle.AttemptPasswordReset(ctx, req.Email, req.IPAddress); err != nil {
+		return err
+	}
+	return s.authService.RequestPasswordReset(ctx, req.Email)
+}
+
+func (s *AuthApplicationService) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.ResetPassword")
+	defer span.End()
+
+	return s.authService.ResetPassword(ctx, req.Token, req.NewPassword)
+}
@@ -1,16 +1,21 @@
 package auth
 
 import (
+	"context"
+
 	"medical-system/domain/entities"
+	"medical-system/domain/tenancy"
 )
 
+// RegisterRequest is public, so it may only ask for the user role; tenant
+// admins hand out the others
 type RegisterRequest struct {
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	TenantID  string `json:"tenant_id"`
-	Role      string `json:"role"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required,min=8"`
+	TenantID  string `json:"tenant_id" validate:"required,uuid"`
+	Role      string `json:"role" validate:"omitempty,oneof=user"`
+	FirstName string `json:"first_name" validate:"max=100"`
+	LastName  string `json:"last_name" validate:"max=100"`
 }
 
 type RegisterResponse struct {
@@ -19,9 +24,9 @@ type RegisterResponse struct {
 }
 
 type UpdateProfileRequest struct {
-	Email     string `json:"email"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
+	Email     string `json:"email" validate:"required,email"`
+	FirstName string `json:"first_name" validate:"max=100"`
+	LastName  string `json:"last_name" validate:"max=100"`
 }
 
 type UpdateProfileResponse struct {
@@ -29,27 +34,37 @@ type UpdateProfileResponse struct {
 	Message string         `json:"message"`
 }
 
-func (s *AuthApplicationService) Register(req RegisterRequest) (*RegisterResponse, error) {
+func (s *AuthApplicationService) Register(ctx context.Context, req RegisterRequest) (*RegisterResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.Register")
+	defer span.End()
+
 	user := &entities.User{
 		Email:    req.Email,
 		TenantID: req.TenantID,
-		Role:     req.Role,
+		Role:     entities.RoleUser,
 		IsActive: true,
 	}
 
-	err := s.authService.RegisterUser(user, req.Password)
+	err := s.authService.RegisterUser(tenancy.WithTenant(ctx, req.TenantID), user, req.Password)
 	if err != nil {
 		return nil, err
 	}
 
+	if _, err := s.rbacEnforcer.AddRoleForUser(user.ID, user.Role, user.TenantID); err != nil {
+		return nil, err
+	}
+
 	return &RegisterResponse{
 		User:    user,
 		Message: "User registered successfully",
 	}, nil
 }
 
-func (s *AuthApplicationService) UpdateProfile(userID string, req UpdateProfileRequest) (*UpdateProfileResponse, error) {
-	user, err := s.authService.UpdateProfile(userID, req.FirstName, req.LastName, req.Email)
+func (s *AuthApplicationService) UpdateProfile(ctx context.Context, userID string, req UpdateProfileRequest) (*UpdateProfileResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.UpdateProfile")
+	defer span.End()
+
+	user, err := s.authService.UpdateProfile(ctx, userID, req.FirstName, req.LastName, req.Email)
 	if err != nil {
 		return nil, err
 	}
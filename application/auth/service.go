@@ -1,61 +1,249 @@
 package auth
 
 import (
+	"context"
+	"errors"
+
 	"medical-system/domain/entities"
 	"medical-system/domain/repositories"
 	"medical-system/domain/services"
+	"medical-system/domain/tenancy"
 	"medical-system/infrastructure/auth"
+
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("medical-system/application/auth")
+
+var ErrUserNotFound = errors.New("user not found")
+
 type AuthApplicationService struct {
-	userRepo     repositories.UserRepository
-	authService  services.AuthService
-	tokenGen     auth.TokenGenerator
-	rbacEnforcer *auth.CasbinEnforcer
+	userRepo       repositories.UserRepository
+	usedTokenRepo  repositories.UsedTokenRepository
+	authService    services.AuthService
+	sessionService services.SessionService
+	mfaService     services.MFAService
+	throttle       services.LoginThrottleService
+	tokenGen       auth.TokenGenerator
+	rbacEnforcer   *auth.CasbinEnforcer
+	audit          services.AuditRecorder
+	metrics        services.BusinessMetrics
 }
 
 func NewAuthApplicationService(
 	userRepo repositories.UserRepository,
+	usedTokenRepo repositories.UsedTokenRepository,
 	authService services.AuthService,
+	sessionService services.SessionService,
+	mfaService services.MFAService,
+	throttle services.LoginThrottleService,
 	tokenGen auth.TokenGenerator,
 	rbacEnforcer *auth.CasbinEnforcer,
+	audit services.AuditRecorder,
+	metrics services.BusinessMetrics,
 ) *AuthApplicationService {
 	return &AuthApplicationService{
-		userRepo:     userRepo,
-		authService:  authService,
-		tokenGen:     tokenGen,
-		rbacEnforcer: rbacEnforcer,
+		userRepo:       userRepo,
+		usedTokenRepo:  usedTokenRepo,
+		authService:    authService,
+		sessionService: sessionService,
+		mfaService:     mfaService,
+		throttle:       throttle,
+		tokenGen:       tokenGen,
+		rbacEnforcer:   rbacEnforcer,
+		audit:          audit,
+		metrics:        metrics,
 	}
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	TenantID string `json:"tenant_id"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	TenantID string `json:"tenant_id" validate:"required,uuid"`
+
+	// Client metadata recorded on the session, filled in by the handler
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+	RequestID string `json:"-"`
 }
 
 type LoginResponse struct {
-	User        *entities.User `json:"user"`
-	AccessToken string         `json:"access_token"`
-	Permissions []string       `json:"permissions"`
+	User         *entities.User `json:"user"`
+	AccessToken  string         `json:"access_token"`
+	RefreshToken string         `json:"refresh_token"`
+	ExpiresIn    int64          `json:"expires_in"`
+	Permissions  []string       `json:"permissions"`
+
+	// Set instead of the tokens when a second factor is needed: the client
+	// exchanges MFAToken and a TOTP code at /api/auth/mfa/verify
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
+
+	// Returned once, when MFA enrollment is completed during login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type UnlockUserResponse struct {
+	UserID  string `json:"user_id"`
+	Message string `json:"message"`
+}
+
+type RevokeSessionsResponse struct {
+	RevokedSessions int64  `json:"revoked_sessions"`
+	Message         string `json:"message"`
+}
+
+// Login acts for the tenant named in the request, which the caller is not
+// yet authenticated for
+func (s *AuthApplicationService) Login(ctx context.Context, req LoginRequest) (*LoginResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.Login")
+	defer span.End()
+
+	ctx = tenancy.WithTenant(ctx, req.TenantID)
+	user, response, err := s.login(ctx, req)
+
+	actor := services.AuditActor{
+		TenantID:  req.TenantID,
+		IPAddress: req.IPAddress,
+		RequestID: req.RequestID,
+	}
+	s.recordLogin(ctx, "auth.login", actor, req.Email, user, response, err)
+
+	return response, err
+}
+
+// login returns the user whenever the credentials identified one, so that
+// failed attempts can be attributed in the audit log
+func (s *AuthApplicationService) login(ctx context.Context, req LoginRequest) (*entities.User, *LoginResponse, error) {
+	if err := s.throttle.Attempt(ctx, req.Email, req.IPAddress); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.authService.VerifyCredentials(ctx, req.Email, req.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.throttle.RecordSuccess(ctx, req.Email, req.IPAddress); err != nil {
+		return user, nil, err
+	}
+
+	mfaRequired, err := s.mfaService.IsRequired(ctx, user)
+	if err != nil {
+		return user, nil, err
+	}
+	if mfaRequired {
+		challenge, err := s.tokenGen.GenerateMFAChallengeToken(user)
+		if err != nil {
+			return user, nil, err
+		}
+
+		return user, &LoginResponse{
+			MFARequired:           true,
+			MFAEnrollmentRequired: !user.MFAEnabled,
+			MFAToken:              challenge,
+		}, nil
+	}
+
+	response, err := s.startSession(ctx, user, req.UserAgent, req.IPAddress)
+	return user, response, err
+}
+
+// Refresh rotates the refresh token and issues a new access token for the same session
+func (s *AuthApplicationService) Refresh(ctx context.Context, req RefreshRequest) (*LoginResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.Refresh")
+	defer span.End()
+
+	session, user, refreshToken, err := s.sessionService.RotateRefreshToken(ctx, req.RefreshToken, req.UserAgent, req.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session, refreshToken)
+}
+
+// Logout revokes the session the current access token belongs to
+func (s *AuthApplicationService) Logout(ctx context.Context, sessionID string) error {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.Logout")
+	defer span.End()
+
+	return s.sessionService.RevokeSession(ctx, sessionID)
 }
 
-func (s *AuthApplicationService) Login(req LoginRequest) (*LoginResponse, error) {
-	user, err := s.authService.VerifyCredentials(req.Email, req.Password, req.TenantID)
+// RevokeUserSessions signs a user of the current tenant out of every device
+func (s *AuthApplicationService) RevokeUserSessions(ctx context.Context, userID string) (*RevokeSessionsResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.RevokeUserSessions")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	revoked, err := s.sessionService.RevokeAllUserSessions(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	token, err := s.tokenGen.GenerateToken(user)
+	return &RevokeSessionsResponse{
+		RevokedSessions: revoked,
+		Message:         "User sessions revoked successfully",
+	}, nil
+}
+
+func (s *AuthApplicationService) startSession(ctx context.Context, user *entities.User, userAgent, ipAddress string) (*LoginResponse, error) {
+	session, refreshToken, err := s.sessionService.CreateSession(ctx, user, userAgent, ipAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	s.rbacEnforcer.AddRoleForUser(user.ID, user.Role, user.TenantID)
+	return s.issueTokens(user, session, refreshToken)
+}
+
+// UnlockUser lifts a login lockout of a user of the current tenant
+func (s *AuthApplicationService) UnlockUser(ctx context.Context, userID string) (*UnlockUserResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthApplicationService.UnlockUser")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.throttle.Unlock(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	return &UnlockUserResponse{
+		UserID:  user.ID,
+		Message: "User unlocked successfully",
+	}, nil
+}
+
+func (s *AuthApplicationService) issueTokens(user *entities.User, session *entities.Session, refreshToken string) (*LoginResponse, error) {
+	token, err := s.tokenGen.GenerateToken(user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.rbacEnforcer.GetUserPermissions(user.ID, user.TenantID)
+	if err != nil {
+		return nil, err
+	}
 
 	return &LoginResponse{
-		User:        user,
-		AccessToken: token,
-		Permissions: []string{},
+		User:         user,
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(auth.AccessTokenTTL.Seconds()),
+		Permissions:  permissions,
 	}, nil
 }
@@ -0,0 +1,234 @@
+package encounters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/services"
+)
+
+type EncounterApplicationService struct {
+	encounterService services.EncounterService
+	audit            services.AuditRecorder
+}
+
+func NewEncounterApplicationService(encounterService services.EncounterService, audit services.AuditRecorder) *EncounterApplicationService {
+	return &EncounterApplicationService{
+		encounterService: encounterService,
+		audit:            audit,
+	}
+}
+
+type SOAPNotes struct {
+	Subjective string `json:"subjective"`
+	Objective  string `json:"objective"`
+	Assessment string `json:"assessment"`
+	Plan       string `json:"plan"`
+}
+
+type DiagnosisRequest struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	IsPrimary   bool   `json:"is_primary"`
+}
+
+// EncounterRequest opens or edits a draft encounter. ProviderID defaults to
+// the current user and is ignored on edits.
+type EncounterRequest struct {
+	PatientID     string             `json:"patient_id"`
+	ProviderID    string             `json:"provider_id"`
+	AppointmentID *string            `json:"appointment_id"`
+	Notes         SOAPNotes          `json:"notes"`
+	Vitals        entities.Vitals    `json:"vitals"`
+	Diagnoses     []DiagnosisRequest `json:"diagnoses"`
+}
+
+type AmendEncounterRequest struct {
+	Section string `json:"section"`
+	Text    string `json:"text"`
+	Reason  string `json:"reason"`
+}
+
+type DiagnosisResponse struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	IsPrimary   bool   `json:"is_primary"`
+}
+
+type AmendmentResponse struct {
+	ID           string    `json:"id"`
+	Section      string    `json:"section"`
+	OriginalText string    `json:"original_text"`
+	AmendedText  string    `json:"amended_text"`
+	Reason       string    `json:"reason"`
+	AmendedBy    string    `json:"amended_by"`
+	AmendedAt    time.Time `json:"amended_at"`
+}
+
+// EncounterResponse shows the notes with all amendments applied. For signed
+// encounters SignedNotes holds the notes exactly as they were signed.
+type EncounterResponse struct {
+	ID            string              `json:"id"`
+	PatientID     string              `json:"patient_id"`
+	ProviderID    string              `json:"provider_id"`
+	AppointmentID *string             `json:"appointment_id"`
+	Status        string              `json:"status"`
+	Notes         SOAPNotes           `json:"notes"`
+	SignedNotes   *SOAPNotes          `json:"signed_notes,omitempty"`
+	Vitals        entities.Vitals     `json:"vitals"`
+	Diagnoses     []DiagnosisResponse `json:"diagnoses"`
+	Amendments    []AmendmentResponse `json:"amendments"`
+	SignedAt      *time.Time          `json:"signed_at,omitempty"`
+	SignedBy      string              `json:"signed_by,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+func (s *EncounterApplicationService) CreateEncounter(ctx context.Context, userID string, req EncounterRequest) (*EncounterResponse, error) {
+	encounter := req.toEntity()
+	encounter.ProviderID = req.ProviderID
+	if encounter.ProviderID == "" {
+		encounter.ProviderID = userID
+	}
+
+	if err := s.encounterService.CreateEncounter(ctx, encounter); err != nil {
+		return nil, err
+	}
+	return toEncounterResponse(encounter), nil
+}
+
+func (s *EncounterApplicationService) GetEncounter(ctx context.Context, id string) (*EncounterResponse, error) {
+	encounter, err := s.encounterService.GetEncounter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toEncounterResponse(encounter), nil
+}
+
+func (s *EncounterApplicationService) ListPatientEncounters(ctx context.Context, patientID string) ([]EncounterResponse, error) {
+	encounters, err := s.encounterService.ListPatientEncounters(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]EncounterResponse, 0, len(encounters))
+	for _, encounter := range encounters {
+		response = append(response, *toEncounterResponse(encounter))
+	}
+	return response, nil
+}
+
+func (s *EncounterApplicationService) UpdateDraft(ctx context.Context, id string, req EncounterRequest) (*EncounterResponse, error) {
+	encounter := req.toEntity()
+	encounter.ID = id
+
+	if err := s.encounterService.UpdateDraft(ctx, encounter); err != nil {
+		return nil, err
+	}
+	return s.GetEncounter(ctx, id)
+}
+
+func (s *EncounterApplicationService) SignEncounter(ctx context.Context, id string) (*EncounterResponse, error) {
+	actor := services.ActorFromContext(ctx)
+	encounter, err := s.encounterService.SignEncounter(ctx, id, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := actor.Event("encounter.sign", "encounter", encounter.ID, entities.AuditOutcomeSuccess)
+	event.Details = fmt.Sprintf("patient_id=%s", encounter.PatientID)
+	s.audit.Record(ctx, event)
+
+	return toEncounterResponse(encounter), nil
+}
+
+func (s *EncounterApplicationService) AmendEncounter(ctx context.Context, id string, req AmendEncounterRequest) (*EncounterResponse, error) {
+	actor := services.ActorFromContext(ctx)
+	encounter, err := s.encounterService.AmendEncounter(ctx, id, req.Section, req.Text, req.Reason, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	amendment := encounter.Amendments[len(encounter.Amendments)-1]
+	event := actor.Event("encounter.amend", "encounter", encounter.ID, entities.AuditOutcomeSuccess)
+	event.Details = fmt.Sprintf("patient_id=%s section=%s amendment_id=%s", encounter.PatientID, amendment.Section, amendment.ID)
+	s.audit.Record(ctx, event)
+
+	return toEncounterResponse(encounter), nil
+}
+
+func (req EncounterRequest) toEntity() *entities.Encounter {
+	encounter := &entities.Encounter{
+		PatientID:     req.PatientID,
+		AppointmentID: req.AppointmentID,
+		Subjective:    req.Notes.Subjective,
+		Objective:     req.Notes.Objective,
+		Assessment:    req.Notes.Assessment,
+		Plan:          req.Notes.Plan,
+		Vitals:        req.Vitals,
+	}
+	for _, diagnosis := range req.Diagnoses {
+		encounter.Diagnoses = append(encounter.Diagnoses, entities.Diagnosis{
+			Code:        diagnosis.Code,
+			Description: diagnosis.Description,
+			IsPrimary:   diagnosis.IsPrimary,
+		})
+	}
+	return encounter
+}
+
+func toEncounterResponse(encounter *entities.Encounter) *EncounterResponse {
+	response := &EncounterResponse{
+		ID:            encounter.ID,
+		PatientID:     encounter.PatientID,
+		ProviderID:    encounter.ProviderID,
+		AppointmentID: encounter.AppointmentID,
+		Status:        string(encounter.Status),
+		Notes: SOAPNotes{
+			Subjective: encounter.CurrentText(entities.SectionSubjective),
+			Objective:  encounter.CurrentText(entities.SectionObjective),
+			Assessment: encounter.CurrentText(entities.SectionAssessment),
+			Plan:       encounter.CurrentText(entities.SectionPlan),
+		},
+		Vitals:     encounter.Vitals,
+		Diagnoses:  make([]DiagnosisResponse, 0, len(encounter.Diagnoses)),
+		Amendments: make([]AmendmentResponse, 0, len(encounter.Amendments)),
+		SignedAt:   encounter.SignedAt,
+		SignedBy:   encounter.SignedBy,
+		CreatedAt:  encounter.CreatedAt,
+		UpdatedAt:  encounter.UpdatedAt,
+	}
+
+	if encounter.IsSigned() {
+		response.SignedNotes = &SOAPNotes{
+			Subjective: encounter.Subjective,
+			Objective:  encounter.Objective,
+			Assessment: encounter.Assessment,
+			Plan:       encounter.Plan,
+		}
+	}
+
+	for _, diagnosis := range encounter.Diagnoses {
+		response.Diagnoses = append(response.Diagnoses, DiagnosisResponse{
+			Code:        diagnosis.Code,
+			Description: diagnosis.Description,
+			IsPrimary:   diagnosis.IsPrimary,
+		})
+	}
+
+	for _, amendment := range encounter.Amendments {
+		response.Amendments = append(response.Amendments, AmendmentResponse{
+			ID:           amendment.ID,
+			Section:      amendment.Section,
+			OriginalText: amendment.OriginalText,
+			AmendedText:  amendment.AmendedText,
+			Reason:       amendment.Reason,
+			AmendedBy:    amendment.AmendedBy,
+			AmendedAt:    amendment.AmendedAt,
+		})
+	}
+
+	return response
+}
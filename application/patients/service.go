@@ -0,0 +1,354 @@
+package patients
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/domain/services"
+)
+
+// dateLayout is the format used for dates in patient requests and responses
+const dateLayout = "2006-01-02"
+
+// Page size limits for patient searches
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var ErrInvalidDate = errors.New("dates must use the YYYY-MM-DD format")
+
+type PatientApplicationService struct {
+	patientService services.PatientService
+}
+
+func NewPatientApplicationService(patientService services.PatientService) *PatientApplicationService {
+	return &PatientApplicationService{patientService: patientService}
+}
+
+type EmergencyContactRequest struct {
+	Name         string `json:"name"`
+	Relationship string `json:"relationship"`
+	Phone        string `json:"phone"`
+	Email        string `json:"email"`
+}
+
+type InsurancePolicyRequest struct {
+	Provider     string `json:"provider"`
+	PolicyNumber string `json:"policy_number"`
+	GroupNumber  string `json:"group_number"`
+	HolderName   string `json:"holder_name"`
+	ValidFrom    string `json:"valid_from"`
+	ValidUntil   string `json:"valid_until"`
+	IsPrimary    bool   `json:"is_primary"`
+}
+
+// PatientRequest is used to register and update patients. Set AllowDuplicate
+// to register a patient whose name and date of birth match an existing one.
+type PatientRequest struct {
+	MRN               string                    `json:"mrn"`
+	NationalID        string                    `json:"national_id"`
+	FirstName         string                    `json:"first_name"`
+	LastName          string                    `json:"last_name"`
+	DateOfBirth       string                    `json:"date_of_birth"`
+	Sex               string                    `json:"sex"`
+	Email             string                    `json:"email"`
+	Phone             string                    `json:"phone"`
+	AddressLine1      string                    `json:"address_line1"`
+	AddressLine2      string                    `json:"address_line2"`
+	City              string                    `json:"city"`
+	State             string                    `json:"state"`
+	PostalCode        string                    `json:"postal_code"`
+	Country           string                    `json:"country"`
+	EmergencyContacts []EmergencyContactRequest `json:"emergency_contacts"`
+	InsurancePolicies []InsurancePolicyRequest  `json:"insurance_policies"`
+	AllowDuplicate    bool                      `json:"allow_duplicate"`
+}
+
+type EmergencyContactResponse struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Relationship string `json:"relationship"`
+	Phone        string `json:"phone"`
+	Email        string `json:"email"`
+}
+
+type InsurancePolicyResponse struct {
+	ID           string `json:"id"`
+	Provider     string `json:"provider"`
+	PolicyNumber string `json:"policy_number"`
+	GroupNumber  string `json:"group_number"`
+	HolderName   string `json:"holder_name"`
+	ValidFrom    string `json:"valid_from,omitempty"`
+	ValidUntil   string `json:"valid_until,omitempty"`
+	IsPrimary    bool   `json:"is_primary"`
+}
+
+type PatientResponse struct {
+	ID                string                     `json:"id"`
+	MRN               string                     `json:"mrn"`
+	NationalID        string                     `json:"national_id"`
+	FirstName         string                     `json:"first_name"`
+	LastName          string                     `json:"last_name"`
+	DateOfBirth       string                     `json:"date_of_birth"`
+	Sex               string                     `json:"sex"`
+	Email             string                     `json:"email"`
+	Phone             string                     `json:"phone"`
+	AddressLine1      string                     `json:"address_line1"`
+	AddressLine2      string                     `json:"address_line2"`
+	City              string                     `json:"city"`
+	State             string                     `json:"state"`
+	PostalCode        string                     `json:"postal_code"`
+	Country           string                     `json:"country"`
+	EmergencyContacts []EmergencyContactResponse `json:"emergency_contacts,omitempty"`
+	InsurancePolicies []InsurancePolicyResponse  `json:"insurance_policies,omitempty"`
+	IsActive          bool                       `json:"is_active"`
+	CreatedAt         time.Time                  `json:"created_at"`
+	UpdatedAt         time.Time                  `json:"updated_at"`
+}
+
+type SearchPatientsRequest struct {
+	Query           string `query:"q"`
+	DateOfBirth     string `query:"date_of_birth"`
+	IncludeInactive bool   `query:"include_inactive"`
+	Page            int    `query:"page"`
+	Limit           int    `query:"limit"`
+}
+
+type PatientListResponse struct {
+	Patients []PatientResponse `json:"patients"`
+	Total    int64             `json:"total"`
+	Page     int               `json:"page"`
+	Limit    int               `json:"limit"`
+}
+
+func (s *PatientApplicationService) RegisterPatient(ctx context.Context, req PatientRequest) (*PatientResponse, error) {
+	patient, err := req.toEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.patientService.RegisterPatient(ctx, patient, req.AllowDuplicate); err != nil {
+		return nil, err
+	}
+
+	return toPatientResponse(patient), nil
+}
+
+func (s *PatientApplicationService) GetPatient(ctx context.Context, id string) (*PatientResponse, error) {
+	patient, err := s.patientService.GetPatient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toPatientResponse(patient), nil
+}
+
+func (s *PatientApplicationService) UpdatePatient(ctx context.Context, id string, req PatientRequest) (*PatientResponse, error) {
+	existing, err := s.patientService.GetPatient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	patient, err := req.toEntity()
+	if err != nil {
+		return nil, err
+	}
+	patient.ID = existing.ID
+	patient.TenantID = existing.TenantID
+	patient.IsActive = existing.IsActive
+	patient.CreatedAt = existing.CreatedAt
+	if patient.MRN == "" {
+		patient.MRN = existing.MRN
+	}
+
+	if err := s.patientService.UpdatePatient(ctx, patient, req.AllowDuplicate); err != nil {
+		return nil, err
+	}
+
+	return toPatientResponse(patient), nil
+}
+
+func (s *PatientApplicationService) ArchivePatient(ctx context.Context, id string) error {
+	return s.patientService.ArchivePatient(ctx, id)
+}
+
+func (s *PatientApplicationService) SearchPatients(ctx context.Context, req SearchPatientsRequest) (*PatientListResponse, error) {
+	if req.Limit <= 0 {
+		req.Limit = defaultPageSize
+	}
+	if req.Limit > maxPageSize {
+		req.Limit = maxPageSize
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
+	criteria := repositories.PatientSearchCriteria{
+		Query:           req.Query,
+		IncludeInactive: req.IncludeInactive,
+		Limit:           req.Limit,
+		Offset:          (req.Page - 1) * req.Limit,
+	}
+	if req.DateOfBirth != "" {
+		dob, err := parseDate(req.DateOfBirth)
+		if err != nil {
+			return nil, err
+		}
+		criteria.DateOfBirth = dob
+	}
+
+	patients, total, err := s.patientService.SearchPatients(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PatientListResponse{
+		Patients: make([]PatientResponse, 0, len(patients)),
+		Total:    total,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	}
+	for _, patient := range patients {
+		response.Patients = append(response.Patients, *toPatientResponse(patient))
+	}
+	return response, nil
+}
+
+// ToPatientResponses converts duplicate candidates for error responses
+func ToPatientResponses(patients []*entities.Patient) []PatientResponse {
+	responses := make([]PatientResponse, 0, len(patients))
+	for _, patient := range patients {
+		responses = append(responses, *toPatientResponse(patient))
+	}
+	return responses
+}
+
+func (req PatientRequest) toEntity() (*entities.Patient, error) {
+	patient := &entities.Patient{
+		MRN:          req.MRN,
+		NationalID:   req.NationalID,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Sex:          req.Sex,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		AddressLine1: req.AddressLine1,
+		AddressLine2: req.AddressLine2,
+		City:         req.City,
+		State:        req.State,
+		PostalCode:   req.PostalCode,
+		Country:      req.Country,
+	}
+
+	if req.DateOfBirth != "" {
+		dob, err := parseDate(req.DateOfBirth)
+		if err != nil {
+			return nil, err
+		}
+		patient.DateOfBirth = *dob
+	}
+
+	for _, contact := range req.EmergencyContacts {
+		patient.EmergencyContacts = append(patient.EmergencyContacts, entities.EmergencyContact{
+			Name:         contact.Name,
+			Relationship: contact.Relationship,
+			Phone:        contact.Phone,
+			Email:        contact.Email,
+		})
+	}
+
+	for _, policy := range req.InsurancePolicies {
+		validFrom, err := parseOptionalDate(policy.ValidFrom)
+		if err != nil {
+			return nil, err
+		}
+		validUntil, err := parseOptionalDate(policy.ValidUntil)
+		if err != nil {
+			return nil, err
+		}
+		patient.InsurancePolicies = append(patient.InsurancePolicies, entities.InsurancePolicy{
+			Provider:     policy.Provider,
+			PolicyNumber: policy.PolicyNumber,
+			GroupNumber:  policy.GroupNumber,
+			HolderName:   policy.HolderName,
+			ValidFrom:    validFrom,
+			ValidUntil:   validUntil,
+			IsPrimary:    policy.IsPrimary,
+		})
+	}
+
+	return patient, nil
+}
+
+func toPatientResponse(patient *entities.Patient) *PatientResponse {
+	response := &PatientResponse{
+		ID:           patient.ID,
+		MRN:          patient.MRN,
+		NationalID:   patient.NationalID,
+		FirstName:    patient.FirstName,
+		LastName:     patient.LastName,
+		DateOfBirth:  patient.DateOfBirth.Format(dateLayout),
+		Sex:          patient.Sex,
+		Email:        patient.Email,
+		Phone:        patient.Phone,
+		AddressLine1: patient.AddressLine1,
+		AddressLine2: patient.AddressLine2,
+		City:         patient.City,
+		State:        patient.State,
+		PostalCode:   patient.PostalCode,
+		Country:      patient.Country,
+		IsActive:     patient.IsActive,
+		CreatedAt:    patient.CreatedAt,
+		UpdatedAt:    patient.UpdatedAt,
+	}
+
+	for _, contact := range patient.EmergencyContacts {
+		response.EmergencyContacts = append(response.EmergencyContacts, EmergencyContactResponse{
+			ID:           contact.ID,
+			Name:         contact.Name,
+			Relationship: contact.Relationship,
+			Phone:        contact.Phone,
+			Email:        contact.Email,
+		})
+	}
+
+	for _, policy := range patient.InsurancePolicies {
+		response.InsurancePolicies = append(response.InsurancePolicies, InsurancePolicyResponse{
+			ID:           policy.ID,
+			Provider:     policy.Provider,
+			PolicyNumber: policy.PolicyNumber,
+			GroupNumber:  policy.GroupNumber,
+			HolderName:   policy.HolderName,
+			ValidFrom:    formatOptionalDate(policy.ValidFrom),
+			ValidUntil:   formatOptionalDate(policy.ValidUntil),
+			IsPrimary:    policy.IsPrimary,
+		})
+	}
+
+	return response
+}
+
+func parseDate(value string) (*time.Time, error) {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, ErrInvalidDate
+	}
+	return &date, nil
+}
+
+func parseOptionalDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return parseDate(value)
+}
+
+func formatOptionalDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(dateLayout)
+}
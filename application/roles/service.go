@@ -0,0 +1,251 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/infrastructure/auth"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+	ErrSystemRole   = errors.New("built-in roles cannot be deleted")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+type RoleApplicationService struct {
+	roleRepo     repositories.RoleRepository
+	userRepo     repositories.UserRepository
+	rbacEnforcer *auth.CasbinEnforcer
+}
+
+func NewRoleApplicationService(
+	roleRepo repositories.RoleRepository,
+	userRepo repositories.UserRepository,
+	rbacEnforcer *auth.CasbinEnforcer,
+) *RoleApplicationService {
+	return &RoleApplicationService{
+		roleRepo:     roleRepo,
+		userRepo:     userRepo,
+		rbacEnforcer: rbacEnforcer,
+	}
+}
+
+type CreateRoleRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type PermissionRequest struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type PermissionResponse struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+type RoleResponse struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	IsSystem    bool                 `json:"is_system"`
+	Permissions []PermissionResponse `json:"permissions"`
+}
+
+type UserRolesResponse struct {
+	UserID      string   `json:"user_id"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+func (s *RoleApplicationService) ListRoles(ctx context.Context) ([]*RoleResponse, error) {
+	roles, err := s.roleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		response, err := s.toRoleResponse(role)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (s *RoleApplicationService) CreateRole(ctx context.Context, req CreateRoleRequest) (*RoleResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("role name cannot be empty")
+	}
+
+	if _, err := s.roleRepo.FindByName(ctx, name); err == nil {
+		return nil, ErrRoleExists
+	}
+
+	role := &entities.Role{
+		Name:        name,
+		Description: req.Description,
+	}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	return s.toRoleResponse(role)
+}
+
+func (s *RoleApplicationService) DeleteRole(ctx context.Context, name string) error {
+	role, err := s.roleRepo.FindByName(ctx, name)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	if role.IsSystem {
+		return ErrSystemRole
+	}
+
+	// The record goes first; should the policies fail to go, it is put back
+	// and the role is left as it was
+	if err := s.roleRepo.Delete(ctx, role.Name); err != nil {
+		return err
+	}
+	if err := s.rbacEnforcer.DeleteRole(role.Name, role.TenantID); err != nil {
+		if restoreErr := s.roleRepo.Create(ctx, role); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *RoleApplicationService) GrantPermission(ctx context.Context, roleName string, req PermissionRequest) (*RoleResponse, error) {
+	role, err := s.roleRepo.FindByName(ctx, roleName)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	resource, action, err := normalizePermission(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.rbacEnforcer.GrantPermission(role.Name, role.TenantID, resource, action); err != nil {
+		return nil, err
+	}
+	return s.toRoleResponse(role)
+}
+
+func (s *RoleApplicationService) RevokePermission(ctx context.Context, roleName string, req PermissionRequest) (*RoleResponse, error) {
+	role, err := s.roleRepo.FindByName(ctx, roleName)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	resource, action, err := normalizePermission(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.rbacEnforcer.RevokePermission(role.Name, role.TenantID, resource, action); err != nil {
+		return nil, err
+	}
+	return s.toRoleResponse(role)
+}
+
+func (s *RoleApplicationService) GetUserRoles(ctx context.Context, userID string) (*UserRolesResponse, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toUserRolesResponse(user)
+}
+
+func (s *RoleApplicationService) AssignRole(ctx context.Context, userID string, req AssignRoleRequest) (*UserRolesResponse, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.roleRepo.FindByName(ctx, strings.TrimSpace(req.Role))
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	if _, err := s.rbacEnforcer.AddRoleForUser(user.ID, role.Name, user.TenantID); err != nil {
+		return nil, err
+	}
+	return s.toUserRolesResponse(user)
+}
+
+func (s *RoleApplicationService) RemoveRole(ctx context.Context, userID, roleName string) (*UserRolesResponse, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.rbacEnforcer.RemoveRoleForUser(user.ID, roleName, user.TenantID); err != nil {
+		return nil, err
+	}
+	return s.toUserRolesResponse(user)
+}
+
+// findUser looks the user up in the tenant bound to ctx, so a tenant admin
+// cannot manage users of another tenant
+func (s *RoleApplicationService) findUser(ctx context.Context, userID string) (*entities.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *RoleApplicationService) toRoleResponse(role *entities.Role) (*RoleResponse, error) {
+	policies, err := s.rbacEnforcer.GetRolePermissions(role.Name, role.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]PermissionResponse, 0, len(policies))
+	for _, policy := range policies {
+		permissions = append(permissions, PermissionResponse{Resource: policy[0], Action: policy[1]})
+	}
+
+	return &RoleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		IsSystem:    role.IsSystem,
+		Permissions: permissions,
+	}, nil
+}
+
+func (s *RoleApplicationService) toUserRolesResponse(user *entities.User) (*UserRolesResponse, error) {
+	permissions, err := s.rbacEnforcer.GetUserPermissions(user.ID, user.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserRolesResponse{
+		UserID:      user.ID,
+		Roles:       s.rbacEnforcer.GetUserRoles(user.ID, user.TenantID),
+		Permissions: permissions,
+	}, nil
+}
+
+func normalizePermission(req PermissionRequest) (string, string, error) {
+	resource := strings.TrimSpace(req.Resource)
+	action := strings.TrimSpace(req.Action)
+	if resource == "" || action == "" {
+		return "", "", errors.New("resource and action are required")
+	}
+	return resource, action, nil
+}
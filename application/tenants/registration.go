@@ -0,0 +1,178 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/services"
+	"medical-system/infrastructure/auth"
+)
+
+var ErrInvalidRegistrationToken = errors.New("invalid or expired registration link")
+
+// RegisterTenantRequest starts the registration of a clinic. Nothing is
+// stored until the link mailed to Email is followed.
+type RegisterTenantRequest struct {
+	Name  string `json:"name" validate:"required,max=255"`
+	Email string `json:"email" validate:"required,email"`
+	Slug  string `json:"slug" validate:"required,max=63"`
+	Plan  string `json:"plan" validate:"required,oneof=basic professional enterprise"`
+}
+
+type TenantRegistrationPendingResponse struct {
+	Email   string `json:"email"`
+	Message string `json:"message"`
+}
+
+// TenantRegistrationResponse holds the clinic details a registration link
+// carries
+type TenantRegistrationResponse struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Slug  string `json:"slug"`
+	Plan  string `json:"plan"`
+}
+
+// CompleteTenantRegistrationRequest names the first admin of the clinic,
+// who signs in with the verified email of the clinic
+type CompleteTenantRegistrationRequest struct {
+	Token     string `json:"token" validate:"required"`
+	FirstName string `json:"first_name" validate:"required,max=100"`
+	LastName  string `json:"last_name" validate:"required,max=100"`
+	Password  string `json:"password" validate:"required,min=8"`
+}
+
+type RegisterTenantResponse struct {
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Email string         `json:"email"`
+	Slug  string         `json:"slug"`
+	Plan  string         `json:"plan"`
+	Admin *entities.User `json:"admin"`
+}
+
+// RegisterTenant checks the clinic details and mails the link that verifies
+// its email. The link is signed and holds the details, so a clinic whose
+// link expired simply registers again.
+func (s *TenantApplicationService) RegisterTenant(ctx context.Context, req RegisterTenantRequest) (*TenantRegistrationPendingResponse, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.RegisterTenant")
+	defer span.End()
+
+	tenant := &entities.Tenant{
+		Name:  req.Name,
+		Email: req.Email,
+		Slug:  req.Slug,
+		Plan:  entities.SubscriptionPlan(req.Plan),
+	}
+	if err := s.tenantService.ValidateNewTenant(ctx, tenant.Name, tenant.Email, tenant.Slug, tenant.Plan); err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokenGen.GenerateTenantRegistrationToken(tenant)
+	if err != nil {
+		return nil, err
+	}
+	link, err := url.Parse(s.registrationURL)
+	if err != nil {
+		return nil, err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	err = s.mailer.Send(services.EmailMessage{
+		To:      tenant.Email,
+		Subject: "Confirm the registration of " + tenant.Name,
+		Body: fmt.Sprintf(
+			"%s was registered with this email address.\n\n"+
+				"Follow this link to confirm the address and create the first administrator: %s\n\n"+
+				"The link expires in %d hours. If you did not register, you can ignore this email.",
+			tenant.Name, link.String(), int(auth.TenantRegistrationTTL.Hours())),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TenantRegistrationPendingResponse{
+		Email:   tenant.Email,
+		Message: "Check your email to confirm the registration",
+	}, nil
+}
+
+// VerifyRegistration returns the details of the clinic a registration link
+// was mailed for, checking they are still free to take
+func (s *TenantApplicationService) VerifyRegistration(ctx context.Context, token string) (*TenantRegistrationResponse, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.VerifyRegistration")
+	defer span.End()
+
+	tenant, err := s.parseRegistration(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tenantService.ValidateNewTenant(ctx, tenant.Name, tenant.Email, tenant.Slug, tenant.Plan); err != nil {
+		return nil, err
+	}
+
+	return &TenantRegistrationResponse{
+		Name:  tenant.Name,
+		Email: tenant.Email,
+		Slug:  tenant.Slug,
+		Plan:  string(tenant.Plan),
+	}, nil
+}
+
+// CompleteRegistration creates the clinic of a registration link with its
+// first admin. A link creates a single tenant: the slug is taken afterwards.
+func (s *TenantApplicationService) CompleteRegistration(ctx context.Context, req CompleteTenantRegistrationRequest) (*RegisterTenantResponse, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.CompleteRegistration")
+	defer span.End()
+
+	details, err := s.parseRegistration(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := &entities.User{
+		Email:     details.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	}
+	tenant, err := s.tenantService.CreateTenant(ctx, details.Name, details.Email, details.Slug, details.Plan, admin, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegisterTenantResponse{
+		ID:    tenant.ID,
+		Name:  tenant.Name,
+		Email: tenant.Email,
+		Slug:  tenant.Slug,
+		Plan:  string(tenant.Plan),
+		Admin: admin,
+	}, nil
+}
+
+func (s *TenantApplicationService) parseRegistration(token string) (*entities.Tenant, error) {
+	claims, err := s.tokenGen.ValidateToken(token)
+	if err != nil {
+		return nil, ErrInvalidRegistrationToken
+	}
+
+	if tokenType, _ := (*claims)["typ"].(string); tokenType != auth.TokenTypeTenantRegistration {
+		return nil, ErrInvalidRegistrationToken
+	}
+
+	name, _ := (*claims)["name"].(string)
+	email, _ := (*claims)["email"].(string)
+	slug, _ := (*claims)["slug"].(string)
+	plan, _ := (*claims)["plan"].(string)
+	return &entities.Tenant{
+		Name:  name,
+		Email: email,
+		Slug:  slug,
+		Plan:  entities.SubscriptionPlan(plan),
+	}, nil
+}
@@ -1,27 +1,27 @@
 package tenants
 
 import (
+	"context"
+	"time"
+
 	"medical-system/domain/entities"
 	"medical-system/domain/services"
-)
+	"medical-system/domain/tenancy"
+	"medical-system/infrastructure/auth"
 
-type TenantApplicationService struct {
-	tenantService services.TenantService
-}
+	"go.opentelemetry.io/otel"
+)
 
-type RegisterTenantRequest struct {
-	Name  string `json:"name" validate:"required"`
-	Email string `json:"email" validate:"required,email"`
-	Slug  string `json:"slug" validate:"required"`
-	Plan  string `json:"plan" validate:"required,oneof=basic professional enterprise"`
-}
+var tracer = otel.Tracer("medical-system/application/tenants")
 
-type RegisterTenantResponse struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Slug  string `json:"slug"`
-	Plan  string `json:"plan"`
+type TenantApplicationService struct {
+	tenantService   services.TenantService
+	tenantResolver  services.TenantResolver
+	domainService   services.TenantDomainService
+	scopeRecorder   tenancy.ScopeRecorder
+	tokenGen        auth.TokenGenerator
+	mailer          services.Mailer
+	registrationURL string
 }
 
 type TenantSettingsResponse struct {
@@ -30,37 +30,74 @@ type TenantSettingsResponse struct {
 	MaxUsers              int    `json:"max_users"`
 	Timezone              string `json:"timezone"`
 	Language              string `json:"language"`
+	RequireMFA            bool   `json:"require_mfa"`
+	MaxFailedLogins       int    `json:"max_failed_logins"`
+	MaxFailedLoginsPerIP  int    `json:"max_failed_logins_per_ip"`
+	LockoutMinutes        int    `json:"lockout_minutes"`
 }
 
-func NewTenantApplicationService(tenantService services.TenantService) *TenantApplicationService {
-	return &TenantApplicationService{
-		tenantService: tenantService,
-	}
+// UpdateTenantSettingsRequest offers the languages request errors are
+// translated to
+type UpdateTenantSettingsRequest struct {
+	AllowUserRegistration bool   `json:"allow_user_registration"`
+	MaxUsers              int    `json:"max_users" validate:"gte=0"`
+	Timezone              string `json:"timezone" validate:"required"`
+	Language              string `json:"language" validate:"required,oneof=en es fr de pt"`
+	RequireMFA            bool   `json:"require_mfa"`
+	MaxFailedLogins       int    `json:"max_failed_logins" validate:"gte=0"`
+	MaxFailedLoginsPerIP  int    `json:"max_failed_logins_per_ip" validate:"gte=0"`
+	LockoutMinutes        int    `json:"lockout_minutes" validate:"gte=0"`
 }
 
-func (s *TenantApplicationService) RegisterTenant(req RegisterTenantRequest) (*RegisterTenantResponse, error) {
-	plan := entities.SubscriptionPlan(req.Plan)
+type AddTenantDomainRequest struct {
+	Domain string `json:"domain" validate:"required,max=253"`
+}
 
-	tenant, err := s.tenantService.CreateTenant(req.Name, req.Email, req.Slug, plan)
-	if err != nil {
-		return nil, err
-	}
+// TenantDomainResponse tells how to verify an unverified domain: publish a
+// TXT record named VerificationRecord holding VerificationValue
+type TenantDomainResponse struct {
+	ID                 string     `json:"id"`
+	Domain             string     `json:"domain"`
+	Verified           bool       `json:"verified"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+	VerificationRecord string     `json:"verification_record,omitempty"`
+	VerificationValue  string     `json:"verification_value,omitempty"`
+}
 
-	return &RegisterTenantResponse{
-		ID:    tenant.ID,
-		Name:  tenant.Name,
-		Email: tenant.Email,
-		Slug:  tenant.Slug,
-		Plan:  string(tenant.Plan),
-	}, nil
+// NewTenantApplicationService mails registering clinics a link to
+// registrationURL, carrying the registration token in its token parameter
+func NewTenantApplicationService(
+	tenantService services.TenantService,
+	tenantResolver services.TenantResolver,
+	domainService services.TenantDomainService,
+	scopeRecorder tenancy.ScopeRecorder,
+	tokenGen auth.TokenGenerator,
+	mailer services.Mailer,
+	registrationURL string,
+) *TenantApplicationService {
+	return &TenantApplicationService{
+		tenantService:   tenantService,
+		tenantResolver:  tenantResolver,
+		domainService:   domainService,
+		scopeRecorder:   scopeRecorder,
+		tokenGen:        tokenGen,
+		mailer:          mailer,
+		registrationURL: registrationURL,
+	}
 }
 
-func (s *TenantApplicationService) GetTenantBySlug(slug string) (*entities.Tenant, error) {
-	return s.tenantService.GetTenantBySlug(slug)
+// ResolveTenant returns the tenant named by its ID, slug or verified custom
+// domain, as requests name them, from the cache when possible
+func (s *TenantApplicationService) ResolveTenant(ctx context.Context, ref string) (*entities.Tenant, error) {
+	return s.tenantResolver.Resolve(ctx, ref)
 }
 
-func (s *TenantApplicationService) GetTenantSettings(tenantID string) (*TenantSettingsResponse, error) {
-	settings, err := s.tenantService.GetTenantSettings(tenantID)
+// GetTenantSettings is used by super admins on any tenant
+func (s *TenantApplicationService) GetTenantSettings(ctx context.Context, tenantID string) (*TenantSettingsResponse, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.GetTenantSettings")
+	defer span.End()
+
+	settings, err := s.tenantService.GetTenantSettings(s.asTenant(ctx, tenantID, "read tenant settings"))
 	if err != nil {
 		return nil, err
 	}
@@ -71,40 +108,133 @@ func (s *TenantApplicationService) GetTenantSettings(tenantID string) (*TenantSe
 		MaxUsers:              settings.MaxUsers,
 		Timezone:              settings.Timezone,
 		Language:              settings.Language,
+		RequireMFA:            settings.RequireMFA,
+		MaxFailedLogins:       settings.MaxFailedLogins,
+		MaxFailedLoginsPerIP:  settings.MaxFailedLoginsPerIP,
+		LockoutMinutes:        settings.LockoutMinutes,
 	}, nil
 }
 
-func (s *TenantApplicationService) UpdateTenantSettings(tenantID string, allowRegistration bool, maxUsers int, timezone, language string) error {
-	settings, err := s.tenantService.GetTenantSettings(tenantID)
+func (s *TenantApplicationService) UpdateTenantSettings(ctx context.Context, tenantID string, req UpdateTenantSettingsRequest) error {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.UpdateTenantSettings")
+	defer span.End()
+
+	ctx = s.asTenant(ctx, tenantID, "update tenant settings")
+	settings, err := s.tenantService.GetTenantSettings(ctx)
 	if err != nil {
 		return err
 	}
 
-	settings.AllowUserRegistration = allowRegistration
-	settings.MaxUsers = maxUsers
-	settings.Timezone = timezone
-	settings.Language = language
+	settings.AllowUserRegistration = req.AllowUserRegistration
+	settings.MaxUsers = req.MaxUsers
+	settings.Timezone = req.Timezone
+	settings.Language = req.Language
+	settings.RequireMFA = req.RequireMFA
+	settings.MaxFailedLogins = req.MaxFailedLogins
+	settings.MaxFailedLoginsPerIP = req.MaxFailedLoginsPerIP
+	settings.LockoutMinutes = req.LockoutMinutes
 
-	return s.tenantService.UpdateTenantSettings(settings)
+	return s.tenantService.UpdateTenantSettings(ctx, settings)
 }
 
-func (s *TenantApplicationService) ValidateTenantForUserRegistration(tenantID string) error {
-	return s.tenantService.ValidateTenantLimits(tenantID)
+func (s *TenantApplicationService) ValidateTenantForUserRegistration(ctx context.Context, tenantID string) error {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.ValidateTenantForUserRegistration")
+	defer span.End()
+
+	return s.tenantService.ValidateTenantLimits(tenancy.WithTenant(ctx, tenantID))
 }
 
 // Admin functions for tenant management
-func (s *TenantApplicationService) ListActiveTenants() ([]*entities.Tenant, error) {
-	return s.tenantService.ListActiveTenants()
+func (s *TenantApplicationService) ListActiveTenants(ctx context.Context) ([]*entities.Tenant, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.ListActiveTenants")
+	defer span.End()
+
+	return s.tenantService.ListActiveTenants(ctx)
+}
+
+func (s *TenantApplicationService) GetTenantByID(ctx context.Context, id string) (*entities.Tenant, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.GetTenantByID")
+	defer span.End()
+
+	return s.tenantService.GetTenantByID(ctx, id)
+}
+
+func (s *TenantApplicationService) UpdateTenant(ctx context.Context, tenant *entities.Tenant) error {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.UpdateTenant")
+	defer span.End()
+
+	return s.tenantService.UpdateTenant(ctx, tenant)
+}
+
+func (s *TenantApplicationService) DeleteTenant(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.DeleteTenant")
+	defer span.End()
+
+	return s.tenantService.DeleteTenant(s.asTenant(ctx, id, "delete tenant"), id)
 }
 
-func (s *TenantApplicationService) GetTenantByID(id string) (*entities.Tenant, error) {
-	return s.tenantService.GetTenantByID(id)
+// AddDomain adds a custom domain to the tenant bound to ctx. The tenant
+// verifies it before requests to it are routed there.
+func (s *TenantApplicationService) AddDomain(ctx context.Context, req AddTenantDomainRequest) (*TenantDomainResponse, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.AddDomain")
+	defer span.End()
+
+	domain, err := s.domainService.AddDomain(ctx, req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	return newTenantDomainResponse(domain), nil
 }
 
-func (s *TenantApplicationService) UpdateTenant(tenant *entities.Tenant) error {
-	return s.tenantService.UpdateTenant(tenant)
+func (s *TenantApplicationService) ListDomains(ctx context.Context) ([]*TenantDomainResponse, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.ListDomains")
+	defer span.End()
+
+	domains, err := s.domainService.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*TenantDomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		responses = append(responses, newTenantDomainResponse(domain))
+	}
+	return responses, nil
+}
+
+func (s *TenantApplicationService) VerifyDomain(ctx context.Context, domainID string) (*TenantDomainResponse, error) {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.VerifyDomain")
+	defer span.End()
+
+	domain, err := s.domainService.VerifyDomain(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	return newTenantDomainResponse(domain), nil
+}
+
+func (s *TenantApplicationService) RemoveDomain(ctx context.Context, domainID string) error {
+	ctx, span := tracer.Start(ctx, "TenantApplicationService.RemoveDomain")
+	defer span.End()
+
+	return s.domainService.RemoveDomain(ctx, domainID)
+}
+
+func newTenantDomainResponse(domain *entities.TenantDomain) *TenantDomainResponse {
+	response := &TenantDomainResponse{
+		ID:         domain.ID,
+		Domain:     domain.Domain,
+		Verified:   domain.IsVerified(),
+		VerifiedAt: domain.VerifiedAt,
+	}
+	if !domain.IsVerified() {
+		response.VerificationRecord, response.VerificationValue = services.DomainVerificationRecord(domain)
+	}
+	return response
 }
 
-func (s *TenantApplicationService) DeleteTenant(id string) error {
-	return s.tenantService.DeleteTenant(id)
+// asTenant lets a super admin, whose request is bound to their own tenant,
+// act on another tenant through the audited system scope
+func (s *TenantApplicationService) asTenant(ctx context.Context, tenantID, reason string) context.Context {
+	return tenancy.WithTenantAccess(ctx, s.scopeRecorder, tenantID, reason)
 }
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"medical-system/container"
+	"medical-system/domain/services"
+	"medical-system/domain/tenancy"
+	infraauth "medical-system/infrastructure/auth"
+	"medical-system/infrastructure/database"
+
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `usage:
+  medical-system                          start the API server
+  medical-system audit verify [tenant]    check the audit log hash chain of one or all tenants
+  medical-system migrate up               apply all pending migrations
+  medical-system migrate down [steps]     revert the last migration, or the last steps ones
+  medical-system migrate to <version>     apply or revert migrations up to version, 0 reverts all
+  medical-system migrate status           list the migrations and whether they are applied
+  medical-system config print [--redact]  print the effective configuration, secrets hidden with --redact
+  medical-system super-admin grant <id>   make a user a platform super admin, applied when the servers restart
+  medical-system super-admin revoke <id>  take the platform super admin role from a user`
+
+// runCommand runs a maintenance command and returns the process exit code
+func runCommand(container *container.Container, args []string) int {
+	if len(args) >= 2 && args[0] == "audit" && args[1] == "verify" && len(args) <= 3 {
+		return verifyAuditLog(container, args[2:])
+	}
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" && len(args) <= 3 {
+		if len(args) == 2 || args[2] == "--redact" {
+			return printConfig(container, len(args) == 3)
+		}
+	}
+	if len(args) == 3 && args[0] == "super-admin" && (args[1] == "grant" || args[1] == "revoke") {
+		return superAdmin(container, args[1] == "grant", args[2])
+	}
+	if len(args) >= 2 && args[0] == "migrate" && len(args) <= 3 {
+		return migrate(container, args[1], args[2:])
+	}
+
+	fmt.Fprintln(os.Stderr, usage)
+	return 2
+}
+
+// verifyAuditLog exits with 1 when any chain is broken so it can run from cron
+func verifyAuditLog(container *container.Container, args []string) int {
+	auditService, err := container.GetAuditService()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to get audit service:", err)
+		return 1
+	}
+
+	// Verification only reads, the transaction just carries the tenant
+	ctx, tx := database.WithTransaction(context.Background())
+	defer tx.End(false)
+
+	var results []*services.AuditVerification
+	if len(args) == 1 {
+		var result *services.AuditVerification
+		result, err = auditService.Verify(tenancy.WithTenant(ctx, args[0]))
+		results = append(results, result)
+	} else {
+		results, err = auditService.VerifyAll(ctx)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to verify the audit log:", err)
+		return 1
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		if result.Valid {
+			fmt.Printf("tenant %s: ok (%d events)\n", result.TenantID, result.Events)
+			continue
+		}
+		exitCode = 1
+		fmt.Printf("tenant %s: TAMPERED at sequence %d: %s\n", result.TenantID, result.BrokenAt, result.Problem)
+	}
+	return exitCode
+}
+
+// superAdmin grants or revokes the platform super admin role, which no
+// tenant admin can grant through the API
+func superAdmin(container *container.Container, grant bool, userID string) int {
+	var enforcer *infraauth.CasbinEnforcer
+	if err := container.DigContainer().Invoke(func(ce *infraauth.CasbinEnforcer) { enforcer = ce }); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load the policies:", err)
+		return 1
+	}
+
+	var err error
+	if grant {
+		err = enforcer.GrantSuperAdmin(userID)
+	} else {
+		err = enforcer.RevokeSuperAdmin(userID)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to update the policies:", err)
+		return 1
+	}
+	return 0
+}
+
+// migrate exits with 2 on usage errors so scripts can tell them from failures
+func migrate(container *container.Container, command string, args []string) int {
+	db, err := container.GetDatabase()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to connect to the database:", err)
+		return 1
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load the migrations:", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch {
+	case command == "up" && len(args) == 0:
+		err = migrator.Up(ctx)
+	case command == "down" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "steps must be a positive number")
+				return 2
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case command == "to" && len(args) == 1:
+		version, parseErr := strconv.ParseInt(args[0], 10, 64)
+		if parseErr != nil || version < 0 {
+			fmt.Fprintln(os.Stderr, "version must be a migration number")
+			return 2
+		}
+		err = migrator.To(ctx, version)
+	case command == "status" && len(args) == 0:
+		return printMigrationStatus(migrator)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Migration failed:", err)
+		return 1
+	}
+
+	return printMigrationStatus(migrator)
+}
+
+// printMigrationStatus exits with 1 when the schema does not match the build
+func printMigrationStatus(migrator *database.Migrator) int {
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read the migration status:", err)
+		return 1
+	}
+
+	exitCode := 0
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Missing:
+			state, exitCode = "applied, UNKNOWN to this build", 1
+		case status.Modified:
+			state, exitCode = "applied, MODIFIED since", 1
+		case status.Applied:
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%04d_%s: %s\n", status.Version, status.Name, state)
+	}
+	return exitCode
+}
+
+// printConfig exits with 1 when the configuration is invalid, after listing
+// every problem
+func printConfig(container *container.Container, redact bool) int {
+	cfg, err := container.GetConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if redact {
+		cfg = cfg.Redacted()
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to print the configuration:", err)
+		return 1
+	}
+	fmt.Print(string(out))
+	return 0
+}
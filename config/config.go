@@ -0,0 +1,330 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete configuration of the service. Values come from the
+// defaults below, then the YAML file named by CONFIG_FILE, if any, then the
+// environment variable in the env tag of each field. Fields tagged secret
+// are hidden by Redacted.
+type Config struct {
+	// Env is "production" or "development", which relaxes some checks
+	Env      string         `yaml:"env" env:"APP_ENV"`
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	MFA      MFAConfig      `yaml:"mfa"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Mail     MailConfig     `yaml:"mail"`
+	Features FeatureFlags   `yaml:"features"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Tenants  TenantsConfig  `yaml:"tenants"`
+}
+
+type ServerConfig struct {
+	Port         int           `yaml:"port" env:"PORT"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds how long in-flight requests may drain on
+	// SIGTERM before they are cut off
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies are the CIDR ranges of the reverse proxies in front of
+	// the server. The client IP is read from X-Forwarded-For only when the
+	// request comes through one of them; without any, the address of the
+	// connection is the client IP.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+type DatabaseConfig struct {
+	// Driver is "postgres" or "sqlite"
+	Driver   string `yaml:"driver" env:"DB_DRIVER"`
+	Host     string `yaml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" env:"DB_USER"`
+	Password string `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" env:"DB_NAME"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE"`
+	// Path is the SQLite database file, ":memory:" keeps it in memory
+	Path string `yaml:"path" env:"DB_PATH"`
+
+	// Pool sizes, 0 leaves the driver default. SQLite always uses one
+	// connection.
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	// DetachedMaxOpenConns sizes the PostgreSQL pool of the writes that must
+	// outlive the request transaction, such as audit events, so they never
+	// wait on connections the requests hold
+	DetachedMaxOpenConns int `yaml:"detached_max_open_conns" env:"DB_DETACHED_MAX_OPEN_CONNS"`
+}
+
+type JWTConfig struct {
+	// Secret signs HS256 tokens when no KeysDir is set
+	Secret string `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	// KeysDir holds <kid>.pem RSA/Ed25519 keys for asymmetric signing
+	KeysDir     string   `yaml:"keys_dir" env:"JWT_KEYS_DIR"`
+	ActiveKID   string   `yaml:"active_kid" env:"JWT_ACTIVE_KID"`
+	RetiredKIDs []string `yaml:"retired_kids" env:"JWT_RETIRED_KIDS"`
+}
+
+type MFAConfig struct {
+	// EncryptionKey encrypts the TOTP secrets stored for users with
+	// AES-256-GCM: 32 bytes, base64 encoded
+	EncryptionKey string `yaml:"encryption_key" env:"MFA_ENCRYPTION_KEY" secret:"true"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+}
+
+type MailConfig struct {
+	// OutboxDir receives a .eml file per message from the development mailer
+	OutboxDir string `yaml:"outbox_dir" env:"MAIL_OUTBOX_DIR"`
+}
+
+type MetricsConfig struct {
+	// Enabled serves Prometheus metrics at /metrics
+	Enabled bool `yaml:"enabled" env:"METRICS_ENABLED"`
+	// TenantLabel labels HTTP metrics with the tenant of the request. Only
+	// the first MaxTenants tenants seen get a label of their own, the
+	// others share "other", so the series cannot grow without bound.
+	TenantLabel bool `yaml:"tenant_label" env:"METRICS_TENANT_LABEL"`
+	MaxTenants  int  `yaml:"max_tenants" env:"METRICS_MAX_TENANTS"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "otlp", "stdout" or "file"
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	// Endpoint is the OTLP/HTTP collector, host:port or a URL
+	Endpoint string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	Insecure bool   `yaml:"insecure" env:"TRACING_INSECURE"`
+	// File receives one JSON span per line with the file exporter
+	File string `yaml:"file" env:"TRACING_FILE"`
+	// SampleRatio is the share of new traces recorded, between 0 and 1.
+	// Requests continue the sampling decision of their caller.
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+type TenantsConfig struct {
+	// CacheTTL is how long resolved tenants are cached, 0 disables the
+	// cache. Changes made on other instances take up to this long to show.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"TENANT_CACHE_TTL"`
+	// RegistrationURL is where the link mailed to clinics registering
+	// themselves points, with the token in the token query parameter
+	RegistrationURL string `yaml:"registration_url" env:"TENANT_REGISTRATION_URL"`
+}
+
+type FeatureFlags struct {
+	// TenantSignup exposes the public tenant registration endpoint
+	TenantSignup bool `yaml:"tenant_signup" env:"FEATURE_TENANT_SIGNUP"`
+	// UserRegistration exposes the public user registration endpoint
+	UserRegistration bool `yaml:"user_registration" env:"FEATURE_USER_REGISTRATION"`
+}
+
+const (
+	EnvProduction  = "production"
+	EnvDevelopment = "development"
+)
+
+// DefaultJWTSecret is only accepted in development
+const DefaultJWTSecret = "your-super-secret-jwt-key-change-this-in-production"
+
+// DefaultMFAEncryptionKey is only accepted in development
+const DefaultMFAEncryptionKey = "ZGV2ZWxvcG1lbnQtb25seS1tZmEta2V5LTMyLWJ5dGU="
+
+// Default returns the configuration used where nothing else is set
+func Default() *Config {
+	return &Config{
+		Env: EnvProduction,
+		Server: ServerConfig{
+			Port:            8080,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:               "postgres",
+			Host:                 "localhost",
+			Port:                 5432,
+			User:                 "postgres",
+			Password:             "postgres",
+			Name:                 "medical_system",
+			SSLMode:              "disable",
+			Path:                 "medical_system.db",
+			MaxOpenConns:         25,
+			MaxIdleConns:         5,
+			DetachedMaxOpenConns: 5,
+			ConnMaxLifetime:      30 * time.Minute,
+		},
+		CORS: CORSConfig{AllowedOrigins: []string{"*"}},
+		Metrics: MetricsConfig{
+			Enabled:    true,
+			MaxTenants: 50,
+		},
+		Tenants: TenantsConfig{
+			CacheTTL:        time.Minute,
+			RegistrationURL: "http://localhost:8080/api/tenants/register/verify",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			File:        "traces.jsonl",
+			SampleRatio: 1,
+			ServiceName: "medical-system",
+		},
+		Features: FeatureFlags{
+			TenantSignup:     true,
+			UserRegistration: true,
+		},
+	}
+}
+
+// Load reads and validates the configuration
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		defer file.Close()
+
+		// Unknown keys are typos, which would silently keep the default
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(cfg, os.Getenv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid value at once
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env != EnvProduction && c.Env != EnvDevelopment {
+		invalid("env must be %s or %s, got %q", EnvProduction, EnvDevelopment, c.Env)
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		invalid("server.port must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		invalid("server timeouts must be positive")
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			invalid("server.trusted_proxies entry %q is not a CIDR range like 10.0.0.0/8", proxy)
+		}
+	}
+
+	switch c.Database.Driver {
+	case "postgres":
+		if c.Database.Host == "" || c.Database.Name == "" {
+			invalid("database.host and database.name are required for postgres")
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			invalid("database.port must be between 1 and 65535, got %d", c.Database.Port)
+		}
+	case "sqlite":
+		if c.Database.Path == "" {
+			invalid("database.path is required for sqlite")
+		}
+	default:
+		invalid("database.driver must be postgres or sqlite, got %q", c.Database.Driver)
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime < 0 || c.Database.DetachedMaxOpenConns < 0 {
+		invalid("database pool settings must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		invalid("database.max_idle_conns (%d) exceeds database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+
+	if c.JWT.KeysDir == "" && c.Env != EnvDevelopment && (c.JWT.Secret == "" || c.JWT.Secret == DefaultJWTSecret) {
+		invalid("jwt.secret or jwt.keys_dir must be configured (the default secret is only allowed in development)")
+	}
+
+	if c.MFA.EncryptionKey == "" || c.MFA.EncryptionKey == DefaultMFAEncryptionKey {
+		if c.Env != EnvDevelopment {
+			invalid("mfa.encryption_key must be configured (the default key is only allowed in development)")
+		}
+	} else if key, err := base64.StdEncoding.DecodeString(c.MFA.EncryptionKey); err != nil || len(key) != 32 {
+		invalid("mfa.encryption_key must be 32 bytes, base64 encoded")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("cors.allowed_origins entry %q is not an origin like https://app.example.com", origin)
+		}
+	}
+
+	if c.Metrics.TenantLabel && c.Metrics.MaxTenants < 1 {
+		invalid("metrics.max_tenants must be positive when metrics.tenant_label is set, got %d", c.Metrics.MaxTenants)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			invalid("tracing.endpoint is required for the otlp exporter")
+		}
+	case "file":
+		if c.Tracing.File == "" {
+			invalid("tracing.file is required for the file exporter")
+		}
+	default:
+		invalid("tracing.exporter must be none, otlp, stdout or file, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	if c.Tenants.CacheTTL < 0 {
+		invalid("tenants.cache_ttl must not be negative")
+	}
+	if c.Features.TenantSignup {
+		if u, err := url.Parse(c.Tenants.RegistrationURL); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("tenants.registration_url must be an absolute URL when tenant signup is enabled, got %q", c.Tenants.RegistrationURL)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// IsDevelopment reports whether development shortcuts are allowed
+func (c *Config) IsDevelopment() bool {
+	return c.Env == EnvDevelopment
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides the fields of cfg whose env variable is set and not
+// empty
+func applyEnv(cfg *Config, getenv func(string) string) error {
+	return walk(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) error {
+		name := field.Tag.Get("env")
+		if name == "" {
+			return nil
+		}
+		raw := strings.TrimSpace(getenv(name))
+		if raw == "" {
+			return nil
+		}
+		if err := setField(value, raw); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Redacted returns a copy of the configuration with every secret that is
+// set replaced, for printing
+func (c *Config) Redacted() *Config {
+	copied := *c
+	copied.JWT.RetiredKIDs = append([]string(nil), c.JWT.RetiredKIDs...)
+	copied.CORS.AllowedOrigins = append([]string(nil), c.CORS.AllowedOrigins...)
+
+	_ = walk(reflect.ValueOf(&copied).Elem(), func(field reflect.StructField, value reflect.Value) error {
+		if field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "" {
+			value.SetString(redacted)
+		}
+		return nil
+	})
+	return &copied
+}
+
+// walk calls fn for every field of the struct v and of the structs nested
+// in it
+func walk(v reflect.Value, fn func(reflect.StructField, reflect.Value) error) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if value.Kind() == reflect.Struct {
+			if err := walk(value, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setField(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(n))
+	case value.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
@@ -1,17 +1,32 @@
 package container
 
 import (
+	"fmt"
+	"log"
+	appappointments "medical-system/application/appointments"
+	appaudit "medical-system/application/audit"
 	appauth "medical-system/application/auth"
+	appencounters "medical-system/application/encounters"
+	apppatients "medical-system/application/patients"
+	approles "medical-system/application/roles"
 	apptenants "medical-system/application/tenants"
+	"medical-system/config"
+	domainrepositories "medical-system/domain/repositories"
 	"medical-system/domain/services"
+	"medical-system/domain/tenancy"
 	infraauth "medical-system/infrastructure/auth"
 	"medical-system/infrastructure/database"
+	"medical-system/infrastructure/mail"
+	"medical-system/infrastructure/metrics"
 	"medical-system/infrastructure/repositories"
+	"medical-system/infrastructure/tracing"
+	"medical-system/infrastructure/validation"
 	authmiddleware "medical-system/middleware"
-	"os"
+	"net"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/dig"
+	"gorm.io/gorm"
 )
 
 type Container struct {
@@ -35,42 +50,157 @@ func NewContainer() *Container {
 }
 
 func (c *Container) registerDependencies() {
-	// Database
-	c.dig.Provide(database.NewConnection)
+	// Configuration, validated before anything is built on it
+	c.dig.Provide(config.Load)
 
-	// Token Generator
-	c.dig.Provide(func() infraauth.TokenGenerator {
-		jwtSecret := os.Getenv("JWT_SECRET")
-		if jwtSecret == "" {
-			jwtSecret = "your-super-secret-jwt-key-change-this-in-production" // fallback
+	// Prometheus metrics, business events are counted by the domain
+	c.dig.Provide(metrics.New)
+	c.dig.Provide(func(m *metrics.Metrics) services.BusinessMetrics {
+		return m
+	})
+
+	// OpenTelemetry tracing, exported as configured
+	c.dig.Provide(tracing.New)
+
+	// Database, with its statements timed and traced
+	c.dig.Provide(func(cfg *config.Config, m *metrics.Metrics, t *tracing.Tracing) (*gorm.DB, error) {
+		db, err := database.NewConnection(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.InstrumentDB(db); err != nil {
+			return nil, fmt.Errorf("failed to instrument the database: %w", err)
 		}
-		return infraauth.NewJWTGenerator(jwtSecret)
+		if err := t.InstrumentDB(db); err != nil {
+			return nil, fmt.Errorf("failed to trace the database: %w", err)
+		}
+		return db, nil
+	})
+
+	// Token Generator
+	c.dig.Provide(newTokenGenerator)
+
+	// Encryption of the stored TOTP secrets
+	c.dig.Provide(newSecretCipher)
+
+	// Outbound mail (written to the log and MAIL_OUTBOX_DIR, if set)
+	c.dig.Provide(func(cfg *config.Config) services.Mailer {
+		return mail.NewLogMailer(cfg.Mail.OutboxDir)
 	})
 
 	// Casbin RBAC
 	c.dig.Provide(infraauth.NewCasbinEnforcer)
+	c.dig.Provide(func(enforcer *infraauth.CasbinEnforcer) services.PolicyManager {
+		return enforcer
+	})
 
 	// Repositories
 	c.dig.Provide(repositories.NewUserRepository)
 	c.dig.Provide(repositories.NewTenantRepository)
 	c.dig.Provide(repositories.NewTenantSettingsRepository)
+	c.dig.Provide(repositories.NewRoleRepository)
+	c.dig.Provide(repositories.NewPasswordResetTokenRepository)
+	c.dig.Provide(repositories.NewSessionRepository)
+	c.dig.Provide(repositories.NewMFARecoveryCodeRepository)
+	c.dig.Provide(repositories.NewLoginThrottleRepository)
+	c.dig.Provide(repositories.NewPatientRepository)
+	c.dig.Provide(repositories.NewScheduleRepository)
+	c.dig.Provide(repositories.NewRoomRepository)
+	c.dig.Provide(repositories.NewAppointmentRepository)
+	c.dig.Provide(repositories.NewEncounterRepository)
+	c.dig.Provide(repositories.NewAuditEventRepository)
+	c.dig.Provide(repositories.NewTenantDomainRepository)
+	c.dig.Provide(repositories.NewUsedTokenRepository)
 
 	// Domain Services
 	c.dig.Provide(services.NewAuthService)
 	c.dig.Provide(services.NewTenantService)
+	c.dig.Provide(func(tenantRepo domainrepositories.TenantRepository, domainRepo domainrepositories.TenantDomainRepository, cfg *config.Config) services.TenantResolver {
+		return services.NewTenantResolver(tenantRepo, domainRepo, cfg.Tenants.CacheTTL)
+	})
+	c.dig.Provide(services.NewTenantDomainService)
+	c.dig.Provide(func() services.TXTResolver {
+		return net.DefaultResolver
+	})
+	c.dig.Provide(services.NewSessionService)
+	c.dig.Provide(services.NewMFAService)
+	c.dig.Provide(services.NewLoginThrottleService)
+	c.dig.Provide(services.NewPatientService)
+	c.dig.Provide(services.NewScheduleService)
+	c.dig.Provide(services.NewAppointmentService)
+	c.dig.Provide(services.NewEncounterService)
+	c.dig.Provide(services.NewAuditService)
+	c.dig.Provide(func(auditService services.AuditService) services.AuditRecorder {
+		return auditService
+	})
+	c.dig.Provide(func(auditService services.AuditService) tenancy.ScopeRecorder {
+		return auditService
+	})
 
 	// Application Services
 	c.dig.Provide(appauth.NewAuthApplicationService)
-	c.dig.Provide(apptenants.NewTenantApplicationService)
+	c.dig.Provide(func(
+		cfg *config.Config,
+		tenantService services.TenantService,
+		tenantResolver services.TenantResolver,
+		domainService services.TenantDomainService,
+		scopeRecorder tenancy.ScopeRecorder,
+		tokenGen infraauth.TokenGenerator,
+		mailer services.Mailer,
+	) *apptenants.TenantApplicationService {
+		return apptenants.NewTenantApplicationService(tenantService, tenantResolver, domainService, scopeRecorder, tokenGen, mailer, cfg.Tenants.RegistrationURL)
+	})
+	c.dig.Provide(approles.NewRoleApplicationService)
+	c.dig.Provide(apppatients.NewPatientApplicationService)
+	c.dig.Provide(appappointments.NewAppointmentApplicationService)
+	c.dig.Provide(appencounters.NewEncounterApplicationService)
+	c.dig.Provide(appaudit.NewAuditApplicationService)
+
+	// Request validation, with messages in the language of the tenant
+	c.dig.Provide(validation.New)
 
 	// Middleware
-	c.dig.Provide(func(tokenGen infraauth.TokenGenerator, tenantService *apptenants.TenantApplicationService) *authmiddleware.AuthMiddleware {
-		return authmiddleware.NewAuthMiddleware(tokenGen, tenantService)
-	})
-	c.dig.Provide(func(tenantService *apptenants.TenantApplicationService) *authmiddleware.TenantMiddleware {
-		return authmiddleware.NewTenantMiddleware(tenantService)
-	})
+	c.dig.Provide(authmiddleware.NewAuthMiddleware)
+	c.dig.Provide(authmiddleware.NewTenantMiddleware)
 	c.dig.Provide(authmiddleware.NewAdminMiddleware)
+	c.dig.Provide(authmiddleware.NewAuditMiddleware)
+}
+
+// newTokenGenerator signs with the asymmetric keys in the JWT keys directory
+// when set, and falls back to HS256 with the JWT secret otherwise
+func newTokenGenerator(cfg *config.Config) (infraauth.TokenGenerator, error) {
+	if cfg.JWT.KeysDir != "" {
+		return infraauth.NewKeySetGenerator(cfg.JWT.KeysDir, cfg.JWT.ActiveKID, cfg.JWT.RetiredKIDs)
+	}
+
+	// Validation only lets the default secret through in development
+	jwtSecret := cfg.JWT.Secret
+	if jwtSecret == "" || jwtSecret == config.DefaultJWTSecret {
+		log.Println("⚠️  Using the default JWT secret, never do this outside development")
+		jwtSecret = config.DefaultJWTSecret
+	}
+	return infraauth.NewJWTGenerator(jwtSecret), nil
+}
+
+// newSecretCipher encrypts with the configured MFA key
+func newSecretCipher(cfg *config.Config) (services.SecretCipher, error) {
+	// Validation only lets the default key through in development
+	key := cfg.MFA.EncryptionKey
+	if key == "" || key == config.DefaultMFAEncryptionKey {
+		log.Println("⚠️  Using the default MFA encryption key, never do this outside development")
+		key = config.DefaultMFAEncryptionKey
+	}
+	return infraauth.NewAESCipher(key)
+}
+
+// GetConfig returns the configuration, or the validation error of config.Load
+// without dig's wrapping so it can be shown to operators as is
+func (c *Container) GetConfig() (*config.Config, error) {
+	var cfg *config.Config
+	err := c.dig.Invoke(func(c *config.Config) {
+		cfg = c
+	})
+	return cfg, dig.RootCause(err)
 }
 
 func (c *Container) GetAuthService() (*appauth.AuthApplicationService, error) {
@@ -89,6 +219,70 @@ func (c *Container) GetTenantService() (*apptenants.TenantApplicationService, er
 	return service, err
 }
 
+func (c *Container) GetRoleService() (*approles.RoleApplicationService, error) {
+	var service *approles.RoleApplicationService
+	err := c.dig.Invoke(func(s *approles.RoleApplicationService) {
+		service = s
+	})
+	return service, err
+}
+
+func (c *Container) GetPatientService() (*apppatients.PatientApplicationService, error) {
+	var service *apppatients.PatientApplicationService
+	err := c.dig.Invoke(func(s *apppatients.PatientApplicationService) {
+		service = s
+	})
+	return service, err
+}
+
+func (c *Container) GetAppointmentService() (*appappointments.AppointmentApplicationService, error) {
+	var service *appappointments.AppointmentApplicationService
+	err := c.dig.Invoke(func(s *appappointments.AppointmentApplicationService) {
+		service = s
+	})
+	return service, err
+}
+
+func (c *Container) GetEncounterService() (*appencounters.EncounterApplicationService, error) {
+	var service *appencounters.EncounterApplicationService
+	err := c.dig.Invoke(func(s *appencounters.EncounterApplicationService) {
+		service = s
+	})
+	return service, err
+}
+
+func (c *Container) GetAuditService() (*appaudit.AuditApplicationService, error) {
+	var service *appaudit.AuditApplicationService
+	err := c.dig.Invoke(func(s *appaudit.AuditApplicationService) {
+		service = s
+	})
+	return service, err
+}
+
+func (c *Container) GetDatabase() (*gorm.DB, error) {
+	var db *gorm.DB
+	err := c.dig.Invoke(func(d *gorm.DB) {
+		db = d
+	})
+	return db, err
+}
+
+func (c *Container) GetTracing() (*tracing.Tracing, error) {
+	var t *tracing.Tracing
+	err := c.dig.Invoke(func(tr *tracing.Tracing) {
+		t = tr
+	})
+	return t, err
+}
+
+func (c *Container) GetValidator() (*validation.Validator, error) {
+	var v *validation.Validator
+	err := c.dig.Invoke(func(validator *validation.Validator) {
+		v = validator
+	})
+	return v, err
+}
+
 func (c *Container) GetTokenGen() (infraauth.TokenGenerator, error) {
 	var tokenGen infraauth.TokenGenerator
 	err := c.dig.Invoke(func(tg infraauth.TokenGenerator) {
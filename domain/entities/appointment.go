@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AppointmentStatus is a step of the appointment lifecycle
+type AppointmentStatus string
+
+const (
+	AppointmentBooked    AppointmentStatus = "booked"
+	AppointmentConfirmed AppointmentStatus = "confirmed"
+	AppointmentCheckedIn AppointmentStatus = "checked_in"
+	AppointmentCompleted AppointmentStatus = "completed"
+	AppointmentNoShow    AppointmentStatus = "no_show"
+	AppointmentCancelled AppointmentStatus = "cancelled"
+)
+
+// appointmentTransitions lists the statuses reachable from each status.
+// Completed, no-show and cancelled appointments are final.
+var appointmentTransitions = map[AppointmentStatus][]AppointmentStatus{
+	AppointmentBooked:    {AppointmentConfirmed, AppointmentCheckedIn, AppointmentNoShow, AppointmentCancelled},
+	AppointmentConfirmed: {AppointmentCheckedIn, AppointmentNoShow, AppointmentCancelled},
+	AppointmentCheckedIn: {AppointmentCompleted, AppointmentCancelled},
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving to next
+func (s AppointmentStatus) CanTransitionTo(next AppointmentStatus) bool {
+	for _, allowed := range appointmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// BlocksTime reports whether an appointment in this status occupies its
+// provider and room. Cancelled and no-show appointments free the slot.
+func (s AppointmentStatus) BlocksTime() bool {
+	return s != AppointmentCancelled && s != AppointmentNoShow
+}
+
+// IsValid reports whether s is a known status
+func (s AppointmentStatus) IsValid() bool {
+	switch s {
+	case AppointmentBooked, AppointmentConfirmed, AppointmentCheckedIn,
+		AppointmentCompleted, AppointmentNoShow, AppointmentCancelled:
+		return true
+	}
+	return false
+}
+
+// Appointment is a visit of a patient with a provider, optionally in a room.
+// StartAt and EndAt are stored in UTC.
+type Appointment struct {
+	ID                 string            `json:"id" gorm:"primaryKey"`
+	TenantID           string            `json:"tenant_id" gorm:"index:idx_appointment_provider_time;index:idx_appointment_room_time;not null"`
+	PatientID          string            `json:"patient_id" gorm:"index;not null"`
+	ProviderID         string            `json:"provider_id" gorm:"index:idx_appointment_provider_time;not null"`
+	RoomID             *string           `json:"room_id" gorm:"index:idx_appointment_room_time"`
+	StartAt            time.Time         `json:"start_at" gorm:"index:idx_appointment_provider_time;index:idx_appointment_room_time;not null"`
+	EndAt              time.Time         `json:"end_at" gorm:"not null"`
+	Status             AppointmentStatus `json:"status" gorm:"default:booked;not null"`
+	Reason             string            `json:"reason"`
+	Notes              string            `json:"notes"`
+	CancellationReason string            `json:"cancellation_reason"`
+	BookedBy           string            `json:"booked_by"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+func (a *Appointment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EncounterStatus tells whether the encounter notes can still be edited
+type EncounterStatus string
+
+const (
+	EncounterDraft  EncounterStatus = "draft"
+	EncounterSigned EncounterStatus = "signed"
+)
+
+// SOAP note sections
+const (
+	SectionSubjective = "subjective"
+	SectionObjective  = "objective"
+	SectionAssessment = "assessment"
+	SectionPlan       = "plan"
+)
+
+// Vitals measured during an encounter. Nil values were not measured.
+type Vitals struct {
+	TemperatureC     *float64 `json:"temperature_c"`
+	HeartRate        *int     `json:"heart_rate"`
+	RespiratoryRate  *int     `json:"respiratory_rate"`
+	SystolicBP       *int     `json:"systolic_bp"`
+	DiastolicBP      *int     `json:"diastolic_bp"`
+	OxygenSaturation *int     `json:"oxygen_saturation"`
+	WeightKg         *float64 `json:"weight_kg"`
+	HeightCm         *float64 `json:"height_cm"`
+}
+
+// Encounter is a clinical contact between a provider and a patient, documented
+// as a SOAP note. Drafts can be edited freely; once signed the note is never
+// changed again and corrections are recorded as amendments.
+type Encounter struct {
+	ID            string          `json:"id" gorm:"primaryKey"`
+	TenantID      string          `json:"tenant_id" gorm:"index:idx_encounter_patient;not null"`
+	PatientID     string          `json:"patient_id" gorm:"index:idx_encounter_patient;not null"`
+	ProviderID    string          `json:"provider_id" gorm:"index;not null"`
+	AppointmentID *string         `json:"appointment_id" gorm:"index"`
+	Status        EncounterStatus `json:"status" gorm:"default:draft;not null"`
+
+	Subjective string `json:"subjective" gorm:"type:text"`
+	Objective  string `json:"objective" gorm:"type:text"`
+	Assessment string `json:"assessment" gorm:"type:text"`
+	Plan       string `json:"plan" gorm:"type:text"`
+
+	Vitals     Vitals               `json:"vitals" gorm:"embedded;embeddedPrefix:vital_"`
+	Diagnoses  []Diagnosis          `json:"diagnoses" gorm:"foreignKey:EncounterID;constraint:OnDelete:CASCADE"`
+	Amendments []EncounterAmendment `json:"amendments" gorm:"foreignKey:EncounterID;constraint:OnDelete:CASCADE"`
+
+	SignedAt  *time.Time `json:"signed_at"`
+	SignedBy  string     `json:"signed_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (e *Encounter) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IsSigned reports whether the notes are final
+func (e *Encounter) IsSigned() bool {
+	return e.Status == EncounterSigned
+}
+
+// SignedText returns a section exactly as it was signed
+func (e *Encounter) SignedText(section string) (string, bool) {
+	switch section {
+	case SectionSubjective:
+		return e.Subjective, true
+	case SectionObjective:
+		return e.Objective, true
+	case SectionAssessment:
+		return e.Assessment, true
+	case SectionPlan:
+		return e.Plan, true
+	}
+	return "", false
+}
+
+// CurrentText returns a section with the latest amendment applied. Amendments
+// must be sorted by AmendedAt.
+func (e *Encounter) CurrentText(section string) string {
+	text, _ := e.SignedText(section)
+	for _, amendment := range e.Amendments {
+		if amendment.Section == section {
+			text = amendment.AmendedText
+		}
+	}
+	return text
+}
+
+// Diagnosis is a coded diagnosis (for example ICD-10) made during an encounter
+type Diagnosis struct {
+	ID          string `json:"id" gorm:"primaryKey"`
+	TenantID    string `json:"tenant_id" gorm:"index;not null"`
+	EncounterID string `json:"encounter_id" gorm:"index;not null"`
+	Code        string `json:"code" gorm:"not null"`
+	Description string `json:"description"`
+	IsPrimary   bool   `json:"is_primary" gorm:"default:false"`
+}
+
+func (d *Diagnosis) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// EncounterAmendment corrects one section of a signed encounter. It keeps
+// the text it replaces, so the full history of the note can be rebuilt.
+type EncounterAmendment struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	TenantID     string    `json:"tenant_id" gorm:"index;not null"`
+	EncounterID  string    `json:"encounter_id" gorm:"index;not null"`
+	Section      string    `json:"section" gorm:"not null"`
+	OriginalText string    `json:"original_text" gorm:"type:text"`
+	AmendedText  string    `json:"amended_text" gorm:"type:text"`
+	Reason       string    `json:"reason" gorm:"not null"`
+	AmendedBy    string    `json:"amended_by" gorm:"not null"`
+	AmendedAt    time.Time `json:"amended_at" gorm:"not null"`
+}
+
+func (a *EncounterAmendment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
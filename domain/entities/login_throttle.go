@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Scopes of the throttle counters: what they count
+const (
+	ThrottleScopeLogin         = "login"
+	ThrottleScopePasswordReset = "password_reset"
+)
+
+// ThrottleCounter counts consecutive failed logins and records a temporary
+// lockout once a threshold is reached
+type ThrottleCounter struct {
+	FailedCount  int        `json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+// LoginThrottle counts the failed logins, or the password reset requests,
+// for an email address within a tenant.
+type LoginThrottle struct {
+	ID              string `json:"id" gorm:"primaryKey"`
+	TenantID        string `json:"tenant_id" gorm:"uniqueIndex:idx_login_throttle_key;not null"`
+	Scope           string `json:"scope" gorm:"uniqueIndex:idx_login_throttle_key;not null"`
+	Identifier      string `json:"identifier" gorm:"uniqueIndex:idx_login_throttle_key;not null"`
+	ThrottleCounter `gorm:"embedded"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (t *LoginThrottle) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IPLoginThrottle counts the failed logins from a client IP. It belongs to
+// no tenant: an address guessing passwords at one clinic is counted at
+// every other.
+type IPLoginThrottle struct {
+	Scope           string `json:"scope" gorm:"primaryKey"`
+	IPAddress       string `json:"ip_address" gorm:"primaryKey"`
+	ThrottleCounter `gorm:"embedded"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MFARecoveryCode is a single-use code that replaces a TOTP code when the
+// user has lost their authenticator. Only the bcrypt hash is stored.
+type MFARecoveryCode struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	TenantID  string     `json:"tenant_id" gorm:"index;not null"`
+	UserID    string     `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (c *MFARecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
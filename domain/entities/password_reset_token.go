@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordResetToken is a single-use credential that allows a user to set a
+// new password. Only the SHA-256 hash of the token is persisted.
+type PasswordResetToken struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	TenantID  string     `json:"tenant_id" gorm:"index;not null"`
+	UserID    string     `json:"user_id" gorm:"index;not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IsUsable reports whether the token has neither been used nor expired
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
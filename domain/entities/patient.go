@@ -0,0 +1,91 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Sex values recorded for a patient
+const (
+	SexMale    = "male"
+	SexFemale  = "female"
+	SexOther   = "other"
+	SexUnknown = "unknown"
+)
+
+// Patient is a person registered with a clinic. The medical record number
+// (MRN) is unique inside the tenant; the national ID is unique inside the
+// tenant when present.
+type Patient struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	TenantID    string    `json:"tenant_id" gorm:"uniqueIndex:idx_patient_mrn_tenant;uniqueIndex:idx_patient_national_id_tenant,where:national_id <> '';index:idx_patient_name_tenant;not null"`
+	MRN         string    `json:"mrn" gorm:"uniqueIndex:idx_patient_mrn_tenant;not null"`
+	NationalID  string    `json:"national_id" gorm:"uniqueIndex:idx_patient_national_id_tenant,where:national_id <> ''"`
+	FirstName   string    `json:"first_name" gorm:"index:idx_patient_name_tenant;not null"`
+	LastName    string    `json:"last_name" gorm:"index:idx_patient_name_tenant;not null"`
+	DateOfBirth time.Time `json:"date_of_birth" gorm:"type:date;not null"`
+	Sex         string    `json:"sex" gorm:"default:unknown"`
+
+	// Contact information
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	AddressLine1 string `json:"address_line1"`
+	AddressLine2 string `json:"address_line2"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	PostalCode   string `json:"postal_code"`
+	Country      string `json:"country"`
+
+	EmergencyContacts []EmergencyContact `json:"emergency_contacts" gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE"`
+	InsurancePolicies []InsurancePolicy  `json:"insurance_policies" gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE"`
+
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (p *Patient) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	return nil
+}
+
+type EmergencyContact struct {
+	ID           string `json:"id" gorm:"primaryKey"`
+	TenantID     string `json:"tenant_id" gorm:"index;not null"`
+	PatientID    string `json:"patient_id" gorm:"index;not null"`
+	Name         string `json:"name" gorm:"not null"`
+	Relationship string `json:"relationship"`
+	Phone        string `json:"phone"`
+	Email        string `json:"email"`
+}
+
+func (c *EmergencyContact) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+type InsurancePolicy struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	TenantID     string     `json:"tenant_id" gorm:"index;not null"`
+	PatientID    string     `json:"patient_id" gorm:"index;not null"`
+	Provider     string     `json:"provider" gorm:"not null"`
+	PolicyNumber string     `json:"policy_number" gorm:"not null"`
+	GroupNumber  string     `json:"group_number"`
+	HolderName   string     `json:"holder_name"`
+	ValidFrom    *time.Time `json:"valid_from" gorm:"type:date"`
+	ValidUntil   *time.Time `json:"valid_until" gorm:"type:date"`
+	IsPrimary    bool       `json:"is_primary" gorm:"default:false"`
+}
+
+func (i *InsurancePolicy) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
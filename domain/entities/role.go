@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Role is a named set of permissions defined inside a tenant. The permissions
+// themselves live in the Casbin policy store; this record makes the role
+// exist even before any permission has been granted to it.
+type Role struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	TenantID    string    `json:"tenant_id" gorm:"uniqueIndex:idx_role_name_tenant;not null"`
+	Name        string    `json:"name" gorm:"uniqueIndex:idx_role_name_tenant;not null"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system" gorm:"default:false"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (r *Role) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// DefaultRoles returns the built-in roles created for every new tenant
+func DefaultRoles(tenantID string) []*Role {
+	return []*Role{
+		{TenantID: tenantID, Name: RoleAdmin, Description: "Clinic administrator", IsSystem: true},
+		{TenantID: tenantID, Name: RoleUser, Description: "Clinic staff member", IsSystem: true},
+	}
+}
@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Room is a consultation room or other bookable resource of a clinic
+type Room struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	TenantID    string    `json:"tenant_id" gorm:"uniqueIndex:idx_room_name_tenant;not null"`
+	Name        string    `json:"name" gorm:"uniqueIndex:idx_room_name_tenant;not null"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (r *Room) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
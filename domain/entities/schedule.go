@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProviderSchedule is a weekly working-hours template of a provider. Start and
+// end are wall-clock times ("15:04") in the tenant's timezone, so a template
+// keeps meaning the same hours across daylight saving changes.
+type ProviderSchedule struct {
+	ID          string       `json:"id" gorm:"primaryKey"`
+	TenantID    string       `json:"tenant_id" gorm:"index:idx_schedule_provider;not null"`
+	ProviderID  string       `json:"provider_id" gorm:"index:idx_schedule_provider;not null"`
+	Weekday     time.Weekday `json:"weekday" gorm:"not null"`
+	StartTime   string       `json:"start_time" gorm:"size:5;not null"`
+	EndTime     string       `json:"end_time" gorm:"size:5;not null"`
+	SlotMinutes int          `json:"slot_minutes" gorm:"default:30"`
+	RoomID      *string      `json:"room_id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+func (s *ProviderSchedule) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// ScheduleException blocks a period in which appointments cannot be booked,
+// such as a provider's leave or, when ProviderID is empty, a clinic holiday
+type ScheduleException struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	TenantID   string    `json:"tenant_id" gorm:"index:idx_schedule_exception_period;not null"`
+	ProviderID string    `json:"provider_id" gorm:"index"`
+	StartsAt   time.Time `json:"starts_at" gorm:"index:idx_schedule_exception_period;not null"`
+	EndsAt     time.Time `json:"ends_at" gorm:"not null"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (e *ScheduleException) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Overlaps reports whether the exception blocks any part of [start, end)
+func (e *ScheduleException) Overlaps(start, end time.Time) bool {
+	return e.StartsAt.Before(end) && e.EndsAt.After(start)
+}
@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is a server-side login session. Access tokens carry the session ID
+// as their jti claim, so revoking the session revokes every access token
+// issued for it. The refresh token rotates on every use; only hashes of the
+// current and previous refresh tokens are stored.
+type Session struct {
+	ID                string     `json:"id" gorm:"primaryKey"`
+	TenantID          string     `json:"tenant_id" gorm:"index;not null"`
+	UserID            string     `json:"user_id" gorm:"index;not null"`
+	RefreshTokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	PreviousTokenHash string     `json:"-" gorm:"index"`
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `json:"ip_address"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IsActive reports whether the session has neither been revoked nor expired
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
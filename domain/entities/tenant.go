@@ -58,6 +58,12 @@ type TenantSettings struct {
 	MaxUsers              int    `json:"max_users"`
 	Timezone              string `json:"timezone" gorm:"default:UTC"`
 	Language              string `json:"language" gorm:"default:en"`
+	RequireMFA            bool   `json:"require_mfa" gorm:"default:false"`
+
+	// Login throttling
+	MaxFailedLogins      int `json:"max_failed_logins" gorm:"default:5"`
+	MaxFailedLoginsPerIP int `json:"max_failed_logins_per_ip" gorm:"default:20"`
+	LockoutMinutes       int `json:"lockout_minutes" gorm:"default:15"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
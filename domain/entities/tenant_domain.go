@@ -0,0 +1,33 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TenantDomain is a custom domain a tenant serves from, such as
+// portal.clinic.com. It routes requests to the tenant once the tenant has
+// proven control of it with a DNS TXT record carrying VerificationToken.
+type TenantDomain struct {
+	ID                string     `json:"id" gorm:"primaryKey"`
+	TenantID          string     `json:"tenant_id" gorm:"not null"`
+	Domain            string     `json:"domain" gorm:"not null"`
+	VerificationToken string     `json:"verification_token" gorm:"not null"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (d *TenantDomain) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IsVerified reports whether the domain routes requests to its tenant
+func (d *TenantDomain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
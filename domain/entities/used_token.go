@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+// UsedToken records that a single-use token, named by its jti claim, was
+// redeemed. The record is only needed until the token expires.
+type UsedToken struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	TenantID  string    `json:"tenant_id" gorm:"index;not null"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
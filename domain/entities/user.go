@@ -7,6 +7,20 @@ import (
 	"gorm.io/gorm"
 )
 
+// Built-in roles provisioned for every tenant
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// RoleSuperAdmin runs the platform: it manages every tenant and may act for
+// one. It is held in PlatformDomain, which is no tenant's ID, so no tenant
+// can grant it to its own users.
+const (
+	RoleSuperAdmin = "super_admin"
+	PlatformDomain = "platform"
+)
+
 type User struct {
 	ID           string `json:"id" gorm:"primaryKey"`
 	Email        string `json:"email" gorm:"uniqueIndex:idx_user_email_tenant"`
@@ -16,8 +30,15 @@ type User struct {
 	FirstName    string `json:"first_name"`
 	LastName     string `json:"last_name"`
 	IsActive     bool   `json:"is_active" gorm:"default:true"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+
+	// TOTP multi-factor authentication. The secret is stored as soon as
+	// enrollment starts but only enforced once MFAEnabled is set.
+	MFAEnabled      bool   `json:"mfa_enabled" gorm:"default:false"`
+	MFASecret       string `json:"-"`
+	MFALastUsedStep int64  `json:"-"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
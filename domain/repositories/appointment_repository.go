@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+)
+
+// AppointmentSearchCriteria filters an appointment listing. Empty fields are
+// ignored; From and To select appointments starting inside [From, To).
+type AppointmentSearchCriteria struct {
+	ProviderID string
+	PatientID  string
+	RoomID     string
+	Status     entities.AppointmentStatus
+	From       *time.Time
+	To         *time.Time
+}
+
+type AppointmentRepository interface {
+	Create(ctx context.Context, appointment *entities.Appointment) error
+	FindByID(ctx context.Context, id string) (*entities.Appointment, error)
+	Update(ctx context.Context, appointment *entities.Appointment) error
+	Search(ctx context.Context, criteria AppointmentSearchCriteria) ([]*entities.Appointment, error)
+
+	// FindOverlapping returns the time-blocking appointments of the provider
+	// or the room that overlap [start, end), except excludeID
+	FindOverlapping(ctx context.Context, providerID string, roomID *string, start, end time.Time, excludeID string) ([]*entities.Appointment, error)
+
+	// LockProvider and LockRoom take a row lock until the end of the current
+	// transaction so concurrent bookings of the same provider or room are
+	// serialized. They fail when the provider or room does not exist.
+	LockProvider(ctx context.Context, providerID string) error
+	LockRoom(ctx context.Context, roomID string) error
+
+	// Transaction runs fn with a repository bound to a single transaction
+	Transaction(ctx context.Context, fn func(repo AppointmentRepository) error) error
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+)
+
+// AuditSearchCriteria filters audit events. Empty fields are ignored.
+type AuditSearchCriteria struct {
+	ActorID      string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Outcome      string
+	RequestID    string
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+	Offset       int
+}
+
+// AuditEventRepository is append-only: audit events are never updated or
+// deleted.
+type AuditEventRepository interface {
+	// Append chains the event to the head of its tenant's chain: it assigns
+	// the next sequence number and the previous hash, then the event hash,
+	// and moves the head to the event.
+	// The event is written to its own tenant's chain whatever scope ctx is in.
+	Append(ctx context.Context, event *entities.AuditEvent) error
+	Search(ctx context.Context, criteria AuditSearchCriteria) ([]*entities.AuditEvent, int64, error)
+	// FindChain returns up to limit events of the tenant, in chain order,
+	// starting after the given sequence number
+	FindChain(ctx context.Context, afterSequence int64, limit int) ([]*entities.AuditEvent, error)
+	// FindHead returns the head of the tenant's chain, nil before its first
+	// event
+	FindHead(ctx context.Context) (*entities.AuditChainHead, error)
+	// FindTenantIDs lists the tenants with a chain; it needs the system scope
+	FindTenantIDs(ctx context.Context) ([]string, error)
+}
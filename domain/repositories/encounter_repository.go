@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
+
+type EncounterRepository interface {
+	Create(ctx context.Context, encounter *entities.Encounter) error
+	FindByID(ctx context.Context, id string) (*entities.Encounter, error)
+	FindByPatient(ctx context.Context, patientID string) ([]*entities.Encounter, error)
+	// UpdateDraft saves the notes, vitals and diagnoses of a draft encounter.
+	// It reports false when the encounter is no longer a draft.
+	UpdateDraft(ctx context.Context, encounter *entities.Encounter) (bool, error)
+	// Sign marks a draft encounter as signed. It reports false when the
+	// encounter had already been signed.
+	Sign(ctx context.Context, encounter *entities.Encounter) (bool, error)
+	CreateAmendment(ctx context.Context, amendment *entities.EncounterAmendment) error
+}
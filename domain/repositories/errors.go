@@ -0,0 +1,6 @@
+package repositories
+
+import "errors"
+
+// ErrDuplicateKey is returned when a write is refused by a unique index
+var ErrDuplicateKey = errors.New("duplicate key")
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+)
+
+type LoginThrottleRepository interface {
+	// FindForUpdate returns the counter, created empty when missing, and
+	// locks it until the end of the current transaction so concurrent
+	// attempts are counted one after the other
+	FindForUpdate(ctx context.Context, scope, identifier string) (*entities.LoginThrottle, error)
+	Save(ctx context.Context, throttle *entities.LoginThrottle) error
+	Delete(ctx context.Context, scope, identifier string) error
+	// FindIPForUpdate is FindForUpdate for the counter of a client IP, which
+	// every tenant shares
+	FindIPForUpdate(ctx context.Context, scope, ipAddress string) (*entities.IPLoginThrottle, error)
+	SaveIP(ctx context.Context, throttle *entities.IPLoginThrottle) error
+	// DeleteInactive removes the counters of the tenant and the IP counters
+	// whose last attempt is older than before
+	DeleteInactive(ctx context.Context, before time.Time) error
+
+	// Transaction runs fn with a repository bound to a single transaction.
+	// It is kept apart from the transaction of the request, so the counters
+	// survive a failed login.
+	Transaction(ctx context.Context, fn func(repo LoginThrottleRepository) error) error
+}
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
+
+type MFARecoveryCodeRepository interface {
+	ReplaceForUser(ctx context.Context, userID string, codes []*entities.MFARecoveryCode) error
+	FindUnusedByUser(ctx context.Context, userID string) ([]*entities.MFARecoveryCode, error)
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	DeleteForUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *entities.PasswordResetToken) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*entities.PasswordResetToken, error)
+	// Redeem marks the unused token as used and stores its user's new
+	// password hash in the same transaction. It reports whether this call
+	// was the one that used the token; otherwise nothing changes.
+	Redeem(ctx context.Context, token *entities.PasswordResetToken, passwordHash string) (bool, error)
+	InvalidateForUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+)
+
+// PatientSearchCriteria filters a patient search. Query is matched against
+// names, MRN, national ID, phone and email.
+type PatientSearchCriteria struct {
+	Query           string
+	DateOfBirth     *time.Time
+	IncludeInactive bool
+	Limit           int
+	Offset          int
+}
+
+type PatientRepository interface {
+	Create(ctx context.Context, patient *entities.Patient) error
+	FindByID(ctx context.Context, id string) (*entities.Patient, error)
+	Update(ctx context.Context, patient *entities.Patient) error
+	Search(ctx context.Context, criteria PatientSearchCriteria) ([]*entities.Patient, int64, error)
+	FindPossibleDuplicates(ctx context.Context, patient *entities.Patient) ([]*entities.Patient, error)
+}
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *entities.Role) error
+	FindByName(ctx context.Context, name string) (*entities.Role, error)
+	List(ctx context.Context) ([]*entities.Role, error)
+	Delete(ctx context.Context, name string) error
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
+
+type RoomRepository interface {
+	Create(ctx context.Context, room *entities.Room) error
+	FindByID(ctx context.Context, id string) (*entities.Room, error)
+	List(ctx context.Context) ([]*entities.Room, error)
+}
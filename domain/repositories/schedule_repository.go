@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+)
+
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *entities.ProviderSchedule) error
+	FindSchedulesByProvider(ctx context.Context, providerID string) ([]*entities.ProviderSchedule, error)
+	DeleteSchedule(ctx context.Context, id string) (bool, error)
+
+	CreateException(ctx context.Context, exception *entities.ScheduleException) error
+	// FindExceptions returns the exceptions overlapping [from, to) that apply
+	// to the provider, including clinic-wide ones. An empty providerID
+	// returns the exceptions of every provider.
+	FindExceptions(ctx context.Context, providerID string, from, to time.Time) ([]*entities.ScheduleException, error)
+	DeleteException(ctx context.Context, id string) (bool, error)
+}
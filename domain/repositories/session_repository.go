@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *entities.Session) error
+	FindByID(ctx context.Context, id string) (*entities.Session, error)
+	FindByRefreshTokenHash(ctx context.Context, tokenHash string) (*entities.Session, error)
+	FindByPreviousTokenHash(ctx context.Context, tokenHash string) (*entities.Session, error)
+	Rotate(ctx context.Context, session *entities.Session, newTokenHash string) (bool, error)
+	// Revoke is kept apart from the transaction of the request: a session
+	// revoked because its refresh token was replayed stays revoked although
+	// the request fails
+	Revoke(ctx context.Context, id string) error
+	RevokeAllForUser(ctx context.Context, userID string) (int64, error)
+	// RevokeAll revokes every session of the tenant bound to ctx
+	RevokeAll(ctx context.Context) (int64, error)
+	// RevokeOthersForUser revokes the user's sessions but the one given
+	RevokeOthersForUser(ctx context.Context, userID, keepSessionID string) (int64, error)
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
+
+// TenantDomainRepository manages the custom domains of the tenant bound to
+// ctx, except for FindVerified, which routes requests before their tenant
+// is known
+type TenantDomainRepository interface {
+	Create(ctx context.Context, domain *entities.TenantDomain) error
+	FindByID(ctx context.Context, id string) (*entities.TenantDomain, error)
+	List(ctx context.Context) ([]*entities.TenantDomain, error)
+	// MarkVerified fails when another tenant verified the domain first
+	MarkVerified(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+	// FindVerified returns the verified domain of any tenant
+	FindVerified(ctx context.Context, domain string) (*entities.TenantDomain, error)
+}
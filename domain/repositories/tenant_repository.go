@@ -1,21 +1,31 @@
 package repositories
 
-import "medical-system/domain/entities"
+import (
+	"context"
 
+	"medical-system/domain/entities"
+)
+
+// TenantRepository manages the tenants themselves, which are not scoped to a
+// tenant
 type TenantRepository interface {
-	Create(tenant *entities.Tenant) error
-	FindByID(id string) (*entities.Tenant, error)
-	FindBySlug(slug string) (*entities.Tenant, error)
-	FindByEmail(email string) (*entities.Tenant, error)
-	Update(tenant *entities.Tenant) error
-	Delete(id string) error
-	ListActive() ([]*entities.Tenant, error)
-	CountUsersByTenant(tenantID string) (int64, error)
+	Create(ctx context.Context, tenant *entities.Tenant) error
+	// Provision stores a new tenant with its settings, roles and first user
+	// in one transaction. ctx must be bound to the new tenant.
+	Provision(ctx context.Context, tenant *entities.Tenant, settings *entities.TenantSettings, roles []*entities.Role, admin *entities.User) error
+	FindByID(ctx context.Context, id string) (*entities.Tenant, error)
+	FindBySlug(ctx context.Context, slug string) (*entities.Tenant, error)
+	FindByEmail(ctx context.Context, email string) (*entities.Tenant, error)
+	Update(ctx context.Context, tenant *entities.Tenant) error
+	ListActive(ctx context.Context) ([]*entities.Tenant, error)
+	// CountUsers counts the users of the tenant bound to ctx
+	CountUsers(ctx context.Context) (int64, error)
 }
 
+// TenantSettingsRepository reads and writes the settings of the tenant bound
+// to ctx
 type TenantSettingsRepository interface {
-	Create(settings *entities.TenantSettings) error
-	FindByTenantID(tenantID string) (*entities.TenantSettings, error)
-	Update(settings *entities.TenantSettings) error
-	Delete(tenantID string) error
+	Create(ctx context.Context, settings *entities.TenantSettings) error
+	Find(ctx context.Context) (*entities.TenantSettings, error)
+	Update(ctx context.Context, settings *entities.TenantSettings) error
 }
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+)
+
+type UsedTokenRepository interface {
+	// Claim records the token as used and reports whether this call was the
+	// one that did it
+	Claim(ctx context.Context, token *entities.UsedToken) (bool, error)
+	// DeleteExpired removes the records of tokens that expired before
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
@@ -1,11 +1,19 @@
 package repositories
 
-import "medical-system/domain/entities"
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
 
 type UserRepository interface {
-	Create(user *entities.User) error
-	FindByEmailAndTenant(email, tenantID string) (*entities.User, error)
-	FindByID(id string) (*entities.User, error)
-	Update(user *entities.User) error
-	Delete(id string) error
+	Create(ctx context.Context, user *entities.User) error
+	FindByEmail(ctx context.Context, email string) (*entities.User, error)
+	FindByID(ctx context.Context, id string) (*entities.User, error)
+	Update(ctx context.Context, user *entities.User) error
+	// ClaimMFAStep records step as the user's last used TOTP time step,
+	// unless that step or a later one was used already, and reports whether
+	// it did
+	ClaimMFAStep(ctx context.Context, userID string, step int64) (bool, error)
+	Delete(ctx context.Context, id string) error
 }
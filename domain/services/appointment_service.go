@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+)
+
+var (
+	ErrAppointmentNotFound     = errors.New("appointment not found")
+	ErrAppointmentConflict     = errors.New("the provider or room is already booked at that time")
+	ErrOutsideWorkingHours     = errors.New("the provider does not work at that time")
+	ErrProviderUnavailable     = errors.New("the provider is not available at that time")
+	ErrAppointmentInPast       = errors.New("appointments cannot be booked in the past")
+	ErrInvalidStatusTransition = errors.New("invalid appointment status transition")
+)
+
+type AppointmentService interface {
+	BookAppointment(ctx context.Context, appointment *entities.Appointment) error
+	RescheduleAppointment(ctx context.Context, id string, start, end time.Time) (*entities.Appointment, error)
+	ChangeStatus(ctx context.Context, id string, status entities.AppointmentStatus, reason string) (*entities.Appointment, error)
+	GetAppointment(ctx context.Context, id string) (*entities.Appointment, error)
+	ListAppointments(ctx context.Context, criteria repositories.AppointmentSearchCriteria) ([]*entities.Appointment, error)
+}
+
+type AppointmentServiceImpl struct {
+	appointmentRepo repositories.AppointmentRepository
+	patientRepo     repositories.PatientRepository
+	scheduleService ScheduleService
+}
+
+func NewAppointmentService(
+	appointmentRepo repositories.AppointmentRepository,
+	patientRepo repositories.PatientRepository,
+	scheduleService ScheduleService,
+) AppointmentService {
+	return &AppointmentServiceImpl{
+		appointmentRepo: appointmentRepo,
+		patientRepo:     patientRepo,
+		scheduleService: scheduleService,
+	}
+}
+
+// BookAppointment books a visit inside the provider's working hours. When no
+// room is given the room of the matching working-hours template is used.
+func (s *AppointmentServiceImpl) BookAppointment(ctx context.Context, appointment *entities.Appointment) error {
+	patient, err := s.patientRepo.FindByID(ctx, appointment.PatientID)
+	if err != nil || !patient.IsActive {
+		return ErrPatientNotFound
+	}
+
+	if err := validateAppointmentPeriod(appointment.StartAt, appointment.EndAt); err != nil {
+		return err
+	}
+
+	window, err := s.scheduleService.WorkingWindow(ctx, appointment.ProviderID, appointment.StartAt, appointment.EndAt)
+	if err != nil {
+		return err
+	}
+	if appointment.RoomID == nil {
+		appointment.RoomID = window.RoomID
+	}
+
+	appointment.StartAt = appointment.StartAt.UTC()
+	appointment.EndAt = appointment.EndAt.UTC()
+	appointment.Status = entities.AppointmentBooked
+
+	return s.appointmentRepo.Transaction(ctx, func(repo repositories.AppointmentRepository) error {
+		if err := reserve(ctx, repo, appointment); err != nil {
+			return err
+		}
+		return repo.Create(ctx, appointment)
+	})
+}
+
+// RescheduleAppointment moves a visit to another period inside the provider's
+// working hours. When the matching template names a room the visit moves there.
+func (s *AppointmentServiceImpl) RescheduleAppointment(ctx context.Context, id string, start, end time.Time) (*entities.Appointment, error) {
+	appointment, err := s.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status != entities.AppointmentBooked && appointment.Status != entities.AppointmentConfirmed {
+		return nil, fmt.Errorf("%w: %s appointments cannot be rescheduled", ErrInvalidStatusTransition, appointment.Status)
+	}
+
+	if err := validateAppointmentPeriod(start, end); err != nil {
+		return nil, err
+	}
+	window, err := s.scheduleService.WorkingWindow(ctx, appointment.ProviderID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	// The provider sees patients in the room of the new template, if it names one
+	if window.RoomID != nil {
+		appointment.RoomID = window.RoomID
+	}
+
+	appointment.StartAt = start.UTC()
+	appointment.EndAt = end.UTC()
+	appointment.UpdatedAt = time.Now()
+
+	err = s.appointmentRepo.Transaction(ctx, func(repo repositories.AppointmentRepository) error {
+		if err := reserve(ctx, repo, appointment); err != nil {
+			return err
+		}
+		return repo.Update(ctx, appointment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+// ChangeStatus moves the appointment along its lifecycle. The reason is
+// recorded for cancellations.
+func (s *AppointmentServiceImpl) ChangeStatus(ctx context.Context, id string, status entities.AppointmentStatus, reason string) (*entities.Appointment, error) {
+	appointment, err := s.GetAppointment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !status.IsValid() || !appointment.Status.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, appointment.Status, status)
+	}
+	if status == entities.AppointmentNoShow && time.Now().Before(appointment.StartAt) {
+		return nil, fmt.Errorf("%w: the appointment has not started yet", ErrInvalidStatusTransition)
+	}
+
+	appointment.Status = status
+	if status == entities.AppointmentCancelled {
+		appointment.CancellationReason = reason
+	}
+	appointment.UpdatedAt = time.Now()
+
+	if err := s.appointmentRepo.Update(ctx, appointment); err != nil {
+		return nil, err
+	}
+	return appointment, nil
+}
+
+func (s *AppointmentServiceImpl) GetAppointment(ctx context.Context, id string) (*entities.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrAppointmentNotFound
+	}
+	return appointment, nil
+}
+
+func (s *AppointmentServiceImpl) ListAppointments(ctx context.Context, criteria repositories.AppointmentSearchCriteria) ([]*entities.Appointment, error) {
+	return s.appointmentRepo.Search(ctx, criteria)
+}
+
+// reserve locks the provider and room rows and checks that nothing else is
+// booked for them in the appointment's period. It must run inside the
+// transaction that stores the appointment, so concurrent bookings of the
+// same provider or room wait for each other instead of both succeeding.
+func reserve(ctx context.Context, repo repositories.AppointmentRepository, appointment *entities.Appointment) error {
+	if err := repo.LockProvider(ctx, appointment.ProviderID); err != nil {
+		return ErrProviderNotFound
+	}
+	if appointment.RoomID != nil {
+		if err := repo.LockRoom(ctx, *appointment.RoomID); err != nil {
+			return ErrRoomNotFound
+		}
+	}
+
+	conflicts, err := repo.FindOverlapping(ctx, appointment.ProviderID, appointment.RoomID,
+		appointment.StartAt, appointment.EndAt, appointment.ID)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return ErrAppointmentConflict
+	}
+	return nil
+}
+
+func validateAppointmentPeriod(start, end time.Time) error {
+	if start.IsZero() || end.IsZero() {
+		return errors.New("appointment start and end are required")
+	}
+	if !end.After(start) {
+		return errors.New("appointment must end after it starts")
+	}
+	if start.Before(time.Now()) {
+		return ErrAppointmentInPast
+	}
+	return nil
+}
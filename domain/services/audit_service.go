@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/domain/tenancy"
+)
+
+// auditVerifyBatchSize is how many events are read at a time when verifying
+const auditVerifyBatchSize = 500
+
+// SystemAuditTenant is the chain system scope entries are recorded in, since
+// they do not belong to any one tenant
+const SystemAuditTenant = "system"
+
+// AuditActor identifies who performs an audited action and from where
+type AuditActor struct {
+	TenantID  string
+	UserID    string
+	Role      string
+	IPAddress string
+	RequestID string
+}
+
+// Event starts an audit event performed by the actor
+func (a AuditActor) Event(action, resourceType, resourceID, outcome string) *entities.AuditEvent {
+	return &entities.AuditEvent{
+		TenantID:     a.TenantID,
+		ActorID:      a.UserID,
+		ActorRole:    a.Role,
+		IPAddress:    a.IPAddress,
+		RequestID:    a.RequestID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Outcome:      outcome,
+	}
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor binds the actor performing the request to ctx
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// ActorFromContext returns the actor bound to ctx, or an anonymous one
+func ActorFromContext(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor
+}
+
+// AuditRecorder is the hook services and middleware use to record events.
+// Events without a tenant are recorded for the tenant bound to ctx.
+type AuditRecorder interface {
+	Record(ctx context.Context, event *entities.AuditEvent)
+}
+
+// AuditVerification is the result of checking a tenant's hash chain.
+// BrokenAt is the sequence number of the first event that does not verify.
+type AuditVerification struct {
+	TenantID string `json:"tenant_id"`
+	Events   int64  `json:"events"`
+	Valid    bool   `json:"valid"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+type AuditService interface {
+	AuditRecorder
+	tenancy.ScopeRecorder
+	Search(ctx context.Context, criteria repositories.AuditSearchCriteria) ([]*entities.AuditEvent, int64, error)
+	// Verify checks the chain of the tenant bound to ctx
+	Verify(ctx context.Context) (*AuditVerification, error)
+	VerifyAll(ctx context.Context) ([]*AuditVerification, error)
+}
+
+type AuditServiceImpl struct {
+	auditRepo repositories.AuditEventRepository
+}
+
+func NewAuditService(auditRepo repositories.AuditEventRepository) AuditService {
+	return &AuditServiceImpl{auditRepo: auditRepo}
+}
+
+// Record appends the event to the audit log. Auditing never fails the audited
+// operation, so errors are logged instead of returned.
+func (s *AuditServiceImpl) Record(ctx context.Context, event *entities.AuditEvent) {
+	if event.TenantID == "" {
+		event.TenantID, _ = tenancy.TenantID(ctx)
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.Outcome == "" {
+		event.Outcome = entities.AuditOutcomeSuccess
+	}
+
+	if err := s.auditRepo.Append(ctx, event); err != nil {
+		log.Printf("⚠️  Failed to record audit event %s on %s %s: %v", event.Action, event.ResourceType, event.ResourceID, err)
+	}
+}
+
+// RecordSystemScope records that the actor bound to ctx left its tenant and
+// why in the system chain. Access to one tenant is recorded in that tenant's
+// chain too, so its admins see who reached their data.
+func (s *AuditServiceImpl) RecordSystemScope(ctx context.Context, tenantID, reason string) {
+	actor := ActorFromContext(ctx)
+	resourceID := tenantID
+	if resourceID == "" {
+		resourceID, _ = tenancy.TenantID(ctx)
+	}
+
+	event := actor.Event("system.scope", "tenant", resourceID, entities.AuditOutcomeSuccess)
+	event.TenantID = SystemAuditTenant
+	event.Details = reason
+	s.Record(ctx, event)
+
+	if tenantID != "" && tenantID != SystemAuditTenant {
+		access := actor.Event("system.scope", "tenant", tenantID, entities.AuditOutcomeSuccess)
+		access.TenantID = tenantID
+		access.Details = reason
+		s.Record(ctx, access)
+	}
+}
+
+func (s *AuditServiceImpl) Search(ctx context.Context, criteria repositories.AuditSearchCriteria) ([]*entities.AuditEvent, int64, error) {
+	return s.auditRepo.Search(ctx, criteria)
+}
+
+// Verify walks the tenant's chain from the first event and checks that the
+// sequence has no gaps, every event links to its predecessor, every hash
+// matches the event contents and the chain still reaches its recorded head.
+// Events appended while it runs are checked as well.
+func (s *AuditServiceImpl) Verify(ctx context.Context) (*AuditVerification, error) {
+	tenantID, ok := tenancy.TenantID(ctx)
+	if !ok {
+		return nil, tenancy.ErrNoTenantScope
+	}
+	result := &AuditVerification{TenantID: tenantID, Valid: true}
+	broken := func(sequence int64, problem string) (*AuditVerification, error) {
+		result.Valid = false
+		result.BrokenAt = sequence
+		result.Problem = problem
+		return result, nil
+	}
+
+	head, err := s.auditRepo.FindHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *entities.AuditEvent
+	for {
+		events, err := s.auditRepo.FindChain(ctx, sequenceOf(previous), auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			result.Events++
+			if problem := chainProblem(previous, event); problem != "" {
+				return broken(event.Sequence, problem)
+			}
+			if head != nil && event.Sequence == head.Sequence && event.Hash != head.Hash {
+				return broken(event.Sequence, "hash does not match the head of the chain: the event was replaced")
+			}
+			previous = event
+		}
+
+		if len(events) < auditVerifyBatchSize {
+			break
+		}
+	}
+
+	if head == nil {
+		if previous == nil {
+			return result, nil
+		}
+		// The first event may have been appended after the head was read
+		if head, err = s.auditRepo.FindHead(ctx); err != nil {
+			return nil, err
+		}
+		if head == nil {
+			return broken(1, "the head of the chain is missing")
+		}
+		return result, nil
+	}
+	if sequenceOf(previous) < head.Sequence {
+		return broken(sequenceOf(previous)+1, fmt.Sprintf("the chain ends before its head at sequence %d: events were removed from the end", head.Sequence))
+	}
+	return result, nil
+}
+
+// sequenceOf is the sequence number of event, 0 before the first one
+func sequenceOf(event *entities.AuditEvent) int64 {
+	if event == nil {
+		return 0
+	}
+	return event.Sequence
+}
+
+// VerifyAll checks the chains of every tenant, including the system chain
+func (s *AuditServiceImpl) VerifyAll(ctx context.Context) ([]*AuditVerification, error) {
+	ctx = tenancy.WithSystemScope(ctx, s, "audit chain verification")
+
+	tenantIDs, err := s.auditRepo.FindTenantIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*AuditVerification, 0, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		result, err := s.Verify(tenancy.WithTenant(ctx, tenantID))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func chainProblem(previous, event *entities.AuditEvent) string {
+	expectedSequence, expectedPrevHash := int64(1), ""
+	if previous != nil {
+		expectedSequence, expectedPrevHash = previous.Sequence+1, previous.Hash
+	}
+
+	switch {
+	case event.Sequence != expectedSequence:
+		return fmt.Sprintf("expected sequence %d, found %d: events were removed", expectedSequence, event.Sequence)
+	case event.PrevHash != expectedPrevHash:
+		return "previous hash does not match the preceding event"
+	case event.Hash != event.ComputeHash():
+		return "hash does not match the event contents: the event was modified"
+	}
+	return ""
+}
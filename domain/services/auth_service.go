@@ -1,12 +1,19 @@
 package services
 
-import "medical-system/domain/entities"
+import (
+	"context"
+
+	"medical-system/domain/entities"
+)
 
 type AuthService interface {
-	RegisterUser(user *entities.User, password string) error
-	VerifyCredentials(email, password, tenantID string) (*entities.User, error)
-	ChangePassword(userID, currentPassword, newPassword string) error
-	RequestPasswordReset(email, tenantID string) error
-	ResetPassword(token, newPassword string) error
-	UpdateProfile(userID, firstName, lastName, email string) (*entities.User, error)
+	RegisterUser(ctx context.Context, user *entities.User, password string) error
+	VerifyCredentials(ctx context.Context, email, password string) (*entities.User, error)
+	// ChangePassword signs the user out of every session but the one the
+	// change is made from
+	ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ResetPassword signs the user out of every session
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	UpdateProfile(ctx context.Context, userID, firstName, lastName, email string) (*entities.User, error)
 }
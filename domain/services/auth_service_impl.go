@@ -1,30 +1,63 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"medical-system/domain/entities"
 	"medical-system/domain/repositories"
+	"medical-system/domain/tenancy"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// passwordResetTTL is how long a password reset token remains valid
+const passwordResetTTL = time.Hour
+
+// minPasswordLength is the shortest password accepted when setting a new one
+const minPasswordLength = 8
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountDisabled    = errors.New("account is disabled")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+)
+
 type AuthServiceImpl struct {
-	userRepo      repositories.UserRepository
-	tenantService TenantService
+	userRepo       repositories.UserRepository
+	resetTokenRepo repositories.PasswordResetTokenRepository
+	sessionRepo    repositories.SessionRepository
+	tenantService  TenantService
+	mailer         Mailer
+	scopeRecorder  tenancy.ScopeRecorder
 }
 
-func NewAuthService(userRepo repositories.UserRepository, tenantService TenantService) AuthService {
+func NewAuthService(
+	userRepo repositories.UserRepository,
+	resetTokenRepo repositories.PasswordResetTokenRepository,
+	sessionRepo repositories.SessionRepository,
+	tenantService TenantService,
+	mailer Mailer,
+	scopeRecorder tenancy.ScopeRecorder,
+) AuthService {
 	return &AuthServiceImpl{
-		userRepo:      userRepo,
-		tenantService: tenantService,
+		userRepo:       userRepo,
+		resetTokenRepo: resetTokenRepo,
+		sessionRepo:    sessionRepo,
+		tenantService:  tenantService,
+		mailer:         mailer,
+		scopeRecorder:  scopeRecorder,
 	}
 }
 
-func (s *AuthServiceImpl) RegisterUser(user *entities.User, password string) error {
+func (s *AuthServiceImpl) RegisterUser(ctx context.Context, user *entities.User, password string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.RegisterUser")
+	defer span.End()
+
 	// Validate tenant limits before registration
-	if err := s.tenantService.ValidateTenantLimits(user.TenantID); err != nil {
+	if err := s.tenantService.ValidateTenantLimits(ctx); err != nil {
 		return err
 	}
 
@@ -36,41 +69,140 @@ func (s *AuthServiceImpl) RegisterUser(user *entities.User, password string) err
 	user.PasswordHash = string(hashedPassword)
 
 	// Save user
-	return s.userRepo.Create(user)
+	return s.userRepo.Create(ctx, user)
 }
 
-func (s *AuthServiceImpl) VerifyCredentials(email, password, tenantID string) (*entities.User, error) {
-	user, err := s.userRepo.FindByEmailAndTenant(email, tenantID)
+func (s *AuthServiceImpl) VerifyCredentials(ctx context.Context, email, password string) (*entities.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.VerifyCredentials")
+	defer span.End()
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	// Only reported after the password matched, so it reveals nothing to guessers
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
+	}
+	if tenant, err := s.tenantService.GetTenantByID(ctx, user.TenantID); err != nil || !tenant.IsActive {
+		return nil, ErrAccountDisabled
 	}
 
 	return user, nil
 }
 
-func (s *AuthServiceImpl) ChangePassword(userID, currentPassword, newPassword string) error {
-	// Implementation for password change
-	return errors.New("not implemented")
+func (s *AuthServiceImpl) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Verify current password
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword))
+	if err != nil {
+		return errors.New("current password is incorrect")
+	}
+
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	_, err = s.sessionRepo.RevokeOthersForUser(ctx, user.ID, sessionID)
+	return err
 }
 
-func (s *AuthServiceImpl) RequestPasswordReset(email, tenantID string) error {
-	// Implementation for password reset request
-	return errors.New("not implemented")
+func (s *AuthServiceImpl) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.RequestPasswordReset")
+	defer span.End()
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil || !user.IsActive {
+		// Do not reveal whether the account exists
+		return nil
+	}
+
+	// Only the most recent reset token may be used
+	if err := s.resetTokenRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	resetToken := &entities.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashOpaqueToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	if err := s.resetTokenRepo.Create(ctx, resetToken); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "Password reset request",
+		Body: fmt.Sprintf(
+			"A password reset was requested for your account.\n\n"+
+				"Use the following token to choose a new password: %s\n\n"+
+				"The token expires in %d minutes. If you did not request a reset, you can ignore this email.",
+			token, int(passwordResetTTL.Minutes())),
+	})
 }
 
-func (s *AuthServiceImpl) ResetPassword(token, newPassword string) error {
-	// Implementation for password reset
-	return errors.New("not implemented")
+// ResetPassword redeems a reset token. The token is the only thing the
+// caller presents, so it is looked up across tenants.
+func (s *AuthServiceImpl) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.ResetPassword")
+	defer span.End()
+
+	lookupCtx := tenancy.WithSystemScope(ctx, s.scopeRecorder, "password reset token lookup")
+	resetToken, err := s.resetTokenRepo.FindByTokenHash(lookupCtx, hashOpaqueToken(token))
+	if err != nil || !resetToken.IsUsable(time.Now()) {
+		return ErrInvalidResetToken
+	}
+
+	ctx = tenancy.WithTenant(ctx, resetToken.TenantID)
+	user, err := s.userRepo.FindByID(ctx, resetToken.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	passwordHash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	// The token is used and the password stored together, so the token can
+	// neither be redeemed twice nor be lost to a failed update
+	redeemed, err := s.resetTokenRepo.Redeem(ctx, resetToken, passwordHash)
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return ErrInvalidResetToken
+	}
+
+	// Whoever knew the old password is signed out
+	_, err = s.sessionRepo.RevokeAllForUser(ctx, user.ID)
+	return err
 }
 
-func (s *AuthServiceImpl) UpdateProfile(userID, firstName, lastName, email string) (*entities.User, error) {
-	user, err := s.userRepo.FindByID(userID)
+func (s *AuthServiceImpl) UpdateProfile(ctx context.Context, userID, firstName, lastName, email string) (*entities.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.UpdateProfile")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -80,10 +212,40 @@ func (s *AuthServiceImpl) UpdateProfile(userID, firstName, lastName, email strin
 	user.Email = email
 	user.UpdatedAt = time.Now()
 
-	err = s.userRepo.Update(user)
+	err = s.userRepo.Update(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
+
+func (s *AuthServiceImpl) setPassword(ctx context.Context, user *entities.User, newPassword string) error {
+	passwordHash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = passwordHash
+	user.UpdatedAt = time.Now()
+	return s.userRepo.Update(ctx, user)
+}
+
+func hashPassword(password string) (string, error) {
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+)
+
+var (
+	ErrEncounterNotFound    = errors.New("encounter not found")
+	ErrEncounterSigned      = errors.New("signed encounters cannot be edited, add an amendment instead")
+	ErrEncounterNotSigned   = errors.New("only signed encounters can be amended, edit the draft instead")
+	ErrNotEncounterProvider = errors.New("only the encounter's provider can sign it")
+	ErrEmptyEncounter       = errors.New("cannot sign an encounter without notes")
+)
+
+type EncounterService interface {
+	CreateEncounter(ctx context.Context, encounter *entities.Encounter) error
+	GetEncounter(ctx context.Context, id string) (*entities.Encounter, error)
+	ListPatientEncounters(ctx context.Context, patientID string) ([]*entities.Encounter, error)
+	UpdateDraft(ctx context.Context, encounter *entities.Encounter) error
+	SignEncounter(ctx context.Context, id, userID string) (*entities.Encounter, error)
+	AmendEncounter(ctx context.Context, id, section, text, reason, userID string) (*entities.Encounter, error)
+}
+
+type EncounterServiceImpl struct {
+	encounterRepo   repositories.EncounterRepository
+	patientRepo     repositories.PatientRepository
+	userRepo        repositories.UserRepository
+	appointmentRepo repositories.AppointmentRepository
+}
+
+func NewEncounterService(
+	encounterRepo repositories.EncounterRepository,
+	patientRepo repositories.PatientRepository,
+	userRepo repositories.UserRepository,
+	appointmentRepo repositories.AppointmentRepository,
+) EncounterService {
+	return &EncounterServiceImpl{
+		encounterRepo:   encounterRepo,
+		patientRepo:     patientRepo,
+		userRepo:        userRepo,
+		appointmentRepo: appointmentRepo,
+	}
+}
+
+func (s *EncounterServiceImpl) CreateEncounter(ctx context.Context, encounter *entities.Encounter) error {
+	if _, err := s.patientRepo.FindByID(ctx, encounter.PatientID); err != nil {
+		return ErrPatientNotFound
+	}
+
+	if _, err := s.userRepo.FindByID(ctx, encounter.ProviderID); err != nil {
+		return ErrProviderNotFound
+	}
+
+	if err := s.checkAppointment(ctx, encounter); err != nil {
+		return err
+	}
+	if err := validateEncounter(encounter); err != nil {
+		return err
+	}
+
+	encounter.Status = entities.EncounterDraft
+	encounter.SignedAt = nil
+	encounter.SignedBy = ""
+	encounter.Amendments = nil
+	return s.encounterRepo.Create(ctx, encounter)
+}
+
+func (s *EncounterServiceImpl) GetEncounter(ctx context.Context, id string) (*entities.Encounter, error) {
+	encounter, err := s.encounterRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrEncounterNotFound
+	}
+	return encounter, nil
+}
+
+func (s *EncounterServiceImpl) ListPatientEncounters(ctx context.Context, patientID string) ([]*entities.Encounter, error) {
+	if _, err := s.patientRepo.FindByID(ctx, patientID); err != nil {
+		return nil, ErrPatientNotFound
+	}
+	return s.encounterRepo.FindByPatient(ctx, patientID)
+}
+
+// UpdateDraft replaces the notes, vitals and diagnoses of a draft encounter
+func (s *EncounterServiceImpl) UpdateDraft(ctx context.Context, encounter *entities.Encounter) error {
+	existing, err := s.GetEncounter(ctx, encounter.ID)
+	if err != nil {
+		return err
+	}
+	if existing.IsSigned() {
+		return ErrEncounterSigned
+	}
+
+	encounter.PatientID = existing.PatientID
+	encounter.ProviderID = existing.ProviderID
+	if err := s.checkAppointment(ctx, encounter); err != nil {
+		return err
+	}
+	if err := validateEncounter(encounter); err != nil {
+		return err
+	}
+
+	encounter.UpdatedAt = time.Now()
+	updated, err := s.encounterRepo.UpdateDraft(ctx, encounter)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrEncounterSigned
+	}
+	return nil
+}
+
+// SignEncounter finalizes the notes. Only the provider who saw the patient
+// can sign.
+func (s *EncounterServiceImpl) SignEncounter(ctx context.Context, id, userID string) (*entities.Encounter, error) {
+	encounter, err := s.GetEncounter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.IsSigned() {
+		return nil, ErrEncounterSigned
+	}
+	if encounter.ProviderID != userID {
+		return nil, ErrNotEncounterProvider
+	}
+	if strings.TrimSpace(encounter.Subjective+encounter.Objective+encounter.Assessment+encounter.Plan) == "" {
+		return nil, ErrEmptyEncounter
+	}
+
+	now := time.Now()
+	encounter.Status = entities.EncounterSigned
+	encounter.SignedAt = &now
+	encounter.SignedBy = userID
+	encounter.UpdatedAt = now
+
+	signed, err := s.encounterRepo.Sign(ctx, encounter)
+	if err != nil {
+		return nil, err
+	}
+	if !signed {
+		return nil, ErrEncounterSigned
+	}
+	return encounter, nil
+}
+
+// AmendEncounter corrects one section of a signed encounter. The signed text
+// stays untouched; the amendment records the text it replaces, the new text,
+// who made the change and why.
+func (s *EncounterServiceImpl) AmendEncounter(ctx context.Context, id, section, text, reason, userID string) (*entities.Encounter, error) {
+	encounter, err := s.GetEncounter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !encounter.IsSigned() {
+		return nil, ErrEncounterNotSigned
+	}
+
+	if _, ok := encounter.SignedText(section); !ok {
+		return nil, fmt.Errorf("unknown section %q, expected subjective, objective, assessment or plan", section)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, errors.New("an amendment requires a reason")
+	}
+
+	current := encounter.CurrentText(section)
+	if text == current {
+		return nil, errors.New("the amended text is identical to the current text")
+	}
+
+	amendment := entities.EncounterAmendment{
+		EncounterID:  encounter.ID,
+		Section:      section,
+		OriginalText: current,
+		AmendedText:  text,
+		Reason:       strings.TrimSpace(reason),
+		AmendedBy:    userID,
+		AmendedAt:    time.Now(),
+	}
+	if err := s.encounterRepo.CreateAmendment(ctx, &amendment); err != nil {
+		return nil, err
+	}
+
+	encounter.Amendments = append(encounter.Amendments, amendment)
+	return encounter, nil
+}
+
+// checkAppointment makes sure a linked appointment is the same visit
+func (s *EncounterServiceImpl) checkAppointment(ctx context.Context, encounter *entities.Encounter) error {
+	if encounter.AppointmentID == nil {
+		return nil
+	}
+
+	appointment, err := s.appointmentRepo.FindByID(ctx, *encounter.AppointmentID)
+	if err != nil {
+		return ErrAppointmentNotFound
+	}
+	if appointment.PatientID != encounter.PatientID {
+		return errors.New("the appointment belongs to a different patient")
+	}
+	return nil
+}
+
+func validateEncounter(encounter *entities.Encounter) error {
+	if err := validateVitals(encounter.Vitals); err != nil {
+		return err
+	}
+
+	primary := 0
+	for i := range encounter.Diagnoses {
+		diagnosis := &encounter.Diagnoses[i]
+		diagnosis.ID = ""
+		diagnosis.EncounterID = encounter.ID
+		diagnosis.Code = strings.ToUpper(strings.TrimSpace(diagnosis.Code))
+		if diagnosis.Code == "" {
+			return errors.New("diagnoses require a code")
+		}
+		if diagnosis.IsPrimary {
+			primary++
+		}
+	}
+	if primary > 1 {
+		return errors.New("only one diagnosis can be primary")
+	}
+	return nil
+}
+
+// validateVitals rejects values that cannot be physiological, which are
+// almost always typing mistakes
+func validateVitals(vitals entities.Vitals) error {
+	checks := []struct {
+		name     string
+		value    *float64
+		min, max float64
+	}{
+		{"temperature", vitals.TemperatureC, 25, 45},
+		{"heart rate", intValue(vitals.HeartRate), 20, 300},
+		{"respiratory rate", intValue(vitals.RespiratoryRate), 2, 80},
+		{"systolic blood pressure", intValue(vitals.SystolicBP), 40, 300},
+		{"diastolic blood pressure", intValue(vitals.DiastolicBP), 20, 200},
+		{"oxygen saturation", intValue(vitals.OxygenSaturation), 50, 100},
+		{"weight", vitals.WeightKg, 0.2, 700},
+		{"height", vitals.HeightCm, 20, 280},
+	}
+	for _, check := range checks {
+		if check.value != nil && (*check.value < check.min || *check.value > check.max) {
+			return fmt.Errorf("%s must be between %g and %g", check.name, check.min, check.max)
+		}
+	}
+
+	if vitals.SystolicBP != nil && vitals.DiastolicBP != nil && *vitals.DiastolicBP >= *vitals.SystolicBP {
+		return errors.New("diastolic blood pressure must be lower than systolic")
+	}
+	return nil
+}
+
+func intValue(value *int) *float64 {
+	if value == nil {
+		return nil
+	}
+	converted := float64(*value)
+	return &converted
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+)
+
+// Fallbacks used when a tenant has no throttling settings configured
+const (
+	defaultMaxFailedLogins      = 5
+	defaultMaxFailedLoginsPerIP = 20
+	defaultLockoutMinutes       = 15
+)
+
+// maxPasswordResets is how many reset mails an account is sent within the
+// lockout window
+const maxPasswordResets = 3
+
+// Progressive delay: after freeFailedLogins attempts every further attempt
+// must wait twice as long as the previous one, up to maxLoginDelay
+const (
+	freeFailedLogins = 2
+	maxLoginDelay    = time.Minute
+)
+
+// LockoutError is returned when a login attempt is refused because of
+// previous failures. RetryAfter tells the client when to try again.
+type LockoutError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LockoutError) Error() string {
+	seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+	if e.Locked {
+		return fmt.Sprintf("account temporarily locked, retry in %d seconds", seconds)
+	}
+	return fmt.Sprintf("too many failed attempts, retry in %d seconds", seconds)
+}
+
+// LoginThrottleService counts login attempts before the credentials are
+// checked: parallel guesses then see each other, and no more than the
+// tenant's limit of them is ever checked.
+type LoginThrottleService interface {
+	// Attempt counts an attempt against the email and the IP, refusing it
+	// while either is locked out or inside its progressive delay
+	Attempt(ctx context.Context, email, ipAddress string) error
+	// AttemptPasswordReset is Attempt for password reset requests, which are
+	// counted apart from logins
+	AttemptPasswordReset(ctx context.Context, email, ipAddress string) error
+	// RecordSuccess clears the email counter and takes the attempt back
+	// from the IP counter
+	RecordSuccess(ctx context.Context, email, ipAddress string) error
+	Unlock(ctx context.Context, email string) error
+}
+
+type LoginThrottleServiceImpl struct {
+	throttleRepo  repositories.LoginThrottleRepository
+	tenantService TenantService
+}
+
+func NewLoginThrottleService(throttleRepo repositories.LoginThrottleRepository, tenantService TenantService) LoginThrottleService {
+	return &LoginThrottleServiceImpl{
+		throttleRepo:  throttleRepo,
+		tenantService: tenantService,
+	}
+}
+
+// Attempt locks the email or the IP out once the attempt reaches the
+// tenant's threshold. The IP counter is shared by every tenant and is held
+// to the threshold of the tenant attempted. The counters are locked while
+// they are read and updated, and a refused attempt counts against neither.
+func (s *LoginThrottleServiceImpl) Attempt(ctx context.Context, email, ipAddress string) error {
+	ctx, span := tracer.Start(ctx, "LoginThrottleService.Attempt")
+	defer span.End()
+
+	maxPerEmail, maxPerIP, lockout := s.limits(ctx)
+	return s.attempt(ctx, entities.ThrottleScopeLogin, email, ipAddress, maxPerEmail, maxPerIP, lockout)
+}
+
+// AttemptPasswordReset counts requests whether or not the account exists,
+// so being refused tells nothing about it
+func (s *LoginThrottleServiceImpl) AttemptPasswordReset(ctx context.Context, email, ipAddress string) error {
+	ctx, span := tracer.Start(ctx, "LoginThrottleService.AttemptPasswordReset")
+	defer span.End()
+
+	_, maxPerIP, lockout := s.limits(ctx)
+	return s.attempt(ctx, entities.ThrottleScopePasswordReset, email, ipAddress, maxPasswordResets, maxPerIP, lockout)
+}
+
+func (s *LoginThrottleServiceImpl) attempt(ctx context.Context, scope, email, ipAddress string, maxPerEmail, maxPerIP int, lockout time.Duration) error {
+	now := time.Now()
+	err := s.throttleRepo.Transaction(ctx, func(repo repositories.LoginThrottleRepository) error {
+		throttle, err := repo.FindForUpdate(ctx, scope, normalizeEmail(email))
+		if err != nil {
+			return err
+		}
+		if err := countAttempt(&throttle.ThrottleCounter, now, maxPerEmail, lockout); err != nil {
+			return err
+		}
+		if err := repo.Save(ctx, throttle); err != nil {
+			return err
+		}
+		if ipAddress == "" {
+			return nil
+		}
+
+		ipThrottle, err := repo.FindIPForUpdate(ctx, scope, ipAddress)
+		if err != nil {
+			return err
+		}
+		if err := countAttempt(&ipThrottle.ThrottleCounter, now, maxPerIP, lockout); err != nil {
+			return err
+		}
+		return repo.SaveIP(ctx, ipThrottle)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Counters of addresses that stopped trying are of no further use;
+	// without this, every address ever tried would keep a row
+	return s.throttleRepo.Transaction(ctx, func(repo repositories.LoginThrottleRepository) error {
+		return repo.DeleteInactive(ctx, now.Add(-lockout))
+	})
+}
+
+// countAttempt counts an attempt made at now, or returns a LockoutError
+// when the counter refuses it
+func countAttempt(throttle *entities.ThrottleCounter, now time.Time, threshold int, lockout time.Duration) error {
+	// Attempts older than the lockout window, or from before an expired
+	// lockout, no longer count
+	expiredLock := throttle.LockedUntil != nil && !now.Before(*throttle.LockedUntil)
+	if expiredLock || now.Sub(throttle.LastFailedAt) > lockout {
+		throttle.FailedCount = 0
+		throttle.LockedUntil = nil
+	}
+
+	if throttle.LockedUntil != nil {
+		return &LockoutError{RetryAfter: throttle.LockedUntil.Sub(now), Locked: true}
+	}
+	if retryAt := throttle.LastFailedAt.Add(progressiveDelay(throttle.FailedCount)); now.Before(retryAt) {
+		return &LockoutError{RetryAfter: retryAt.Sub(now)}
+	}
+
+	throttle.FailedCount++
+	throttle.LastFailedAt = now
+	if throttle.FailedCount >= threshold {
+		lockedUntil := now.Add(lockout)
+		throttle.LockedUntil = &lockedUntil
+	}
+	return nil
+}
+
+// RecordSuccess leaves the failures before the attempt on the IP counter to
+// expire on their own, so a valid login cannot be used to keep guessing
+// other accounts
+func (s *LoginThrottleServiceImpl) RecordSuccess(ctx context.Context, email, ipAddress string) error {
+	ctx, span := tracer.Start(ctx, "LoginThrottleService.RecordSuccess")
+	defer span.End()
+
+	_, maxPerIP, _ := s.limits(ctx)
+	return s.throttleRepo.Transaction(ctx, func(repo repositories.LoginThrottleRepository) error {
+		if err := repo.Delete(ctx, entities.ThrottleScopeLogin, normalizeEmail(email)); err != nil {
+			return err
+		}
+		if ipAddress == "" {
+			return nil
+		}
+
+		throttle, err := repo.FindIPForUpdate(ctx, entities.ThrottleScopeLogin, ipAddress)
+		if err != nil {
+			return err
+		}
+		if throttle.FailedCount > 0 {
+			throttle.FailedCount--
+		}
+		if throttle.FailedCount < maxPerIP {
+			throttle.LockedUntil = nil
+		}
+		return repo.SaveIP(ctx, throttle)
+	})
+}
+
+func (s *LoginThrottleServiceImpl) Unlock(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "LoginThrottleService.Unlock")
+	defer span.End()
+
+	return s.throttleRepo.Transaction(ctx, func(repo repositories.LoginThrottleRepository) error {
+		return repo.Delete(ctx, entities.ThrottleScopeLogin, normalizeEmail(email))
+	})
+}
+
+func (s *LoginThrottleServiceImpl) limits(ctx context.Context) (int, int, time.Duration) {
+	maxPerEmail, maxPerIP, lockoutMinutes := defaultMaxFailedLogins, defaultMaxFailedLoginsPerIP, defaultLockoutMinutes
+
+	if settings, err := s.tenantService.GetTenantSettings(ctx); err == nil {
+		if settings.MaxFailedLogins > 0 {
+			maxPerEmail = settings.MaxFailedLogins
+		}
+		if settings.MaxFailedLoginsPerIP > 0 {
+			maxPerIP = settings.MaxFailedLoginsPerIP
+		}
+		if settings.LockoutMinutes > 0 {
+			lockoutMinutes = settings.LockoutMinutes
+		}
+	}
+
+	return maxPerEmail, maxPerIP, time.Duration(lockoutMinutes) * time.Minute
+}
+
+func progressiveDelay(failedCount int) time.Duration {
+	if failedCount <= freeFailedLogins {
+		return 0
+	}
+
+	delay := time.Second << uint(failedCount-freeFailedLogins-1)
+	if delay > maxLoginDelay || delay <= 0 {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
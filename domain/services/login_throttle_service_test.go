@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"medical-system/domain/entities"
+)
+
+func TestProgressiveDelay(t *testing.T) {
+	for _, tc := range []struct {
+		failedCount int
+		want        time.Duration
+	}{
+		{0, 0},
+		{freeFailedLogins, 0},
+		{freeFailedLogins + 1, time.Second},
+		{freeFailedLogins + 2, 2 * time.Second},
+		{freeFailedLogins + 6, 32 * time.Second},
+		{freeFailedLogins + 7, maxLoginDelay},
+		{freeFailedLogins + 100, maxLoginDelay},
+	} {
+		if got := progressiveDelay(tc.failedCount); got != tc.want {
+			t.Errorf("progressiveDelay(%d) = %v, want %v", tc.failedCount, got, tc.want)
+		}
+	}
+}
+
+func TestCountAttempt(t *testing.T) {
+	const threshold, lockout = 5, 15 * time.Minute
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(time.Minute)
+	expiredLock := now.Add(-time.Second)
+
+	for _, tc := range []struct {
+		name        string
+		throttle    entities.ThrottleCounter
+		wantCount   int
+		wantLocked  bool
+		wantRefused bool
+	}{
+		{
+			name:      "first attempt",
+			wantCount: 1,
+		},
+		{
+			name:      "within the free attempts",
+			throttle:  entities.ThrottleCounter{FailedCount: freeFailedLogins, LastFailedAt: now},
+			wantCount: freeFailedLogins + 1,
+		},
+		{
+			name:        "inside the progressive delay",
+			throttle:    entities.ThrottleCounter{FailedCount: freeFailedLogins + 1, LastFailedAt: now.Add(-500 * time.Millisecond)},
+			wantCount:   freeFailedLogins + 1,
+			wantRefused: true,
+		},
+		{
+			name:      "after the progressive delay",
+			throttle:  entities.ThrottleCounter{FailedCount: freeFailedLogins + 1, LastFailedAt: now.Add(-time.Second)},
+			wantCount: freeFailedLogins + 2,
+		},
+		{
+			name:       "reaching the threshold locks",
+			throttle:   entities.ThrottleCounter{FailedCount: threshold - 1, LastFailedAt: now.Add(-time.Minute)},
+			wantCount:  threshold,
+			wantLocked: true,
+		},
+		{
+			name:        "locked",
+			throttle:    entities.ThrottleCounter{FailedCount: threshold, LastFailedAt: now.Add(-time.Minute), LockedUntil: &lockedUntil},
+			wantCount:   threshold,
+			wantLocked:  true,
+			wantRefused: true,
+		},
+		{
+			name:      "expired lockout starts over",
+			throttle:  entities.ThrottleCounter{FailedCount: threshold, LastFailedAt: now.Add(-lockout), LockedUntil: &expiredLock},
+			wantCount: 1,
+		},
+		{
+			name:      "attempts older than the window start over",
+			throttle:  entities.ThrottleCounter{FailedCount: threshold - 1, LastFailedAt: now.Add(-lockout - time.Second)},
+			wantCount: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			throttle := tc.throttle
+			err := countAttempt(&throttle, now, threshold, lockout)
+
+			var lockoutErr *LockoutError
+			if refused := errors.As(err, &lockoutErr); refused != tc.wantRefused {
+				t.Fatalf("err = %v, want refused %v", err, tc.wantRefused)
+			}
+			if throttle.FailedCount != tc.wantCount {
+				t.Errorf("FailedCount = %d, want %d", throttle.FailedCount, tc.wantCount)
+			}
+			if locked := throttle.LockedUntil != nil; locked != tc.wantLocked {
+				t.Errorf("LockedUntil = %v, want locked %v", throttle.LockedUntil, tc.wantLocked)
+			}
+		})
+	}
+}
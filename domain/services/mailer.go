@@ -0,0 +1,13 @@
+package services
+
+// EmailMessage is an outbound plain-text email
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outbound email
+type Mailer interface {
+	Send(message EmailMessage) error
+}
@@ -0,0 +1,21 @@
+package services
+
+import "medical-system/domain/entities"
+
+// Outcomes of a login step counted by BusinessMetrics
+const (
+	LoginSucceeded   = "succeeded"
+	LoginFailed      = "failed"
+	LoginDenied      = "denied"
+	LoginMFARequired = "mfa_required"
+)
+
+// BusinessMetrics counts business events for monitoring. Implementations
+// must be cheap and safe for concurrent use; they never fail the operation.
+type BusinessMetrics interface {
+	// LoginAttempt counts the outcome of a login step, "login" or
+	// "mfa_verify"
+	LoginAttempt(step, outcome string)
+	TenantRegistered(plan entities.SubscriptionPlan)
+	UserLimitRejected()
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mfaIssuer is the account issuer shown by authenticator apps
+const mfaIssuer = "Medical System"
+
+// recoveryCodeCount is the number of recovery codes issued on enrollment
+const recoveryCodeCount = 10
+
+// recoveryCodeAlphabet avoids characters that are easily confused (0/O, 1/I/L)
+const recoveryCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+var (
+	ErrInvalidMFACode      = errors.New("invalid authentication code")
+	ErrMFAAlreadyEnabled   = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnrolled      = errors.New("multi-factor authentication enrollment has not been started")
+	ErrMFARequiredByTenant = errors.New("multi-factor authentication is required by your organization")
+	ErrMFANotEnabled       = errors.New("multi-factor authentication is not enabled")
+)
+
+// MFAEnrollment holds what a client needs to register the TOTP secret in an authenticator app
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFAService interface {
+	IsRequired(ctx context.Context, user *entities.User) (bool, error)
+	BeginEnrollment(ctx context.Context, userID string) (*MFAEnrollment, error)
+	ActivateEnrollment(ctx context.Context, userID, code string) ([]string, error)
+	VerifyCode(ctx context.Context, userID, code string) error
+	VerifyRecoveryCode(ctx context.Context, userID, recoveryCode string) error
+	Disable(ctx context.Context, userID, code string) error
+}
+
+type MFAServiceImpl struct {
+	userRepo         repositories.UserRepository
+	recoveryCodeRepo repositories.MFARecoveryCodeRepository
+	tenantService    TenantService
+	secretCipher     SecretCipher
+}
+
+func NewMFAService(
+	userRepo repositories.UserRepository,
+	recoveryCodeRepo repositories.MFARecoveryCodeRepository,
+	tenantService TenantService,
+	secretCipher SecretCipher,
+) MFAService {
+	return &MFAServiceImpl{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		tenantService:    tenantService,
+		secretCipher:     secretCipher,
+	}
+}
+
+// IsRequired reports whether the user must pass a second factor to log in,
+// either because they opted in or because their tenant enforces it
+func (s *MFAServiceImpl) IsRequired(ctx context.Context, user *entities.User) (bool, error) {
+	ctx, span := tracer.Start(ctx, "MFAService.IsRequired")
+	defer span.End()
+
+	if user.MFAEnabled {
+		return true, nil
+	}
+	return s.tenantRequiresMFA(ctx)
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. The secret only
+// takes effect once it is confirmed with ActivateEnrollment.
+func (s *MFAServiceImpl) BeginEnrollment(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	ctx, span := tracer.Start(ctx, "MFAService.BeginEnrollment")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encryptedSecret, err := s.secretCipher.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFASecret = encryptedSecret
+	user.MFALastUsedStep = 0
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ActivateEnrollment confirms the pending secret with a valid code, enables
+// MFA and returns a fresh set of recovery codes, shown to the user only once
+func (s *MFAServiceImpl) ActivateEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "MFAService.ActivateEnrollment")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if err := s.useCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.issueRecoveryCodes(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFAEnabled = true
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// VerifyCode checks a TOTP code, refusing codes from an already used time step
+func (s *MFAServiceImpl) VerifyCode(ctx context.Context, userID, code string) error {
+	ctx, span := tracer.Start(ctx, "MFAService.VerifyCode")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+
+	return s.useCode(ctx, user, code)
+}
+
+// useCode checks a TOTP code and claims its time step, so a code is accepted
+// once even when it is presented twice at the same time
+func (s *MFAServiceImpl) useCode(ctx context.Context, user *entities.User, code string) error {
+	secret, err := s.secretCipher.Decrypt(user.MFASecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok || step <= user.MFALastUsedStep {
+		return ErrInvalidMFACode
+	}
+
+	claimed, err := s.userRepo.ClaimMFAStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrInvalidMFACode
+	}
+	user.MFALastUsedStep = step
+	return nil
+}
+
+// VerifyRecoveryCode consumes one of the user's recovery codes
+func (s *MFAServiceImpl) VerifyRecoveryCode(ctx context.Context, userID, recoveryCode string) error {
+	ctx, span := tracer.Start(ctx, "MFAService.VerifyRecoveryCode")
+	defer span.End()
+
+	codes, err := s.recoveryCodeRepo.FindUnusedByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	normalized := normalizeRecoveryCode(recoveryCode)
+	for _, code := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(code.CodeHash), []byte(normalized)) != nil {
+			continue
+		}
+
+		claimed, err := s.recoveryCodeRepo.MarkUsed(ctx, code.ID)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			return nil
+		}
+		break
+	}
+
+	return ErrInvalidMFACode
+}
+
+// Disable turns MFA off after confirming a current code. Users of tenants
+// that enforce MFA cannot opt out.
+func (s *MFAServiceImpl) Disable(ctx context.Context, userID, code string) error {
+	ctx, span := tracer.Start(ctx, "MFAService.Disable")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	required, err := s.tenantRequiresMFA(ctx)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByTenant
+	}
+
+	if err := s.VerifyCode(ctx, userID, code); err != nil {
+		return err
+	}
+
+	// VerifyCode updated the last used step, reload before clearing
+	user, err = s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	user.MFALastUsedStep = 0
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	return s.recoveryCodeRepo.DeleteForUser(ctx, userID)
+}
+
+func (s *MFAServiceImpl) tenantRequiresMFA(ctx context.Context) (bool, error) {
+	settings, err := s.tenantService.GetTenantSettings(ctx)
+	if err != nil {
+		return false, err
+	}
+	return settings.RequireMFA, nil
+}
+
+func (s *MFAServiceImpl) issueRecoveryCodes(ctx context.Context, user *entities.User) ([]string, error) {
+	plain := make([]string, 0, recoveryCodeCount)
+	records := make([]*entities.MFARecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(normalizeRecoveryCode(code)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+
+		plain = append(plain, code)
+		records = append(records, &entities.MFARecoveryCode{
+			UserID:   user.ID,
+			CodeHash: string(hash),
+		})
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(ctx, user.ID, records); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// newRecoveryCode returns a code formatted as XXXXX-XXXXX, its characters
+// drawn uniformly from the alphabet
+func newRecoveryCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	var b strings.Builder
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			b.WriteByte('-')
+		}
+		index, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(recoveryCodeAlphabet[index.Int64()])
+	}
+	return b.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// newOpaqueToken returns a random URL-safe token with 256 bits of entropy
+func newOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// newTenantToken returns an opaque token that names the tenant it belongs
+// to, so that it can be looked up in that tenant alone. The name is no more
+// than a hint: a token presented to another tenant matches nothing there.
+func newTenantToken(tenantID string) (string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return tenantID + "." + token, nil
+}
+
+// tokenTenant returns the tenant named by a token of newTenantToken
+func tokenTenant(token string) (string, bool) {
+	tenantID, _, ok := strings.Cut(token, ".")
+	return tenantID, ok && tenantID != ""
+}
+
+// hashOpaqueToken returns the hex SHA-256 digest under which a token is stored
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
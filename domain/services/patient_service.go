@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+)
+
+// maxPatientAge bounds plausible dates of birth
+const maxPatientAge = 150
+
+// mrnCreateAttempts is how many generated MRNs are tried before giving up
+const mrnCreateAttempts = 3
+
+var ErrPatientNotFound = errors.New("patient not found")
+
+// DuplicatePatientError reports existing patients that look like the one
+// being registered. Hard duplicates share the MRN or national ID and are
+// always rejected; name and date-of-birth matches can be overridden.
+type DuplicatePatientError struct {
+	Matches []*entities.Patient
+	Hard    bool
+}
+
+func (e *DuplicatePatientError) Error() string {
+	if e.Hard {
+		return "a patient with the same MRN or national ID already exists"
+	}
+	return "a patient with the same name and date of birth already exists"
+}
+
+type PatientService interface {
+	RegisterPatient(ctx context.Context, patient *entities.Patient, allowDuplicate bool) error
+	GetPatient(ctx context.Context, id string) (*entities.Patient, error)
+	UpdatePatient(ctx context.Context, patient *entities.Patient, allowDuplicate bool) error
+	ArchivePatient(ctx context.Context, id string) error
+	SearchPatients(ctx context.Context, criteria repositories.PatientSearchCriteria) ([]*entities.Patient, int64, error)
+}
+
+type PatientServiceImpl struct {
+	patientRepo repositories.PatientRepository
+}
+
+func NewPatientService(patientRepo repositories.PatientRepository) PatientService {
+	return &PatientServiceImpl{patientRepo: patientRepo}
+}
+
+func (s *PatientServiceImpl) RegisterPatient(ctx context.Context, patient *entities.Patient, allowDuplicate bool) error {
+	normalizePatient(patient)
+	if err := validatePatient(patient); err != nil {
+		return err
+	}
+
+	if err := s.checkDuplicates(ctx, patient, allowDuplicate); err != nil {
+		return err
+	}
+
+	patient.IsActive = true
+	if patient.MRN != "" {
+		return s.uniqueWrite(ctx, patient, s.patientRepo.Create(ctx, patient))
+	}
+
+	// Generated MRNs are random, retry in the unlikely event of a collision
+	var err error
+	for attempt := 0; attempt < mrnCreateAttempts; attempt++ {
+		if patient.MRN, err = generateMRN(); err != nil {
+			return err
+		}
+		err = s.patientRepo.Create(ctx, patient)
+		if !errors.Is(err, repositories.ErrDuplicateKey) {
+			return err
+		}
+		patient.ID = ""
+
+		// Another MRN does not help when the national ID is taken
+		if patient.NationalID != "" {
+			withoutMRN := *patient
+			withoutMRN.MRN = ""
+			matches, findErr := s.patientRepo.FindPossibleDuplicates(ctx, &withoutMRN)
+			if findErr != nil {
+				return findErr
+			}
+			if hardDuplicate(&withoutMRN, matches) {
+				return &DuplicatePatientError{Matches: matches, Hard: true}
+			}
+		}
+	}
+	return err
+}
+
+func (s *PatientServiceImpl) GetPatient(ctx context.Context, id string) (*entities.Patient, error) {
+	patient, err := s.patientRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrPatientNotFound
+	}
+	return patient, nil
+}
+
+func (s *PatientServiceImpl) UpdatePatient(ctx context.Context, patient *entities.Patient, allowDuplicate bool) error {
+	if _, err := s.GetPatient(ctx, patient.ID); err != nil {
+		return err
+	}
+
+	normalizePatient(patient)
+	if err := validatePatient(patient); err != nil {
+		return err
+	}
+	if patient.MRN == "" {
+		return errors.New("MRN cannot be empty")
+	}
+
+	if err := s.checkDuplicates(ctx, patient, allowDuplicate); err != nil {
+		return err
+	}
+
+	patient.UpdatedAt = time.Now()
+	return s.uniqueWrite(ctx, patient, s.patientRepo.Update(ctx, patient))
+}
+
+// ArchivePatient deactivates a patient. Patient records are never physically
+// removed because clinical history refers to them.
+func (s *PatientServiceImpl) ArchivePatient(ctx context.Context, id string) error {
+	patient, err := s.GetPatient(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	patient.IsActive = false
+	patient.UpdatedAt = time.Now()
+	return s.patientRepo.Update(ctx, patient)
+}
+
+func (s *PatientServiceImpl) SearchPatients(ctx context.Context, criteria repositories.PatientSearchCriteria) ([]*entities.Patient, int64, error) {
+	return s.patientRepo.Search(ctx, criteria)
+}
+
+func (s *PatientServiceImpl) checkDuplicates(ctx context.Context, patient *entities.Patient, allowDuplicate bool) error {
+	matches, err := s.patientRepo.FindPossibleDuplicates(ctx, patient)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	if hardDuplicate(patient, matches) {
+		return &DuplicatePatientError{Matches: matches, Hard: true}
+	}
+	if allowDuplicate {
+		return nil
+	}
+	return &DuplicatePatientError{Matches: matches}
+}
+
+// uniqueWrite reports a write the unique indexes refused as a hard
+// duplicate, along with the patients registered since checkDuplicates looked
+func (s *PatientServiceImpl) uniqueWrite(ctx context.Context, patient *entities.Patient, err error) error {
+	if !errors.Is(err, repositories.ErrDuplicateKey) {
+		return err
+	}
+
+	matches, findErr := s.patientRepo.FindPossibleDuplicates(ctx, patient)
+	if findErr != nil {
+		return findErr
+	}
+	return &DuplicatePatientError{Matches: matches, Hard: true}
+}
+
+// hardDuplicate tells whether a match shares the MRN or national ID of patient
+func hardDuplicate(patient *entities.Patient, matches []*entities.Patient) bool {
+	for _, match := range matches {
+		sameMRN := patient.MRN != "" && match.MRN == patient.MRN
+		sameNationalID := patient.NationalID != "" && match.NationalID == patient.NationalID
+		if sameMRN || sameNationalID {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePatient trims input and makes the nested records belong to the patient
+func normalizePatient(patient *entities.Patient) {
+	patient.MRN = strings.ToUpper(strings.TrimSpace(patient.MRN))
+	patient.NationalID = strings.ToUpper(strings.TrimSpace(patient.NationalID))
+	patient.FirstName = strings.TrimSpace(patient.FirstName)
+	patient.LastName = strings.TrimSpace(patient.LastName)
+	patient.Email = normalizeEmail(patient.Email)
+	patient.Phone = strings.TrimSpace(patient.Phone)
+	patient.Sex = strings.ToLower(strings.TrimSpace(patient.Sex))
+	if patient.Sex == "" {
+		patient.Sex = entities.SexUnknown
+	}
+
+	for i := range patient.EmergencyContacts {
+		contact := &patient.EmergencyContacts[i]
+		contact.ID = ""
+		contact.PatientID = patient.ID
+		contact.Name = strings.TrimSpace(contact.Name)
+		contact.Phone = strings.TrimSpace(contact.Phone)
+	}
+	for i := range patient.InsurancePolicies {
+		policy := &patient.InsurancePolicies[i]
+		policy.ID = ""
+		policy.PatientID = patient.ID
+		policy.Provider = strings.TrimSpace(policy.Provider)
+		policy.PolicyNumber = strings.TrimSpace(policy.PolicyNumber)
+	}
+}
+
+func validatePatient(patient *entities.Patient) error {
+	if patient.FirstName == "" || patient.LastName == "" {
+		return errors.New("first and last name are required")
+	}
+
+	if patient.DateOfBirth.IsZero() {
+		return errors.New("date of birth is required")
+	}
+	now := time.Now()
+	if patient.DateOfBirth.After(now) {
+		return errors.New("date of birth cannot be in the future")
+	}
+	if patient.DateOfBirth.Before(now.AddDate(-maxPatientAge, 0, 0)) {
+		return fmt.Errorf("date of birth cannot be more than %d years ago", maxPatientAge)
+	}
+
+	switch patient.Sex {
+	case entities.SexMale, entities.SexFemale, entities.SexOther, entities.SexUnknown:
+	default:
+		return errors.New("sex must be one of male, female, other, unknown")
+	}
+
+	if patient.Email != "" {
+		if _, err := mail.ParseAddress(patient.Email); err != nil {
+			return errors.New("invalid email address")
+		}
+	}
+
+	for _, contact := range patient.EmergencyContacts {
+		if contact.Name == "" || contact.Phone == "" {
+			return errors.New("emergency contacts require a name and phone")
+		}
+	}
+
+	primaryPolicies := 0
+	for _, policy := range patient.InsurancePolicies {
+		if policy.Provider == "" || policy.PolicyNumber == "" {
+			return errors.New("insurance policies require a provider and policy number")
+		}
+		if policy.ValidFrom != nil && policy.ValidUntil != nil && policy.ValidUntil.Before(*policy.ValidFrom) {
+			return errors.New("insurance policy validity ends before it starts")
+		}
+		if policy.IsPrimary {
+			primaryPolicies++
+		}
+	}
+	if primaryPolicies > 1 {
+		return errors.New("only one insurance policy can be primary")
+	}
+
+	return nil
+}
+
+// generateMRN returns a random medical record number such as MRN-5KQ2ZC7H
+func generateMRN() (string, error) {
+	raw := make([]byte, 5)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "MRN-" + base32.StdEncoding.EncodeToString(raw), nil
+}
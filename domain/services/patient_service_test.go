@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+)
+
+// racingPatientRepository refuses creates with the queued errors, as if
+// patients were registered between the duplicate check and the insert.
+// Those patients only show up once a create was refused.
+type racingPatientRepository struct {
+	repositories.PatientRepository
+	createErrs []error
+	registered []*entities.Patient
+	creates    int
+	refused    bool
+}
+
+func (r *racingPatientRepository) Create(ctx context.Context, patient *entities.Patient) error {
+	r.creates++
+	if len(r.createErrs) == 0 {
+		return nil
+	}
+	err := r.createErrs[0]
+	r.createErrs = r.createErrs[1:]
+	r.refused = r.refused || err != nil
+	return err
+}
+
+func (r *racingPatientRepository) FindPossibleDuplicates(ctx context.Context, patient *entities.Patient) ([]*entities.Patient, error) {
+	if !r.refused {
+		return nil, nil
+	}
+	var matches []*entities.Patient
+	for _, registered := range r.registered {
+		if (patient.MRN != "" && registered.MRN == patient.MRN) || (patient.NationalID != "" && registered.NationalID == patient.NationalID) {
+			matches = append(matches, registered)
+		}
+	}
+	return matches, nil
+}
+
+func TestRegisterPatientUniqueWrites(t *testing.T) {
+	duplicate := fmt.Errorf("%w: UNIQUE constraint failed", repositories.ErrDuplicateKey)
+	unavailable := errors.New("database is unavailable")
+
+	for _, tc := range []struct {
+		name        string
+		mrn         string
+		nationalID  string
+		registered  []*entities.Patient
+		createErrs  []error
+		wantCreates int
+		wantErr     error
+		wantMatches int
+	}{
+		{
+			name:        "generated MRN taken",
+			createErrs:  []error{duplicate, duplicate},
+			wantCreates: 3,
+		},
+		{
+			name:        "no free MRN",
+			createErrs:  []error{duplicate, duplicate, duplicate},
+			wantCreates: mrnCreateAttempts,
+			wantErr:     repositories.ErrDuplicateKey,
+		},
+		{
+			name:        "other errors are not retried",
+			createErrs:  []error{unavailable},
+			wantCreates: 1,
+			wantErr:     unavailable,
+		},
+		{
+			name:        "national ID registered meanwhile",
+			nationalID:  "NID-1",
+			registered:  []*entities.Patient{{ID: "p-1", NationalID: "NID-1"}},
+			createErrs:  []error{duplicate},
+			wantCreates: 1,
+			wantMatches: 1,
+		},
+		{
+			name:        "MRN registered meanwhile",
+			mrn:         "MRN-1",
+			registered:  []*entities.Patient{{ID: "p-1", MRN: "MRN-1"}},
+			createErrs:  []error{duplicate},
+			wantCreates: 1,
+			wantMatches: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &racingPatientRepository{createErrs: tc.createErrs, registered: tc.registered}
+			patient := &entities.Patient{
+				MRN:         tc.mrn,
+				NationalID:  tc.nationalID,
+				FirstName:   "Ada",
+				LastName:    "Lovelace",
+				DateOfBirth: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+			}
+
+			err := NewPatientService(repo).RegisterPatient(context.Background(), patient, false)
+			if repo.creates != tc.wantCreates {
+				t.Errorf("%d creates, want %d", repo.creates, tc.wantCreates)
+			}
+
+			var duplicateErr *DuplicatePatientError
+			switch {
+			case tc.wantMatches > 0:
+				if !errors.As(err, &duplicateErr) || !duplicateErr.Hard || len(duplicateErr.Matches) != tc.wantMatches {
+					t.Errorf("err = %v, want a hard duplicate with %d matches", err, tc.wantMatches)
+				}
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("err = %v, want %v", err, tc.wantErr)
+				}
+			case err != nil:
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package services
+
+// PolicyManager provisions the authorization policies that belong to a tenant
+type PolicyManager interface {
+	SeedTenantPolicies(tenantID string) error
+	// AssignRole gives a user a role inside a tenant
+	AssignRole(userID, role, tenantID string) error
+	// RemoveTenantPolicies removes every permission and role assignment
+	// inside a tenant
+	RemoveTenantPolicies(tenantID string) error
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+)
+
+// wallClockLayout is the format of working-hours template times
+const wallClockLayout = "15:04"
+
+// Limits for the length of the slots generated from a template
+const (
+	defaultSlotMinutes = 30
+	minSlotMinutes     = 5
+	maxSlotMinutes     = 8 * 60
+)
+
+var (
+	ErrProviderNotFound          = errors.New("provider not found")
+	ErrRoomNotFound              = errors.New("room not found")
+	ErrScheduleNotFound          = errors.New("schedule not found")
+	ErrScheduleExceptionNotFound = errors.New("schedule exception not found")
+	ErrScheduleOverlap           = errors.New("the provider already works at that time on that weekday")
+)
+
+// Slot is a bookable period. Start and End carry the tenant's timezone.
+type Slot struct {
+	Start  time.Time
+	End    time.Time
+	RoomID *string
+}
+
+type ScheduleService interface {
+	CreateRoom(ctx context.Context, room *entities.Room) error
+	ListRooms(ctx context.Context) ([]*entities.Room, error)
+
+	AddSchedule(ctx context.Context, schedule *entities.ProviderSchedule) error
+	ListSchedules(ctx context.Context, providerID string) ([]*entities.ProviderSchedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+
+	AddException(ctx context.Context, exception *entities.ScheduleException) error
+	ListExceptions(ctx context.Context, providerID string, from, to time.Time) ([]*entities.ScheduleException, error)
+	DeleteException(ctx context.Context, id string) error
+
+	// Location returns the timezone configured for the tenant
+	Location(ctx context.Context) *time.Location
+	// AvailableSlots returns the free slots of the provider on the calendar
+	// day of date, interpreted in the tenant's timezone
+	AvailableSlots(ctx context.Context, providerID string, date time.Time) ([]Slot, error)
+	// WorkingWindow returns the template that fully contains [start, end) and
+	// fails when the period is outside working hours or blocked by an exception
+	WorkingWindow(ctx context.Context, providerID string, start, end time.Time) (*entities.ProviderSchedule, error)
+}
+
+type ScheduleServiceImpl struct {
+	scheduleRepo    repositories.ScheduleRepository
+	roomRepo        repositories.RoomRepository
+	appointmentRepo repositories.AppointmentRepository
+	userRepo        repositories.UserRepository
+	tenantService   TenantService
+}
+
+func NewScheduleService(
+	scheduleRepo repositories.ScheduleRepository,
+	roomRepo repositories.RoomRepository,
+	appointmentRepo repositories.AppointmentRepository,
+	userRepo repositories.UserRepository,
+	tenantService TenantService,
+) ScheduleService {
+	return &ScheduleServiceImpl{
+		scheduleRepo:    scheduleRepo,
+		roomRepo:        roomRepo,
+		appointmentRepo: appointmentRepo,
+		userRepo:        userRepo,
+		tenantService:   tenantService,
+	}
+}
+
+func (s *ScheduleServiceImpl) CreateRoom(ctx context.Context, room *entities.Room) error {
+	room.Name = strings.TrimSpace(room.Name)
+	if room.Name == "" {
+		return errors.New("room name cannot be empty")
+	}
+	room.IsActive = true
+	return s.roomRepo.Create(ctx, room)
+}
+
+func (s *ScheduleServiceImpl) ListRooms(ctx context.Context) ([]*entities.Room, error) {
+	return s.roomRepo.List(ctx)
+}
+
+func (s *ScheduleServiceImpl) AddSchedule(ctx context.Context, schedule *entities.ProviderSchedule) error {
+	if err := s.checkProvider(ctx, schedule.ProviderID); err != nil {
+		return err
+	}
+	if schedule.RoomID != nil {
+		if _, err := s.roomRepo.FindByID(ctx, *schedule.RoomID); err != nil {
+			return ErrRoomNotFound
+		}
+	}
+
+	if schedule.Weekday < time.Sunday || schedule.Weekday > time.Saturday {
+		return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	start, err := parseWallClock(schedule.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseWallClock(schedule.EndTime)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return errors.New("schedule must end after it starts")
+	}
+
+	if schedule.SlotMinutes == 0 {
+		schedule.SlotMinutes = defaultSlotMinutes
+	}
+	if schedule.SlotMinutes < minSlotMinutes || schedule.SlotMinutes > maxSlotMinutes {
+		return fmt.Errorf("slot length must be between %d and %d minutes", minSlotMinutes, maxSlotMinutes)
+	}
+
+	existing, err := s.scheduleRepo.FindSchedulesByProvider(ctx, schedule.ProviderID)
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.Weekday != schedule.Weekday {
+			continue
+		}
+		otherStart, _ := parseWallClock(other.StartTime)
+		otherEnd, _ := parseWallClock(other.EndTime)
+		if start < otherEnd && end > otherStart {
+			return ErrScheduleOverlap
+		}
+	}
+
+	return s.scheduleRepo.CreateSchedule(ctx, schedule)
+}
+
+func (s *ScheduleServiceImpl) ListSchedules(ctx context.Context, providerID string) ([]*entities.ProviderSchedule, error) {
+	return s.scheduleRepo.FindSchedulesByProvider(ctx, providerID)
+}
+
+func (s *ScheduleServiceImpl) DeleteSchedule(ctx context.Context, id string) error {
+	deleted, err := s.scheduleRepo.DeleteSchedule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+func (s *ScheduleServiceImpl) AddException(ctx context.Context, exception *entities.ScheduleException) error {
+	if exception.ProviderID != "" {
+		if err := s.checkProvider(ctx, exception.ProviderID); err != nil {
+			return err
+		}
+	}
+	if !exception.EndsAt.After(exception.StartsAt) {
+		return errors.New("exception must end after it starts")
+	}
+
+	exception.StartsAt = exception.StartsAt.UTC()
+	exception.EndsAt = exception.EndsAt.UTC()
+	return s.scheduleRepo.CreateException(ctx, exception)
+}
+
+func (s *ScheduleServiceImpl) ListExceptions(ctx context.Context, providerID string, from, to time.Time) ([]*entities.ScheduleException, error) {
+	return s.scheduleRepo.FindExceptions(ctx, providerID, from, to)
+}
+
+func (s *ScheduleServiceImpl) DeleteException(ctx context.Context, id string) error {
+	deleted, err := s.scheduleRepo.DeleteException(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrScheduleExceptionNotFound
+	}
+	return nil
+}
+
+func (s *ScheduleServiceImpl) Location(ctx context.Context) *time.Location {
+	settings, err := s.tenantService.GetTenantSettings(ctx)
+	if err != nil || settings.Timezone == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func (s *ScheduleServiceImpl) AvailableSlots(ctx context.Context, providerID string, date time.Time) ([]Slot, error) {
+	if err := s.checkProvider(ctx, providerID); err != nil {
+		return nil, err
+	}
+
+	location := s.Location(ctx)
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	schedules, err := s.scheduleRepo.FindSchedulesByProvider(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.scheduleRepo.FindExceptions(ctx, providerID, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	slots := []Slot{}
+	for _, schedule := range schedules {
+		if schedule.Weekday != dayStart.Weekday() {
+			continue
+		}
+
+		windowStart, windowEnd := templateWindow(schedule, dayStart)
+		booked, err := s.appointmentRepo.FindOverlapping(ctx, providerID, schedule.RoomID, windowStart, windowEnd, "")
+		if err != nil {
+			return nil, err
+		}
+
+		slotLength := time.Duration(schedule.SlotMinutes) * time.Minute
+		for start := windowStart; !start.Add(slotLength).After(windowEnd); start = start.Add(slotLength) {
+			end := start.Add(slotLength)
+			if start.Before(now) || blockedByException(exceptions, start, end) || blockedByAppointment(booked, start, end) {
+				continue
+			}
+			slots = append(slots, Slot{Start: start, End: end, RoomID: schedule.RoomID})
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	return slots, nil
+}
+
+func (s *ScheduleServiceImpl) WorkingWindow(ctx context.Context, providerID string, start, end time.Time) (*entities.ProviderSchedule, error) {
+	location := s.Location(ctx)
+	localStart := start.In(location)
+	dayStart := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, location)
+
+	schedules, err := s.scheduleRepo.FindSchedulesByProvider(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var window *entities.ProviderSchedule
+	for _, schedule := range schedules {
+		if schedule.Weekday != dayStart.Weekday() {
+			continue
+		}
+		windowStart, windowEnd := templateWindow(schedule, dayStart)
+		if !start.Before(windowStart) && !end.After(windowEnd) {
+			window = schedule
+			break
+		}
+	}
+	if window == nil {
+		return nil, ErrOutsideWorkingHours
+	}
+
+	exceptions, err := s.scheduleRepo.FindExceptions(ctx, providerID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(exceptions) > 0 {
+		return nil, ErrProviderUnavailable
+	}
+	return window, nil
+}
+
+// checkProvider makes sure the provider is an active user of the tenant
+func (s *ScheduleServiceImpl) checkProvider(ctx context.Context, providerID string) error {
+	provider, err := s.userRepo.FindByID(ctx, providerID)
+	if err != nil || !provider.IsActive {
+		return ErrProviderNotFound
+	}
+	return nil
+}
+
+// templateWindow returns the working period of a template on the given day,
+// which must be midnight in the tenant's timezone
+func templateWindow(schedule *entities.ProviderSchedule, dayStart time.Time) (time.Time, time.Time) {
+	start, _ := parseWallClock(schedule.StartTime)
+	end, _ := parseWallClock(schedule.EndTime)
+	return atWallClock(dayStart, start), atWallClock(dayStart, end)
+}
+
+// atWallClock returns the instant the clock shows the given minutes after
+// midnight of day, so daylight saving changes keep working hours intact
+func atWallClock(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// parseWallClock converts "15:04" into minutes after midnight
+func parseWallClock(value string) (int, error) {
+	clock, err := time.Parse(wallClockLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+func blockedByException(exceptions []*entities.ScheduleException, start, end time.Time) bool {
+	for _, exception := range exceptions {
+		if exception.Overlaps(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+func blockedByAppointment(appointments []*entities.Appointment, start, end time.Time) bool {
+	for _, appointment := range appointments {
+		if appointment.StartAt.Before(end) && appointment.EndAt.After(start) {
+			return true
+		}
+	}
+	return false
+}
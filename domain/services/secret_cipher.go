@@ -0,0 +1,8 @@
+package services
+
+// SecretCipher encrypts secrets the application must be able to read back,
+// like TOTP secrets, before they are stored
+type SecretCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
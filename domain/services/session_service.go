@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/domain/tenancy"
+)
+
+// refreshTokenTTL is the absolute lifetime of a session
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+type SessionService interface {
+	CreateSession(ctx context.Context, user *entities.User, userAgent, ipAddress string) (*entities.Session, string, error)
+	RotateRefreshToken(ctx context.Context, refreshToken, userAgent, ipAddress string) (*entities.Session, *entities.User, string, error)
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	RevokeAllUserSessions(ctx context.Context, userID string) (int64, error)
+}
+
+type SessionServiceImpl struct {
+	sessionRepo repositories.SessionRepository
+	userRepo    repositories.UserRepository
+}
+
+func NewSessionService(sessionRepo repositories.SessionRepository, userRepo repositories.UserRepository) SessionService {
+	return &SessionServiceImpl{
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// CreateSession starts a new session for the user and returns it together
+// with its first refresh token
+func (s *SessionServiceImpl) CreateSession(ctx context.Context, user *entities.User, userAgent, ipAddress string) (*entities.Session, string, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.CreateSession")
+	defer span.End()
+
+	refreshToken, err := newTenantToken(user.TenantID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &entities.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: hashOpaqueToken(refreshToken),
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+		ExpiresAt:        now.Add(refreshTokenTTL),
+		LastUsedAt:       now,
+	}
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one. Presenting a
+// refresh token that was already rotated means it leaked, so the whole
+// session is revoked. The refresh token is all the caller presents; it names
+// the tenant the session is looked up in.
+func (s *SessionServiceImpl) RotateRefreshToken(ctx context.Context, refreshToken, userAgent, ipAddress string) (*entities.Session, *entities.User, string, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.RotateRefreshToken")
+	defer span.End()
+
+	tenantID, ok := tokenTenant(refreshToken)
+	if !ok {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+	ctx = tenancy.WithTenant(ctx, tenantID)
+	tokenHash := hashOpaqueToken(refreshToken)
+
+	session, err := s.sessionRepo.FindByRefreshTokenHash(ctx, tokenHash)
+	if err != nil {
+		if reused, err := s.sessionRepo.FindByPreviousTokenHash(ctx, tokenHash); err == nil {
+			s.sessionRepo.Revoke(ctx, reused.ID)
+		}
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	if !session.IsActive(time.Now()) {
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, session.UserID)
+	if err != nil || !user.IsActive {
+		s.sessionRepo.Revoke(ctx, session.ID)
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	newRefreshToken, err := newTenantToken(session.TenantID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	session.UserAgent = userAgent
+	session.IPAddress = ipAddress
+	rotated, err := s.sessionRepo.Rotate(ctx, session, hashOpaqueToken(newRefreshToken))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !rotated {
+		// Another request rotated the same token first
+		return nil, nil, "", ErrInvalidRefreshToken
+	}
+
+	return session, user, newRefreshToken, nil
+}
+
+func (s *SessionServiceImpl) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.IsSessionActive")
+	defer span.End()
+
+	session, err := s.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	return session.IsActive(time.Now()), nil
+}
+
+func (s *SessionServiceImpl) RevokeSession(ctx context.Context, sessionID string) error {
+	ctx, span := tracer.Start(ctx, "SessionService.RevokeSession")
+	defer span.End()
+
+	return s.sessionRepo.Revoke(ctx, sessionID)
+}
+
+func (s *SessionServiceImpl) RevokeAllUserSessions(ctx context.Context, userID string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.RevokeAllUserSessions")
+	defer span.End()
+
+	return s.sessionRepo.RevokeAllForUser(ctx, userID)
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+)
+
+// The TXT record that proves control of a domain, see
+// DomainVerificationRecord
+const (
+	domainVerificationPrefix = "_medical-system."
+	domainVerificationValue  = "medical-system-verification="
+)
+
+var (
+	ErrInvalidDomain            = errors.New("invalid domain name")
+	ErrDomainExists             = errors.New("domain already added")
+	ErrDomainTaken              = errors.New("domain is already verified by another tenant")
+	ErrDomainNotFound           = errors.New("domain not found")
+	ErrDomainVerificationFailed = errors.New("verification TXT record not found")
+)
+
+// TXTResolver looks up DNS TXT records; net.DefaultResolver is one. Tests
+// provide a stub.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// TenantDomainService manages the custom domains of the tenant bound to ctx
+type TenantDomainService interface {
+	// AddDomain claims a domain for the tenant, unverified until
+	// VerifyDomain finds its token in DNS
+	AddDomain(ctx context.Context, domain string) (*entities.TenantDomain, error)
+	ListDomains(ctx context.Context) ([]*entities.TenantDomain, error)
+	VerifyDomain(ctx context.Context, id string) (*entities.TenantDomain, error)
+	RemoveDomain(ctx context.Context, id string) error
+}
+
+type TenantDomainServiceImpl struct {
+	domainRepo repositories.TenantDomainRepository
+	resolver   TenantResolver
+	dns        TXTResolver
+}
+
+func NewTenantDomainService(domainRepo repositories.TenantDomainRepository, resolver TenantResolver, dns TXTResolver) TenantDomainService {
+	return &TenantDomainServiceImpl{
+		domainRepo: domainRepo,
+		resolver:   resolver,
+		dns:        dns,
+	}
+}
+
+func (s *TenantDomainServiceImpl) AddDomain(ctx context.Context, domain string) (*entities.TenantDomain, error) {
+	ctx, span := tracer.Start(ctx, "TenantDomainService.AddDomain")
+	defer span.End()
+
+	domain, err := NormalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := s.domainRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range domains {
+		if existing.Domain == domain {
+			return nil, ErrDomainExists
+		}
+	}
+	if verified, err := s.domainRepo.FindVerified(ctx, domain); err == nil && verified != nil {
+		return nil, ErrDomainTaken
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	tenantDomain := &entities.TenantDomain{
+		Domain:            domain,
+		VerificationToken: token,
+	}
+	if err := s.domainRepo.Create(ctx, tenantDomain); err != nil {
+		return nil, err
+	}
+	return tenantDomain, nil
+}
+
+func (s *TenantDomainServiceImpl) ListDomains(ctx context.Context) ([]*entities.TenantDomain, error) {
+	ctx, span := tracer.Start(ctx, "TenantDomainService.ListDomains")
+	defer span.End()
+
+	return s.domainRepo.List(ctx)
+}
+
+// VerifyDomain looks for the domain's verification record in DNS.
+// Verifying an already verified domain checks nothing and succeeds.
+func (s *TenantDomainServiceImpl) VerifyDomain(ctx context.Context, id string) (*entities.TenantDomain, error) {
+	ctx, span := tracer.Start(ctx, "TenantDomainService.VerifyDomain")
+	defer span.End()
+
+	tenantDomain, err := s.domainRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrDomainNotFound
+	}
+	if tenantDomain.IsVerified() {
+		return tenantDomain, nil
+	}
+
+	name, value := DomainVerificationRecord(tenantDomain)
+	records, err := s.dns.LookupTXT(ctx, name)
+	if err != nil {
+		return nil, ErrDomainVerificationFailed
+	}
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrDomainVerificationFailed
+	}
+
+	// The unique index on verified domains settles races between tenants
+	if err := s.domainRepo.MarkVerified(ctx, tenantDomain.ID); err != nil {
+		if verified, findErr := s.domainRepo.FindVerified(ctx, tenantDomain.Domain); findErr == nil && verified.TenantID != tenantDomain.TenantID {
+			return nil, ErrDomainTaken
+		}
+		return nil, err
+	}
+	s.resolver.InvalidateReference(tenantDomain.Domain)
+
+	return s.domainRepo.FindByID(ctx, tenantDomain.ID)
+}
+
+func (s *TenantDomainServiceImpl) RemoveDomain(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "TenantDomainService.RemoveDomain")
+	defer span.End()
+
+	tenantDomain, err := s.domainRepo.FindByID(ctx, id)
+	if err != nil {
+		return ErrDomainNotFound
+	}
+	if err := s.domainRepo.Delete(ctx, tenantDomain.ID); err != nil {
+		return err
+	}
+
+	// Stop routing the domain at once
+	s.resolver.InvalidateReference(tenantDomain.Domain)
+	return nil
+}
+
+// DomainVerificationRecord returns the name and value of the TXT record
+// the tenant publishes to verify the domain
+func DomainVerificationRecord(domain *entities.TenantDomain) (name, value string) {
+	return domainVerificationPrefix + domain.Domain, domainVerificationValue + domain.VerificationToken
+}
+
+// NormalizeDomain lowercases a host name and strips a trailing dot and a
+// port. IP addresses and names without a dot are refused.
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	domain = strings.TrimSuffix(domain, ".")
+
+	if len(domain) > 253 || net.ParseIP(domain) != nil || !strings.Contains(domain, ".") {
+		return "", ErrInvalidDomain
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", ErrInvalidDomain
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return "", ErrInvalidDomain
+			}
+		}
+	}
+	return domain, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"github.com/google/uuid"
+)
+
+// ErrTenantNotFound is returned when a reference names no tenant
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// TenantResolver turns the tenant references requests carry, an ID from a
+// token, a slug from a subdomain or header or a verified custom domain, into
+// the tenant they name. Tenants are cached for a while, so a change made on
+// another instance may take up to the TTL to be seen; changes made through
+// TenantService and TenantDomainService are seen at once.
+type TenantResolver interface {
+	Resolve(ctx context.Context, ref string) (*entities.Tenant, error)
+	// Invalidate forgets the cached tenant, under every reference
+	Invalidate(tenantID string)
+	// InvalidateReference forgets what a reference resolved to, including
+	// that it named no tenant
+	InvalidateReference(ref string)
+}
+
+// maxCachedTenantReferences bounds the references remembered to name no
+// tenant
+const maxCachedTenantReferences = 10000
+
+// cachedTenant has no tenant when the reference, a domain, named none: most
+// requests come in on hosts that are not custom domains
+type cachedTenant struct {
+	tenant  *entities.Tenant
+	expires time.Time
+}
+
+type TenantResolverImpl struct {
+	tenantRepo repositories.TenantRepository
+	domainRepo repositories.TenantDomainRepository
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	byRef   map[string]cachedTenant
+	refsFor map[string][]string
+}
+
+// NewTenantResolver caches tenants for ttl, a zero ttl disables the cache
+func NewTenantResolver(tenantRepo repositories.TenantRepository, domainRepo repositories.TenantDomainRepository, ttl time.Duration) TenantResolver {
+	return &TenantResolverImpl{
+		tenantRepo: tenantRepo,
+		domainRepo: domainRepo,
+		ttl:        ttl,
+		now:        time.Now,
+		byRef:      make(map[string]cachedTenant),
+		refsFor:    make(map[string][]string),
+	}
+}
+
+// Resolve treats a reference that parses as a UUID as an ID, one with a dot
+// as a custom domain and anything else as a slug. The tenant returned is a
+// copy the caller may change.
+func (r *TenantResolverImpl) Resolve(ctx context.Context, ref string) (*entities.Tenant, error) {
+	ctx, span := tracer.Start(ctx, "TenantResolver.Resolve")
+	defer span.End()
+
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return nil, ErrTenantNotFound
+	}
+	if entry, ok := r.cached(ref); ok {
+		if entry.tenant == nil {
+			return nil, ErrTenantNotFound
+		}
+		copied := *entry.tenant
+		return &copied, nil
+	}
+
+	var (
+		tenant *entities.Tenant
+		err    error
+	)
+	switch _, parseErr := uuid.Parse(ref); {
+	case parseErr == nil:
+		tenant, err = r.tenantRepo.FindByID(ctx, ref)
+	case strings.Contains(ref, "."):
+		var domain *entities.TenantDomain
+		if domain, err = r.domainRepo.FindVerified(ctx, ref); err != nil {
+			r.storeMiss(ref)
+			return nil, ErrTenantNotFound
+		}
+		tenant, err = r.tenantRepo.FindByID(ctx, domain.TenantID)
+	default:
+		tenant, err = r.tenantRepo.FindBySlug(ctx, ref)
+	}
+	if err != nil {
+		return nil, ErrTenantNotFound
+	}
+
+	r.store(ref, tenant)
+	copied := *tenant
+	return &copied, nil
+}
+
+func (r *TenantResolverImpl) cached(ref string) (cachedTenant, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.byRef[ref]
+	if !ok {
+		return cachedTenant{}, false
+	}
+	if r.now().After(entry.expires) {
+		delete(r.byRef, ref)
+		if entry.tenant != nil {
+			r.forget(entry.tenant.ID)
+		}
+		return cachedTenant{}, false
+	}
+	return entry, true
+}
+
+// storeMiss remembers that a domain names no tenant. Clients choose the
+// hosts they send, so misses stop being cached once the cache is full.
+func (r *TenantResolverImpl) storeMiss(ref string) {
+	if r.ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.byRef) < maxCachedTenantReferences {
+		r.byRef[ref] = cachedTenant{expires: r.now().Add(r.ttl)}
+	}
+}
+
+func (r *TenantResolverImpl) store(ref string, tenant *entities.Tenant) {
+	if r.ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A tenant found by slug is cached under its ID as well, the ID the
+	// tokens issued afterwards carry
+	r.forget(tenant.ID)
+	copied := *tenant
+	entry := cachedTenant{tenant: &copied, expires: r.now().Add(r.ttl)}
+	keys := []string{ref}
+	if id := strings.ToLower(tenant.ID); id != ref {
+		keys = append(keys, id)
+	}
+	for _, key := range keys {
+		r.byRef[key] = entry
+	}
+	r.refsFor[tenant.ID] = keys
+}
+
+func (r *TenantResolverImpl) Invalidate(tenantID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.forget(tenantID)
+}
+
+func (r *TenantResolverImpl) InvalidateReference(ref string) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.byRef[ref]; ok && entry.tenant != nil {
+		r.forget(entry.tenant.ID)
+	}
+	delete(r.byRef, ref)
+}
+
+// forget must be called with mu held
+func (r *TenantResolverImpl) forget(tenantID string) {
+	for _, ref := range r.refsFor[tenantID] {
+		delete(r.byRef, ref)
+	}
+	delete(r.refsFor, tenantID)
+}
@@ -1,123 +1,247 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"log"
 	"medical-system/domain/entities"
 	"medical-system/domain/repositories"
+	"medical-system/domain/tenancy"
+	"net/mail"
 	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrUserLimitReached is returned when a tenant has as many users as its
+// settings or plan allow
+var ErrUserLimitReached = errors.New("tenant has reached maximum user limit")
+
+// TenantService manages tenants. Methods about a tenant's own data, its
+// settings and limits, act on the tenant bound to ctx.
 type TenantService interface {
-	CreateTenant(name, email, slug string, plan entities.SubscriptionPlan) (*entities.Tenant, error)
-	GetTenantByID(id string) (*entities.Tenant, error)
-	GetTenantBySlug(slug string) (*entities.Tenant, error)
-	UpdateTenant(tenant *entities.Tenant) error
-	DeleteTenant(id string) error
-	ListActiveTenants() ([]*entities.Tenant, error)
-	ValidateTenantLimits(tenantID string) error
-	GetTenantSettings(tenantID string) (*entities.TenantSettings, error)
-	UpdateTenantSettings(settings *entities.TenantSettings) error
+	CreateTenant(ctx context.Context, name, email, slug string, plan entities.SubscriptionPlan, admin *entities.User, password string) (*entities.Tenant, error)
+	ValidateNewTenant(ctx context.Context, name, email, slug string, plan entities.SubscriptionPlan) error
+	GetTenantByID(ctx context.Context, id string) (*entities.Tenant, error)
+	GetTenantBySlug(ctx context.Context, slug string) (*entities.Tenant, error)
+	UpdateTenant(ctx context.Context, tenant *entities.Tenant) error
+	DeleteTenant(ctx context.Context, id string) error
+	ListActiveTenants(ctx context.Context) ([]*entities.Tenant, error)
+	ValidateTenantLimits(ctx context.Context) error
+	GetTenantSettings(ctx context.Context) (*entities.TenantSettings, error)
+	UpdateTenantSettings(ctx context.Context, settings *entities.TenantSettings) error
 }
 
 type TenantServiceImpl struct {
 	tenantRepo         repositories.TenantRepository
 	tenantSettingsRepo repositories.TenantSettingsRepository
+	roleRepo           repositories.RoleRepository
+	sessionRepo        repositories.SessionRepository
+	policyManager      PolicyManager
+	metrics            BusinessMetrics
+	resolver           TenantResolver
 }
 
-func NewTenantService(tenantRepo repositories.TenantRepository, tenantSettingsRepo repositories.TenantSettingsRepository) TenantService {
+func NewTenantService(tenantRepo repositories.TenantRepository, tenantSettingsRepo repositories.TenantSettingsRepository, roleRepo repositories.RoleRepository, sessionRepo repositories.SessionRepository, policyManager PolicyManager, metrics BusinessMetrics, resolver TenantResolver) TenantService {
 	return &TenantServiceImpl{
 		tenantRepo:         tenantRepo,
 		tenantSettingsRepo: tenantSettingsRepo,
+		roleRepo:           roleRepo,
+		sessionRepo:        sessionRepo,
+		policyManager:      policyManager,
+		metrics:            metrics,
+		resolver:           resolver,
 	}
 }
 
-func (s *TenantServiceImpl) CreateTenant(name, email, slug string, plan entities.SubscriptionPlan) (*entities.Tenant, error) {
-	// Validate inputs
-	if strings.TrimSpace(name) == "" {
-		return nil, errors.New("tenant name cannot be empty")
-	}
-	if strings.TrimSpace(email) == "" {
-		return nil, errors.New("tenant email cannot be empty")
-	}
-	if strings.TrimSpace(slug) == "" {
-		return nil, errors.New("tenant slug cannot be empty")
-	}
+// CreateTenant stores the tenant with its settings, its built-in roles and
+// admin, its first user, or nothing at all. The caller fills in the admin's
+// name and email; the rest is set here.
+func (s *TenantServiceImpl) CreateTenant(ctx context.Context, name, email, slug string, plan entities.SubscriptionPlan, admin *entities.User, password string) (*entities.Tenant, error) {
+	ctx, span := tracer.Start(ctx, "TenantService.CreateTenant")
+	defer span.End()
 
-	// Check if email or slug already exists
-	if _, err := s.tenantRepo.FindByEmail(email); err == nil {
-		return nil, errors.New("tenant with this email already exists")
+	if err := s.ValidateNewTenant(ctx, name, email, slug, plan); err != nil {
+		return nil, err
 	}
-	if _, err := s.tenantRepo.FindBySlug(slug); err == nil {
-		return nil, errors.New("tenant with this slug already exists")
+	if err := validatePassword(password); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create tenant
+	// The IDs are chosen up front, the policies need them before the rows
+	// are stored
 	tenant := &entities.Tenant{
+		ID:       uuid.New().String(),
 		Name:     name,
 		Email:    email,
 		Slug:     slug,
 		Plan:     plan,
 		IsActive: true,
 	}
-
-	if err := s.tenantRepo.Create(tenant); err != nil {
-		return nil, err
-	}
-
-	// Create default settings
 	settings := &entities.TenantSettings{
-		TenantID:              tenant.ID,
 		AllowUserRegistration: true,
 		MaxUsers:              plan.GetUserLimit(),
 		Timezone:              "UTC",
 		Language:              "en",
+		MaxFailedLogins:       defaultMaxFailedLogins,
+		MaxFailedLoginsPerIP:  defaultMaxFailedLoginsPerIP,
+		LockoutMinutes:        defaultLockoutMinutes,
 	}
+	admin.ID = uuid.New().String()
+	admin.TenantID = tenant.ID
+	admin.Role = entities.RoleAdmin
+	admin.IsActive = true
+	admin.PasswordHash = string(hashedPassword)
 
-	if err := s.tenantSettingsRepo.Create(settings); err != nil {
-		// If settings creation fails, we should probably delete the tenant
-		// But for simplicity, we'll just return the error
+	// The policy store cannot join the database transaction, so the
+	// policies are granted first and taken back when a later step fails.
+	// Until the tenant is stored they grant nothing anyone can use.
+	err = s.policyManager.SeedTenantPolicies(tenant.ID)
+	if err == nil {
+		err = s.policyManager.AssignRole(admin.ID, admin.Role, tenant.ID)
+	}
+	if err == nil {
+		err = s.tenantRepo.Provision(tenancy.WithTenant(ctx, tenant.ID), tenant, settings, entities.DefaultRoles(tenant.ID), admin)
+	}
+	if err != nil {
+		if removeErr := s.policyManager.RemoveTenantPolicies(tenant.ID); removeErr != nil {
+			log.Printf("⚠️  Failed to remove the policies of tenant %s after its creation failed: %v", tenant.ID, removeErr)
+		}
 		return nil, err
 	}
 
+	s.metrics.TenantRegistered(plan)
 	return tenant, nil
 }
 
-func (s *TenantServiceImpl) GetTenantByID(id string) (*entities.Tenant, error) {
-	return s.tenantRepo.FindByID(id)
+// ValidateNewTenant checks the details of a tenant about to be created,
+// including that no tenant has its email or slug yet
+func (s *TenantServiceImpl) ValidateNewTenant(ctx context.Context, name, email, slug string, plan entities.SubscriptionPlan) error {
+	ctx, span := tracer.Start(ctx, "TenantService.ValidateNewTenant")
+	defer span.End()
+
+	if strings.TrimSpace(name) == "" {
+		return errors.New("tenant name cannot be empty")
+	}
+	if strings.TrimSpace(email) == "" {
+		return errors.New("tenant email cannot be empty")
+	}
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return errors.New("tenant email is not a valid address")
+	}
+	if strings.TrimSpace(slug) == "" {
+		return errors.New("tenant slug cannot be empty")
+	}
+	if !validSlug(slug) {
+		return errors.New("tenant slug may only contain lowercase letters, digits and inner hyphens")
+	}
+	if plan.GetUserLimit() == 0 {
+		return errors.New("unknown subscription plan")
+	}
+
+	if _, err := s.tenantRepo.FindByEmail(ctx, email); err == nil {
+		return errors.New("tenant with this email already exists")
+	}
+	if _, err := s.tenantRepo.FindBySlug(ctx, slug); err == nil {
+		return errors.New("tenant with this slug already exists")
+	}
+	return nil
+}
+
+// validSlug accepts slugs that can be subdomains and that the resolver
+// cannot take for an ID or a domain
+func validSlug(slug string) bool {
+	if len(slug) > 63 || strings.HasPrefix(slug, "-") || strings.HasSuffix(slug, "-") {
+		return false
+	}
+	if _, err := uuid.Parse(slug); err == nil {
+		return false
+	}
+	for _, r := range slug {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
 }
 
-func (s *TenantServiceImpl) GetTenantBySlug(slug string) (*entities.Tenant, error) {
-	return s.tenantRepo.FindBySlug(slug)
+func (s *TenantServiceImpl) GetTenantByID(ctx context.Context, id string) (*entities.Tenant, error) {
+	ctx, span := tracer.Start(ctx, "TenantService.GetTenantByID")
+	defer span.End()
+
+	return s.tenantRepo.FindByID(ctx, id)
 }
 
-func (s *TenantServiceImpl) UpdateTenant(tenant *entities.Tenant) error {
-	return s.tenantRepo.Update(tenant)
+func (s *TenantServiceImpl) GetTenantBySlug(ctx context.Context, slug string) (*entities.Tenant, error) {
+	ctx, span := tracer.Start(ctx, "TenantService.GetTenantBySlug")
+	defer span.End()
+
+	return s.tenantRepo.FindBySlug(ctx, slug)
 }
 
-func (s *TenantServiceImpl) DeleteTenant(id string) error {
-	// First delete settings, then tenant
-	if err := s.tenantSettingsRepo.Delete(id); err != nil {
+func (s *TenantServiceImpl) UpdateTenant(ctx context.Context, tenant *entities.Tenant) error {
+	ctx, span := tracer.Start(ctx, "TenantService.UpdateTenant")
+	defer span.End()
+
+	// Status changes must reach the middleware at once
+	defer s.resolver.Invalidate(tenant.ID)
+	return s.tenantRepo.Update(ctx, tenant)
+}
+
+// DeleteTenant closes the tenant: it is deactivated and its users are signed
+// out. Its records stay, as medical and audit records must be retained, and
+// so do its policies, so setting its status again reopens it as it was.
+// ctx must be bound to the tenant.
+func (s *TenantServiceImpl) DeleteTenant(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "TenantService.DeleteTenant")
+	defer span.End()
+
+	tenant, err := s.tenantRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	defer s.resolver.Invalidate(id)
+	tenant.IsActive = false
+	if err := s.tenantRepo.Update(ctx, tenant); err != nil {
 		return err
 	}
-	return s.tenantRepo.Delete(id)
+	_, err = s.sessionRepo.RevokeAll(ctx)
+	return err
 }
 
-func (s *TenantServiceImpl) ListActiveTenants() ([]*entities.Tenant, error) {
-	return s.tenantRepo.ListActive()
+func (s *TenantServiceImpl) ListActiveTenants(ctx context.Context) ([]*entities.Tenant, error) {
+	ctx, span := tracer.Start(ctx, "TenantService.ListActiveTenants")
+	defer span.End()
+
+	return s.tenantRepo.ListActive(ctx)
 }
 
-func (s *TenantServiceImpl) ValidateTenantLimits(tenantID string) error {
-	tenant, err := s.tenantRepo.FindByID(tenantID)
+func (s *TenantServiceImpl) ValidateTenantLimits(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "TenantService.ValidateTenantLimits")
+	defer span.End()
+
+	tenantID, ok := tenancy.TenantID(ctx)
+	if !ok {
+		return tenancy.ErrNoTenantScope
+	}
+
+	tenant, err := s.tenantRepo.FindByID(ctx, tenantID)
 	if err != nil {
 		return err
 	}
 
-	settings, err := s.tenantSettingsRepo.FindByTenantID(tenantID)
+	settings, err := s.tenantSettingsRepo.Find(ctx)
 	if err != nil {
 		return err
 	}
 
-	userCount, err := s.tenantRepo.CountUsersByTenant(tenantID)
+	userCount, err := s.tenantRepo.CountUsers(ctx)
 	if err != nil {
 		return err
 	}
@@ -128,16 +252,23 @@ func (s *TenantServiceImpl) ValidateTenantLimits(tenantID string) error {
 	}
 
 	if int(userCount) >= maxUsers {
-		return errors.New("tenant has reached maximum user limit")
+		s.metrics.UserLimitRejected()
+		return ErrUserLimitReached
 	}
 
 	return nil
 }
 
-func (s *TenantServiceImpl) GetTenantSettings(tenantID string) (*entities.TenantSettings, error) {
-	return s.tenantSettingsRepo.FindByTenantID(tenantID)
+func (s *TenantServiceImpl) GetTenantSettings(ctx context.Context) (*entities.TenantSettings, error) {
+	ctx, span := tracer.Start(ctx, "TenantService.GetTenantSettings")
+	defer span.End()
+
+	return s.tenantSettingsRepo.Find(ctx)
 }
 
-func (s *TenantServiceImpl) UpdateTenantSettings(settings *entities.TenantSettings) error {
-	return s.tenantSettingsRepo.Update(settings)
+func (s *TenantServiceImpl) UpdateTenantSettings(ctx context.Context, settings *entities.TenantSettings) error {
+	ctx, span := tracer.Start(ctx, "TenantService.UpdateTenantSettings")
+	defer span.End()
+
+	return s.tenantSettingsRepo.Update(ctx, settings)
 }
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last six digits of the RFC 6238 SHA-1 vectors
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		if got := totpCode(key, tc.unix/totpPeriod); got != tc.want {
+			t.Errorf("code at %d = %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	for _, tc := range []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfc6238Secret, totpCode(key, current), current, true},
+		{"previous step", rfc6238Secret, totpCode(key, current-1), current - 1, true},
+		{"next step", rfc6238Secret, totpCode(key, current+1), current + 1, true},
+		{"lowercase secret", strings.ToLower(rfc6238Secret), totpCode(key, current), current, true},
+		{"two steps ago", rfc6238Secret, totpCode(key, current-2), 0, false},
+		{"two steps ahead", rfc6238Secret, totpCode(key, current+2), 0, false},
+		{"too short", rfc6238Secret, totpCode(key, current)[:5], 0, false},
+		{"malformed secret", "not base32!", totpCode(key, current), 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			step, ok := validateTOTP(tc.secret, tc.code, now)
+			if ok != tc.wantOK || step != tc.wantStep {
+				t.Errorf("validateTOTP = (%d, %v), want (%d, %v)", step, ok, tc.wantStep, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewRecoveryCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("recovery code %q is not formatted as XXXXX-XXXXX", code)
+		}
+		for _, c := range strings.ReplaceAll(code, "-", "") {
+			if !strings.ContainsRune(recoveryCodeAlphabet, c) {
+				t.Fatalf("recovery code %q has %q outside the alphabet", code, c)
+			}
+		}
+	}
+}
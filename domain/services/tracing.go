@@ -0,0 +1,7 @@
+package services
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts the spans of the domain services. It follows the global
+// tracer provider, which records nothing until tracing is configured.
+var tracer = otel.Tracer("medical-system/domain/services")
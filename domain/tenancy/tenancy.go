@@ -0,0 +1,88 @@
+// Package tenancy carries the tenant a request acts for through
+// context.Context. Repositories confine every statement to the tenant bound
+// to their context; reaching across tenants takes an explicit, audited
+// system scope.
+package tenancy
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNoTenantScope    = errors.New("no tenant bound to the context")
+	ErrCrossTenantScope = errors.New("context is already bound to another tenant")
+)
+
+// ScopeRecorder is told every time code enters the system scope, so the
+// audit log shows who reached across tenants and why. tenantID is the tenant
+// the scope is narrowed to, empty when it spans every tenant.
+type ScopeRecorder interface {
+	RecordSystemScope(ctx context.Context, tenantID, reason string)
+}
+
+type scopeKey struct{}
+
+type scope struct {
+	tenantID string
+	system   bool
+	reason   string
+	conflict bool
+}
+
+// WithTenant binds ctx to a tenant. A context already bound to a different
+// tenant cannot be rebound; the result refuses every statement instead.
+// Narrowing a system scope to one tenant is allowed.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	current, _ := ctx.Value(scopeKey{}).(scope)
+	if current.conflict || (current.tenantID != "" && current.tenantID != tenantID) {
+		return context.WithValue(ctx, scopeKey{}, scope{conflict: true})
+	}
+	return context.WithValue(ctx, scopeKey{}, scope{tenantID: tenantID})
+}
+
+// WithSystemScope lifts the tenant restriction for statements run with the
+// returned context. The entry is recorded with the given reason before the
+// context is handed out.
+func WithSystemScope(ctx context.Context, recorder ScopeRecorder, reason string) context.Context {
+	recorder.RecordSystemScope(ctx, "", reason)
+	return context.WithValue(ctx, scopeKey{}, scope{system: true, reason: reason})
+}
+
+// WithTenantAccess is the system scope narrowed to one tenant, for acting on
+// its data from outside of it. The entry is recorded with the tenant.
+func WithTenantAccess(ctx context.Context, recorder ScopeRecorder, tenantID, reason string) context.Context {
+	recorder.RecordSystemScope(ctx, tenantID, reason)
+	return WithTenant(context.WithValue(ctx, scopeKey{}, scope{system: true, reason: reason}), tenantID)
+}
+
+// TenantID returns the tenant bound to ctx
+func TenantID(ctx context.Context) (string, bool) {
+	current, _ := ctx.Value(scopeKey{}).(scope)
+	if current.conflict || current.tenantID == "" {
+		return "", false
+	}
+	return current.tenantID, true
+}
+
+// SystemScope reports whether ctx is in the system scope and why it was entered
+func SystemScope(ctx context.Context) (string, bool) {
+	current, _ := ctx.Value(scopeKey{}).(scope)
+	return current.reason, current.system
+}
+
+// Resolve returns the tenant statements run with ctx are confined to. system
+// is true, with an empty tenant, inside the system scope.
+func Resolve(ctx context.Context) (tenantID string, system bool, err error) {
+	current, _ := ctx.Value(scopeKey{}).(scope)
+	switch {
+	case current.conflict:
+		return "", false, ErrCrossTenantScope
+	case current.system:
+		return "", true, nil
+	case current.tenantID == "":
+		return "", false, ErrNoTenantScope
+	default:
+		return current.tenantID, false, nil
+	}
+}
@@ -1,15 +1,74 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"log"
 
+	"medical-system/domain/entities"
+
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"gorm.io/gorm"
 )
 
+// rbacModel is an RBAC model with domains, where every tenant is a domain:
+// a user holds roles inside a tenant and roles are granted (resource, action)
+// pairs inside that same tenant.
+const rbacModel = `
+[request_definition]
+r = sub, dom, obj, act
+
+[policy_definition]
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && r.obj == p.obj && r.act == p.act
+`
+
+// defaultRolePermissions lists the (resource, action) pairs granted to the
+// built-in roles of every new tenant
+var defaultRolePermissions = map[string][][2]string{
+	entities.RoleAdmin: {
+		{"user", "read"},
+		{"user", "write"},
+		{"profile", "read"},
+		{"profile", "write"},
+		{"patient", "read"},
+		{"patient", "write"},
+		{"patient", "delete"},
+		{"appointment", "read"},
+		{"appointment", "write"},
+		{"schedule", "read"},
+		{"schedule", "write"},
+		{"encounter", "read"},
+		{"encounter", "write"},
+		{"encounter", "sign"},
+	},
+	entities.RoleUser: {
+		{"profile", "read"},
+		{"profile", "write"},
+		{"patient", "read"},
+		{"patient", "write"},
+		{"appointment", "read"},
+		{"appointment", "write"},
+		{"schedule", "read"},
+		{"encounter", "read"},
+		{"encounter", "write"},
+		{"encounter", "sign"},
+	},
+}
+
 type CasbinEnforcer struct {
-	enforcer *casbin.Enforcer
+	enforcer *casbin.SyncedEnforcer
+	db       *gorm.DB
 }
 
 func NewCasbinEnforcer(db *gorm.DB) (*CasbinEnforcer, error) {
@@ -18,13 +77,29 @@ func NewCasbinEnforcer(db *gorm.DB) (*CasbinEnforcer, error) {
 		return nil, err
 	}
 
-	// Simple enforcer without model for now - just initialize
-	enforcer := &casbin.Enforcer{}
-	// We'll skip the full initialization for now to avoid model issues
-	_ = adapter
+	m, err := model.NewModelFromString(rbacModel)
+	if err != nil {
+		return nil, err
+	}
+
+	enforcer, err := casbin.NewSyncedEnforcer(m, adapter)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Println("Casbin RBAC initialized (simplified)")
-	return &CasbinEnforcer{enforcer: enforcer}, nil
+	if err := enforcer.LoadPolicy(); err != nil {
+		return nil, err
+	}
+
+	log.Println("Casbin RBAC with domains initialized")
+	return &CasbinEnforcer{enforcer: enforcer, db: db}, nil
+}
+
+// Ping checks that the policy store can still be read, for readiness probes.
+// The policies themselves are cached, so a failure only affects changes.
+func (c *CasbinEnforcer) Ping(ctx context.Context) error {
+	var found []int
+	return c.db.WithContext(ctx).Raw("SELECT 1 FROM casbin_rule LIMIT 1").Scan(&found).Error
 }
 
 func (c *CasbinEnforcer) CheckPermission(userID, tenantID, resource, action string) (bool, error) {
@@ -35,21 +110,119 @@ func (c *CasbinEnforcer) AddRoleForUser(userID, role, tenantID string) (bool, er
 	return c.enforcer.AddRoleForUserInDomain(userID, role, tenantID)
 }
 
-func loadInitialPolicies(enforcer *casbin.Enforcer) error {
-	// Add some default policies
-	policies := [][]string{
-		{"admin", "tenant1", "user", "read"},
-		{"admin", "tenant1", "user", "write"},
-		{"user", "tenant1", "profile", "read"},
-		{"user", "tenant1", "profile", "write"},
+// SeedTenantPolicies grants the default permissions of the built-in roles
+// inside the given tenant. Policies that already exist are left untouched.
+func (c *CasbinEnforcer) SeedTenantPolicies(tenantID string) error {
+	var policies [][]string
+	for role, permissions := range defaultRolePermissions {
+		for _, permission := range permissions {
+			policies = append(policies, []string{role, tenantID, permission[0], permission[1]})
+		}
+	}
+
+	_, err := c.enforcer.AddPoliciesEx(policies)
+	return err
+}
+
+func (c *CasbinEnforcer) AssignRole(userID, role, tenantID string) error {
+	_, err := c.enforcer.AddRoleForUserInDomain(userID, role, tenantID)
+	return err
+}
+
+func (c *CasbinEnforcer) RemoveTenantPolicies(tenantID string) error {
+	if _, err := c.enforcer.RemoveFilteredPolicy(1, tenantID); err != nil {
+		return err
+	}
+	_, err := c.enforcer.RemoveFilteredGroupingPolicy(2, tenantID)
+	return err
+}
+
+// GetRolePermissions returns the (resource, action) pairs granted to a role in a tenant
+func (c *CasbinEnforcer) GetRolePermissions(role, tenantID string) ([][]string, error) {
+	policies, err := c.enforcer.GetFilteredPolicy(0, role, tenantID)
+	if err != nil {
+		return nil, err
 	}
 
+	permissions := make([][]string, 0, len(policies))
 	for _, policy := range policies {
-		_, err := enforcer.AddPolicy(policy)
-		if err != nil {
-			return err
-		}
+		permissions = append(permissions, []string{policy[2], policy[3]})
 	}
+	return permissions, nil
+}
+
+func (c *CasbinEnforcer) GrantPermission(role, tenantID, resource, action string) (bool, error) {
+	return c.enforcer.AddPolicy(role, tenantID, resource, action)
+}
+
+func (c *CasbinEnforcer) RevokePermission(role, tenantID, resource, action string) (bool, error) {
+	return c.enforcer.RemovePolicy(role, tenantID, resource, action)
+}
 
+// DeleteRole removes every permission of a role and every assignment of the
+// role to users, within the given tenant only. When the assignments cannot be
+// removed, the permissions are put back.
+func (c *CasbinEnforcer) DeleteRole(role, tenantID string) error {
+	policies, err := c.enforcer.GetFilteredPolicy(0, role, tenantID)
+	if err != nil {
+		return err
+	}
+	if _, err := c.enforcer.RemoveFilteredPolicy(0, role, tenantID); err != nil {
+		return err
+	}
+	if _, err := c.enforcer.RemoveFilteredGroupingPolicy(1, role, tenantID); err != nil {
+		if len(policies) > 0 {
+			if _, restoreErr := c.enforcer.AddPoliciesEx(policies); restoreErr != nil {
+				return errors.Join(err, restoreErr)
+			}
+		}
+		return err
+	}
 	return nil
 }
+
+func (c *CasbinEnforcer) RemoveRoleForUser(userID, role, tenantID string) (bool, error) {
+	return c.enforcer.DeleteRoleForUserInDomain(userID, role, tenantID)
+}
+
+func (c *CasbinEnforcer) GetUserRoles(userID, tenantID string) []string {
+	return c.enforcer.GetRolesForUserInDomain(userID, tenantID)
+}
+
+// IsSuperAdmin reports whether the user holds the platform super admin role
+func (c *CasbinEnforcer) IsSuperAdmin(userID string) bool {
+	held, err := c.enforcer.HasRoleForUser(userID, entities.RoleSuperAdmin, entities.PlatformDomain)
+	return err == nil && held
+}
+
+// GrantSuperAdmin makes the user a platform super admin. Only the command
+// line grants it, see the super-admin command.
+func (c *CasbinEnforcer) GrantSuperAdmin(userID string) error {
+	_, err := c.enforcer.AddRoleForUserInDomain(userID, entities.RoleSuperAdmin, entities.PlatformDomain)
+	return err
+}
+
+func (c *CasbinEnforcer) RevokeSuperAdmin(userID string) error {
+	_, err := c.enforcer.DeleteRoleForUserInDomain(userID, entities.RoleSuperAdmin, entities.PlatformDomain)
+	return err
+}
+
+// GetUserPermissions returns the effective permissions of a user in a tenant,
+// formatted as "resource:action"
+func (c *CasbinEnforcer) GetUserPermissions(userID, tenantID string) ([]string, error) {
+	policies, err := c.enforcer.GetImplicitPermissionsForUser(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(policies))
+	permissions := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		permission := policy[2] + ":" + policy[3]
+		if !seen[permission] {
+			seen[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"testing"
+
+	"medical-system/config"
+	"medical-system/domain/entities"
+	"medical-system/infrastructure/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openEnforcerTestDatabase opens an in-memory SQLite database for the policy store
+func openEnforcerTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.NewConnection(&config.Config{Database: config.DatabaseConfig{Driver: "sqlite", Path: ":memory:"}})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	t.Cleanup(func() { database.Close(db) })
+	return db
+}
+
+func TestCasbinEnforcerDomains(t *testing.T) {
+	db := openEnforcerTestDatabase(t)
+	enforcer, err := NewCasbinEnforcer(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tenantID := range []string{"tenant-a", "tenant-b"} {
+		if err := enforcer.SeedTenantPolicies(tenantID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, assignment := range [][3]string{
+		{"doctor-a", entities.RoleUser, "tenant-a"},
+		{"admin-b", entities.RoleAdmin, "tenant-b"},
+		{"doctor-b", entities.RoleUser, "tenant-b"},
+	} {
+		if err := enforcer.AssignRole(assignment[0], assignment[1], assignment[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := enforcer.GrantPermission(entities.RoleUser, "tenant-a", "report", "read"); err != nil {
+		t.Fatal(err)
+	}
+	if err := enforcer.GrantSuperAdmin("doctor-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(enforcer *CasbinEnforcer, userID, tenantID, resource, action string, want bool) {
+		t.Helper()
+
+		allowed, err := enforcer.CheckPermission(userID, tenantID, resource, action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != want {
+			t.Errorf("%s in %s %s:%s = %v, want %v", userID, tenantID, resource, action, allowed, want)
+		}
+	}
+
+	tests := []struct {
+		name                               string
+		userID, tenantID, resource, action string
+		want                               bool
+	}{
+		{"granted by the role", "doctor-a", "tenant-a", "patient", "read", true},
+		{"not granted to the role", "doctor-a", "tenant-a", "patient", "delete", false},
+		{"the role in another tenant", "doctor-a", "tenant-b", "patient", "read", false},
+		{"granted to the role in this tenant only", "doctor-a", "tenant-a", "report", "read", true},
+		{"the same role without the grant", "doctor-b", "tenant-b", "report", "read", false},
+		{"admin in its tenant", "admin-b", "tenant-b", "patient", "delete", true},
+		{"admin in another tenant", "admin-b", "tenant-a", "user", "read", false},
+		{"super admin grants nothing inside tenants", "doctor-a", entities.PlatformDomain, "patient", "read", false},
+		{"unknown user", "nobody", "tenant-a", "profile", "read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check(enforcer, tt.userID, tt.tenantID, tt.resource, tt.action, tt.want)
+		})
+	}
+
+	if !enforcer.IsSuperAdmin("doctor-a") || enforcer.IsSuperAdmin("admin-b") {
+		t.Error("super admin must be held by doctor-a only")
+	}
+	permissions, err := enforcer.GetUserPermissions("doctor-a", "tenant-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(permissions, "report:read") || contains(permissions, "patient:delete") {
+		t.Errorf("permissions = %v", permissions)
+	}
+
+	// Policies are stored, a new enforcer sees them
+	reloaded, err := NewCasbinEnforcer(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(reloaded, "doctor-a", "tenant-a", "report", "read", true)
+
+	// Removing a tenant leaves the other tenants alone
+	if err := enforcer.RemoveTenantPolicies("tenant-a"); err != nil {
+		t.Fatal(err)
+	}
+	check(enforcer, "doctor-a", "tenant-a", "patient", "read", false)
+	check(enforcer, "doctor-b", "tenant-b", "patient", "read", true)
+	if roles := enforcer.GetUserRoles("doctor-a", "tenant-a"); len(roles) != 0 {
+		t.Errorf("roles left in the removed tenant = %v", roles)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"medical-system/domain/entities"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signing keys
+const minRSAKeyBits = 2048
+
+type signingKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// KeySetGenerator signs tokens with an asymmetric key (RS256 for RSA keys,
+// EdDSA for Ed25519 keys) and stamps the key ID in the kid header. Keys are
+// loaded from a directory of PEM files named <kid>.pem. Tokens are signed
+// with the active key and validated against every key that is not retired,
+// so a new key can be activated while tokens signed with the previous one
+// are still in circulation.
+type KeySetGenerator struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// NewKeySetGenerator loads every <kid>.pem file in keysDir. When activeKID is
+// empty the lexicographically greatest non-retired kid is used for signing,
+// so naming keys by date rotates them naturally. Retired kids are neither
+// used for validation nor published.
+func NewKeySetGenerator(keysDir, activeKID string, retiredKIDs []string) (*KeySetGenerator, error) {
+	files, err := filepath.Glob(filepath.Join(keysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	retired := make(map[string]bool, len(retiredKIDs))
+	for _, kid := range retiredKIDs {
+		retired[kid] = true
+	}
+
+	g := &KeySetGenerator{keys: make(map[string]*signingKey)}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		if retired[kid] {
+			continue
+		}
+
+		key, err := loadSigningKey(file, kid)
+		if err != nil {
+			return nil, err
+		}
+		g.keys[kid] = key
+	}
+
+	if len(g.keys) == 0 {
+		return nil, fmt.Errorf("no usable signing keys found in %s", keysDir)
+	}
+
+	if activeKID == "" {
+		kids := make([]string, 0, len(g.keys))
+		for kid := range g.keys {
+			kids = append(kids, kid)
+		}
+		sort.Strings(kids)
+		activeKID = kids[len(kids)-1]
+	}
+
+	active, ok := g.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found or retired", activeKID)
+	}
+	g.active = active
+
+	return g, nil
+}
+
+func (g *KeySetGenerator) GenerateToken(user *entities.User, sessionID string) (string, error) {
+	token := jwt.NewWithClaims(g.active.method, accessTokenClaims(user, sessionID))
+	token.Header["kid"] = g.active.kid
+	return token.SignedString(g.active.privateKey)
+}
+
+func (g *KeySetGenerator) GenerateMFAChallengeToken(user *entities.User) (string, error) {
+	token := jwt.NewWithClaims(g.active.method, mfaChallengeClaims(user))
+	token.Header["kid"] = g.active.kid
+	return token.SignedString(g.active.privateKey)
+}
+
+func (g *KeySetGenerator) GenerateTenantRegistrationToken(tenant *entities.Tenant) (string, error) {
+	token := jwt.NewWithClaims(g.active.method, tenantRegistrationClaims(tenant))
+	token.Header["kid"] = g.active.kid
+	return token.SignedString(g.active.privateKey)
+}
+
+func (g *KeySetGenerator) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("token has no kid header")
+		}
+
+		key, ok := g.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		// Reject tokens whose alg does not match the key, e.g. HS256 with a public key
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return key.publicKey, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*jwt.MapClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, jwt.ErrSignatureInvalid
+}
+
+// JWKS returns the public half of every non-retired key
+func (g *KeySetGenerator) JWKS() JSONWebKeySet {
+	kids := make([]string, 0, len(g.keys))
+	for kid := range g.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(kids))}
+	for _, kid := range kids {
+		key := g.keys[kid]
+		set.Keys = append(set.Keys, newJSONWebKey(kid, key.method.Alg(), key.publicKey))
+	}
+	return set
+}
+
+func loadSigningKey(path, kid string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%s: RSA keys must be at least %d bits", path, minRSAKeyBits)
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, privateKey: key, publicKey: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, privateKey: key, publicKey: key.Public()}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+}
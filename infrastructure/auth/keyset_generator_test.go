@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"medical-system/domain/entities"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var testUser = &entities.User{ID: "user-1", TenantID: "tenant-1", Email: "ada@clinic.test", Role: entities.RoleUser}
+
+// writeRSAKey stores a fresh RSA key as <kid>.pem in dir, PKCS #1 encoded
+func writeRSAKey(t *testing.T, dir, kid string, bits int) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	return key
+}
+
+// writeEd25519Key stores a fresh Ed25519 key as <kid>.pem in dir, PKCS #8
+// encoded
+func writeEd25519Key(t *testing.T, dir, kid string) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "PRIVATE KEY", der)
+	return key
+}
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tokenHeader decodes the header of a signed token without validating it
+func tokenHeader(t *testing.T, tokenString string) map[string]interface{} {
+	t.Helper()
+
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token.Header
+}
+
+func TestKeySetGeneratorSigning(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		write func(t *testing.T, dir string)
+		alg   string
+	}{
+		{"RSA", func(t *testing.T, dir string) { writeRSAKey(t, dir, "2026-01", 2048) }, "RS256"},
+		{"Ed25519", func(t *testing.T, dir string) { writeEd25519Key(t, dir, "2026-01") }, "EdDSA"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.write(t, dir)
+			g, err := NewKeySetGenerator(dir, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := g.GenerateToken(testUser, "session-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header := tokenHeader(t, token); header["alg"] != tc.alg || header["kid"] != "2026-01" {
+				t.Errorf("header = %v, want alg %s and kid 2026-01", header, tc.alg)
+			}
+
+			claims, err := g.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if (*claims)["user_id"] != testUser.ID || (*claims)["jti"] != "session-1" {
+				t.Errorf("claims = %v", *claims)
+			}
+
+			// Any change to the token breaks the signature
+			parts := strings.Split(token, ".")
+			other := *testUser
+			other.ID = "user-2"
+			unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, accessTokenClaims(&other, "session-1")).SigningString()
+			if err != nil {
+				t.Fatal(err)
+			}
+			forged := parts[0] + "." + strings.Split(unsigned, ".")[1] + "." + parts[2]
+			if _, err := g.ValidateToken(forged); err == nil {
+				t.Error("a token with altered claims validated")
+			}
+		})
+	}
+}
+
+func TestKeySetGeneratorKeySelection(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01", 2048)
+	writeEd25519Key(t, dir, "2026-06")
+
+	// The greatest kid signs unless another one is made active
+	latest, err := NewKeySetGenerator(dir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := NewKeySetGenerator(dir, "2026-01", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latestToken, err := latest.GenerateToken(testUser, "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousToken, err := previous.GenerateToken(testUser, "session-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := tokenHeader(t, latestToken); header["kid"] != "2026-06" || header["alg"] != "EdDSA" {
+		t.Errorf("default key header = %v", header)
+	}
+	if header := tokenHeader(t, previousToken); header["kid"] != "2026-01" || header["alg"] != "RS256" {
+		t.Errorf("active key header = %v", header)
+	}
+
+	// Tokens are validated against the key their kid names
+	for _, g := range []*KeySetGenerator{latest, previous} {
+		for _, token := range []string{latestToken, previousToken} {
+			if _, err := g.ValidateToken(token); err != nil {
+				t.Errorf("validate %s: %v", tokenHeader(t, token)["kid"], err)
+			}
+		}
+	}
+
+	if _, err := NewKeySetGenerator(dir, "2027-01", nil); err == nil {
+		t.Error("a missing active key was accepted")
+	}
+	if _, err := NewKeySetGenerator(t.TempDir(), "", nil); err == nil {
+		t.Error("an empty keys directory was accepted")
+	}
+
+	weak := t.TempDir()
+	writeRSAKey(t, weak, "2026-01", 1024)
+	if _, err := NewKeySetGenerator(weak, "", nil); err == nil {
+		t.Error("a 1024-bit RSA key was accepted")
+	}
+}
+
+func TestKeySetGeneratorRetiredKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01", 2048)
+	writeEd25519Key(t, dir, "2026-06")
+
+	before, err := NewKeySetGenerator(dir, "2026-01", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := before.GenerateToken(testUser, "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := NewKeySetGenerator(dir, "", []string{"2026-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := after.ValidateToken(token); err == nil {
+		t.Error("a token signed with a retired key validated")
+	}
+	for _, key := range after.JWKS().Keys {
+		if key.KeyID == "2026-01" {
+			t.Error("a retired key is published")
+		}
+	}
+
+	if _, err := NewKeySetGenerator(dir, "2026-01", []string{"2026-01"}); err == nil {
+		t.Error("a retired key was made active")
+	}
+}
+
+func TestKeySetGeneratorRejectsAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := writeRSAKey(t, dir, "2026-01", 2048)
+	edKey := writeEd25519Key(t, dir, "2026-06")
+	g, err := NewKeySetGenerator(dir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := accessTokenClaims(testUser, "session-1")
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		t.Helper()
+
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	publicPEM, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		// The published key used as an HMAC secret
+		"HS256 with the public key": sign(jwt.SigningMethodHS256, "2026-01", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicPEM})),
+		"EdDSA under an RSA kid":    sign(jwt.SigningMethodEdDSA, "2026-01", edKey),
+		"RS256 under an EdDSA kid":  sign(jwt.SigningMethodRS256, "2026-06", rsaKey),
+		"RS512 with the RSA key":    sign(jwt.SigningMethodRS512, "2026-01", rsaKey),
+		"unknown kid":               sign(jwt.SigningMethodRS256, "2025-01", rsaKey),
+		"no kid":                    sign(jwt.SigningMethodRS256, "", rsaKey),
+	} {
+		if _, err := g.ValidateToken(token); err == nil {
+			t.Errorf("%s: token validated", name)
+		}
+	}
+
+	// The same keys with matching algorithms are accepted
+	for _, token := range []string{
+		sign(jwt.SigningMethodRS256, "2026-01", rsaKey),
+		sign(jwt.SigningMethodEdDSA, "2026-06", edKey),
+	} {
+		if _, err := g.ValidateToken(token); err != nil {
+			t.Errorf("validate: %v", err)
+		}
+	}
+}
+
+func TestKeySetGeneratorJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := writeRSAKey(t, dir, "2026-01", 2048)
+	edKey := writeEd25519Key(t, dir, "2026-06")
+	g, err := NewKeySetGenerator(dir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := g.JWKS().Keys
+	if len(keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2", len(keys))
+	}
+	decode := func(value string) []byte {
+		t.Helper()
+
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	rsaJWK := keys[0]
+	if rsaJWK.KeyID != "2026-01" || rsaJWK.KeyType != "RSA" || rsaJWK.Algorithm != "RS256" || rsaJWK.Use != "sig" {
+		t.Errorf("RSA key = %+v", rsaJWK)
+	}
+	published := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(decode(rsaJWK.Modulus)),
+		E: int(new(big.Int).SetBytes(decode(rsaJWK.Exponent)).Int64()),
+	}
+	if !published.Equal(&rsaKey.PublicKey) {
+		t.Error("the published RSA key is not the signing key")
+	}
+
+	edJWK := keys[1]
+	if edJWK.KeyID != "2026-06" || edJWK.KeyType != "OKP" || edJWK.Curve != "Ed25519" || edJWK.Algorithm != "EdDSA" || edJWK.Use != "sig" {
+		t.Errorf("Ed25519 key = %+v", edJWK)
+	}
+	if !ed25519.PublicKey(decode(edJWK.X)).Equal(edKey.Public()) {
+		t.Error("the published Ed25519 key is not the signing key")
+	}
+
+	// A token is verifiable with nothing but the published key
+	token, err := g.GenerateToken(testUser, "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return ed25519.PublicKey(decode(edJWK.X)), nil
+	})
+	if err != nil || !parsed.Valid {
+		t.Errorf("verify with the published key: %v", err)
+	}
+	if strings.Contains(rsaJWK.Modulus+rsaJWK.Exponent+edJWK.X, "=") {
+		t.Error("JWK values must be unpadded base64url")
+	}
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"medical-system/domain/services"
+)
+
+// sealedPrefix marks values encrypted by AESCipher. Values without it were
+// stored before encryption was introduced and are returned as they are.
+const sealedPrefix = "v1:"
+
+var errMalformedCiphertext = errors.New("malformed ciphertext")
+
+// AESCipher encrypts with AES-256-GCM under a random nonce per value
+type AESCipher struct {
+	aead cipher.AEAD
+}
+
+// NewAESCipher takes the 32-byte key, base64 encoded
+func NewAESCipher(encodedKey string) (services.SecretCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key is %d bytes, want 32", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESCipher{aead: aead}, nil
+}
+
+func (c *AESCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *AESCipher) Decrypt(ciphertext string) (string, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, sealedPrefix)
+	if !ok {
+		return ciphertext, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errMalformedCiphertext
+	}
+
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
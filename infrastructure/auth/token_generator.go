@@ -5,10 +5,32 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// AccessTokenTTL is the lifetime of an access token. Tokens are kept short
+// because they are refreshed through a revocable server-side session.
+const AccessTokenTTL = 15 * time.Minute
+
+// MFAChallengeTTL is how long a user has to present their second factor after
+// the password step of the login
+const MFAChallengeTTL = 5 * time.Minute
+
+// TenantRegistrationTTL is how long the link that verifies the email of a
+// clinic registering itself stays valid
+const TenantRegistrationTTL = 24 * time.Hour
+
+// Values of the typ claim. Only access tokens are accepted by JWTMiddleware.
+const (
+	TokenTypeAccess             = "access"
+	TokenTypeMFAChallenge       = "mfa_challenge"
+	TokenTypeTenantRegistration = "tenant_registration"
 )
 
 type TokenGenerator interface {
-	GenerateToken(user *entities.User) (string, error)
+	GenerateToken(user *entities.User, sessionID string) (string, error)
+	GenerateMFAChallengeToken(user *entities.User) (string, error)
+	GenerateTenantRegistrationToken(tenant *entities.Tenant) (string, error)
 	ValidateToken(tokenString string) (*jwt.MapClaims, error)
 }
 
@@ -20,22 +42,24 @@ func NewJWTGenerator(secretKey string) TokenGenerator {
 	return &JWTGenerator{secretKey: secretKey}
 }
 
-func (j *JWTGenerator) GenerateToken(user *entities.User) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id":   user.ID,
-		"email":     user.Email,
-		"role":      user.Role,
-		"tenant_id": user.TenantID,
-		"exp":       time.Now().Add(time.Hour * 24).Unix(),
-		"iat":       time.Now().Unix(),
-	}
+func (j *JWTGenerator) GenerateToken(user *entities.User, sessionID string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims(user, sessionID))
+	return token.SignedString([]byte(j.secretKey))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+func (j *JWTGenerator) GenerateMFAChallengeToken(user *entities.User) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mfaChallengeClaims(user))
+	return token.SignedString([]byte(j.secretKey))
+}
+
+func (j *JWTGenerator) GenerateTenantRegistrationToken(tenant *entities.Tenant) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tenantRegistrationClaims(tenant))
 	return token.SignedString([]byte(j.secretKey))
 }
 
 func (j *JWTGenerator) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	token, err := parser.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(j.secretKey), nil
 	})
 
@@ -49,3 +73,46 @@ func (j *JWTGenerator) ValidateToken(tokenString string) (*jwt.MapClaims, error)
 
 	return nil, jwt.ErrSignatureInvalid
 }
+
+// accessTokenClaims builds the claims shared by every TokenGenerator implementation
+func accessTokenClaims(user *entities.User, sessionID string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"typ":       TokenTypeAccess,
+		"jti":       sessionID,
+		"user_id":   user.ID,
+		"email":     user.Email,
+		"role":      user.Role,
+		"tenant_id": user.TenantID,
+		"exp":       time.Now().Add(AccessTokenTTL).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+}
+
+// mfaChallengeClaims builds the claims of the short-lived token that proves
+// the password step of a login succeeded. The jti lets the challenge be
+// redeemed once.
+func mfaChallengeClaims(user *entities.User) jwt.MapClaims {
+	return jwt.MapClaims{
+		"typ":       TokenTypeMFAChallenge,
+		"jti":       uuid.New().String(),
+		"user_id":   user.ID,
+		"tenant_id": user.TenantID,
+		"exp":       time.Now().Add(MFAChallengeTTL).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+}
+
+// tenantRegistrationClaims carry the details of a clinic that registered
+// itself, so the link mailed to its address is all it takes to create the
+// tenant once the address is verified
+func tenantRegistrationClaims(tenant *entities.Tenant) jwt.MapClaims {
+	return jwt.MapClaims{
+		"typ":   TokenTypeTenantRegistration,
+		"name":  tenant.Name,
+		"email": tenant.Email,
+		"slug":  tenant.Slug,
+		"plan":  string(tenant.Plan),
+		"exp":   time.Now().Add(TenantRegistrationTTL).Unix(),
+		"iat":   time.Now().Unix(),
+	}
+}
@@ -1,46 +1,117 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
-	"os"
 
-	"medical-system/domain/entities"
+	"medical-system/config"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func NewConnection() (*gorm.DB, error) {
-	// PostgreSQL connection using environment variables
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "postgres")
-	dbname := getEnv("DB_NAME", "medical_system")
-	sslmode := getEnv("DB_SSLMODE", "disable")
+// NewConnection opens the configured database: PostgreSQL, or SQLite for
+// local development and tests
+func NewConnection(cfg *config.Config) (*gorm.DB, error) {
+	dbConfig := cfg.Database
+	driver := dbConfig.Driver
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
+	var dialector gorm.Dialector
+	var dsn string
+	switch driver {
+	case "postgres":
+		dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.Name, dbConfig.SSLMode)
+		dialector = postgres.Open(dsn)
+	case "sqlite":
+		dialector = sqlite.Open(sqliteDSN(dbConfig.Path))
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q, use postgres or sqlite", driver)
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Auto-migrate
-	err = db.AutoMigrate(&entities.User{}, &entities.Tenant{}, &entities.TenantSettings{})
+	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if dbConfig.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	}
+	if dbConfig.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	}
+	if dbConfig.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
 	}
 
-	log.Println("PostgreSQL database connection established")
+	// The schema is managed by the migrations, see Migrator. PostgreSQL
+	// enforces tenant isolation as well, on the tenant of the transaction
+	// each request runs in.
+	if driver == "sqlite" {
+		if err := useSQLiteConnections(db); err != nil {
+			return nil, fmt.Errorf("failed to configure sqlite: %w", err)
+		}
+	}
+	if err := warnIfBypassingRowLevelSecurity(db); err != nil {
+		return nil, fmt.Errorf("failed to check row-level security: %w", err)
+	}
+	var detached *sql.DB
+	if driver == "postgres" {
+		if detached, err = openDetachedPool(dsn, dbConfig); err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+	}
+	if err := UseTenantTransactions(db, detached); err != nil {
+		return nil, fmt.Errorf("failed to enable tenant transactions: %w", err)
+	}
+
+	if err := RegisterTenantScope(db); err != nil {
+		return nil, fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+
+	log.Printf("Database connection established (%s)", driver)
 	return db, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Close closes the pools NewConnection opened
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	err = sqlDB.Close()
+	if pool, ok := db.ConnPool.(*tenantConnPool); ok && pool.detached != sqlDB {
+		if closeErr := pool.detached.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// openDetachedPool opens the pool of the Detached statements, apart from the
+// one requests hold their transactions on
+func openDetachedPool(dsn string, dbConfig config.DatabaseConfig) (*sql.DB, error) {
+	// The gorm driver registers pgx with database/sql
+	pool, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dbConfig.DetachedMaxOpenConns > 0 {
+		pool.SetMaxOpenConns(dbConfig.DetachedMaxOpenConns)
+		pool.SetMaxIdleConns(dbConfig.DetachedMaxOpenConns)
+	}
+	if dbConfig.ConnMaxLifetime > 0 {
+		pool.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
+	}
+	if err := pool.Ping(); err != nil {
+		pool.Close()
+		return nil, err
 	}
-	return defaultValue
+	return pool, nil
 }
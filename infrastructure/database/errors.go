@@ -0,0 +1,34 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Codes of unique index violations
+const (
+	postgresUniqueViolation = "23505"
+	sqliteConstraintUnique  = 2067
+	sqliteConstraintPrimary = 1555
+)
+
+// IsUniqueViolation tells whether err comes from a write that a unique index
+// or primary key refused
+func IsUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == postgresUniqueViolation
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqliteConstraintUnique || sqliteErr.Code() == sqliteConstraintPrimary
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/tenancy"
+)
+
+func TestPatientUniqueIndexes(t *testing.T) {
+	db := openTenantScopeTestDatabase(t)
+	ctxA := tenancy.WithTenant(context.Background(), "tenant-a")
+	ctxB := tenancy.WithTenant(context.Background(), "tenant-b")
+
+	create := func(ctx context.Context, mrn, nationalID string) error {
+		return db.WithContext(ctx).Create(&entities.Patient{
+			MRN:         mrn,
+			NationalID:  nationalID,
+			FirstName:   "Ada",
+			LastName:    "Lovelace",
+			DateOfBirth: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+		}).Error
+	}
+	if err := create(ctxA, "MRN-1", "NID-1"); err != nil {
+		t.Fatalf("create patient: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		ctx        context.Context
+		mrn        string
+		nationalID string
+		unique     bool
+	}{
+		{"same MRN", ctxA, "MRN-1", "NID-2", false},
+		{"same national ID", ctxA, "MRN-2", "NID-1", false},
+		{"same MRN and national ID in another tenant", ctxB, "MRN-1", "NID-1", true},
+		{"no national ID", ctxA, "MRN-3", "", true},
+		{"no national ID again", ctxA, "MRN-4", "", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := create(tc.ctx, tc.mrn, tc.nationalID)
+			if tc.unique && err != nil {
+				t.Fatalf("create patient: %v", err)
+			}
+			if !tc.unique && !IsUniqueViolation(err) {
+				t.Fatalf("err = %v, want a unique violation", err)
+			}
+		})
+	}
+
+	// Other constraints are not unique violations
+	err := db.WithContext(ctxA).Exec("INSERT INTO patients (id, tenant_id, first_name, last_name, date_of_birth) VALUES ('p-1', 'tenant-a', 'Ada', 'Lovelace', '1990-01-02')").Error
+	if err == nil || IsUniqueViolation(err) {
+		t.Errorf("patient without MRN: err = %v, want a NOT NULL violation", err)
+	}
+	if IsUniqueViolation(context.Canceled) || IsUniqueViolation(nil) {
+		t.Error("other errors are reported as unique violations")
+	}
+}
@@ -0,0 +1,358 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrSchemaBehind      = errors.New("database schema is behind the application")
+	ErrSchemaAhead       = errors.New("database schema has migrations this build does not know")
+	ErrChecksumMismatch  = errors.New("applied migration differs from its file")
+	ErrUnknownMigration  = errors.New("unknown migration version")
+	ErrInvalidMigrations = errors.New("invalid migration files")
+)
+
+// migrationLockID serialises migrators of concurrent deployments on PostgreSQL
+const migrationLockID = 72616469
+
+// migrationFileName matches e.g. 0001_initial_schema.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change with the SQL to apply and revert it
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describes a migration known to the build, the database,
+// or both
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified is set when the applied checksum differs from the file
+	Modified bool
+	// Missing is set for applied migrations the build has no file for
+	Missing bool
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies the SQL migrations embedded in the binary and records
+// them in schema_migrations. Every migration runs in its own transaction
+// together with its bookkeeping, so a failed migration leaves no trace.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations of the dialect of db from
+// migrations/<dialect>. Every dialect has the same versions.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, path.Join("migrations", db.Dialector.Name()))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: none for the %s dialect", ErrInvalidMigrations, db.Dialector.Name())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations reads the up and down files of dir, ordered by version
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidMigrations, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: bad version in %s", ErrInvalidMigrations, entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d is used by %s and %s", ErrInvalidMigrations, version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: %04d_%s needs both an up and a down file", ErrInvalidMigrations, migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the version of the newest migration of the build
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status lists every migration of the build and every applied one
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.Checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// CheckCurrent returns ErrSchemaBehind when migrations are pending and an
+// error as well when applied migrations were modified or are unknown
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkIntegrity(statuses); err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s) %v", ErrSchemaBehind, len(pending), pending)
+	}
+	return nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the given number of most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var applied []int64
+	for _, status := range statuses {
+		if status.Applied {
+			applied = append(applied, status.Version)
+		}
+	}
+	if steps <= 0 || len(applied) == 0 {
+		return nil
+	}
+	if steps >= len(applied) {
+		return m.To(ctx, 0)
+	}
+	return m.To(ctx, applied[len(applied)-steps-1])
+}
+
+// To applies or reverts migrations until version is the newest applied one.
+// Version 0 reverts everything.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkIntegrity(statuses); err != nil {
+		return err
+	}
+
+	// Revert newest first, then apply oldest first
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if status := statuses[i]; status.Applied && status.Version > version {
+			if err := m.revert(ctx, m.find(status.Version)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, status := range statuses {
+		if !status.Applied && status.Version <= version {
+			if err := m.apply(ctx, m.find(status.Version)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration *Migration) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		done, err := m.lock(tx, migration.Version)
+		if err != nil || done {
+			return err
+		}
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&appliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, migration *Migration) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		done, err := m.lock(tx, migration.Version)
+		if err != nil || !done {
+			return err
+		}
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&appliedMigration{}, "version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("revert migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// lock keeps concurrent migrators out until tx ends and reports whether
+// version is applied, which may have changed while waiting
+func (m *Migrator) lock(tx *gorm.DB, version int64) (bool, error) {
+	if tx.Dialector.Name() == "postgres" {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+			return false, err
+		}
+	}
+
+	var count int64
+	if err := tx.Model(&appliedMigration{}).Where("version = ?", version).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]appliedMigration, error) {
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	checksum text NOT NULL,
+	applied_at timestamp NOT NULL
+)`).Error
+	if err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var rows []appliedMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// checkIntegrity refuses to work on a schema whose history differs from the
+// build: migrations must never be edited once applied
+func checkIntegrity(statuses []MigrationStatus) error {
+	for _, status := range statuses {
+		switch {
+		case status.Missing:
+			return fmt.Errorf("%w: %04d_%s", ErrSchemaAhead, status.Version, status.Name)
+		case status.Modified:
+			return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, status.Version, status.Name)
+		}
+	}
+	return nil
+}
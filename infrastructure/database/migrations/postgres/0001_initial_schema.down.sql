@@ -0,0 +1,21 @@
+-- Drops every table of the initial schema, and all data with it
+
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS encounter_amendments;
+DROP TABLE IF EXISTS diagnoses;
+DROP TABLE IF EXISTS encounters;
+DROP TABLE IF EXISTS appointments;
+DROP TABLE IF EXISTS schedule_exceptions;
+DROP TABLE IF EXISTS provider_schedules;
+DROP TABLE IF EXISTS rooms;
+DROP TABLE IF EXISTS insurance_policies;
+DROP TABLE IF EXISTS emergency_contacts;
+DROP TABLE IF EXISTS patients;
+DROP TABLE IF EXISTS login_throttles;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS tenant_settings;
+DROP TABLE IF EXISTS tenants;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,330 @@
+-- The schema as created by gorm's AutoMigrate before versioned migrations.
+-- IF NOT EXISTS lets databases created that way adopt this migration as
+-- their baseline.
+
+CREATE TABLE IF NOT EXISTS users (
+    id text,
+    email text,
+    tenant_id text,
+    password_hash text,
+    role text DEFAULT 'user',
+    first_name text,
+    last_name text,
+    is_active boolean DEFAULT true,
+    mfa_enabled boolean DEFAULT false,
+    mfa_secret text,
+    mfa_last_used_step bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_email_tenant ON users (email, tenant_id);
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id text,
+    name text NOT NULL,
+    slug text NOT NULL,
+    email text NOT NULL,
+    "plan" text DEFAULT 'basic',
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_email ON tenants (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_slug ON tenants (slug);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_name ON tenants (name);
+
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    id text,
+    tenant_id text NOT NULL,
+    allow_user_registration boolean DEFAULT true,
+    max_users bigint,
+    timezone text DEFAULT 'UTC',
+    language text DEFAULT 'en',
+    require_mfa boolean DEFAULT false,
+    max_failed_logins bigint DEFAULT 5,
+    max_failed_logins_per_ip bigint DEFAULT 20,
+    lockout_minutes bigint DEFAULT 15,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_tenant_settings_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_settings_tenant_id ON tenant_settings (tenant_id);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id text,
+    tenant_id text NOT NULL,
+    name text NOT NULL,
+    description text,
+    is_system boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_role_name_tenant ON roles (tenant_id, name);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id text,
+    tenant_id text NOT NULL,
+    user_id text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamptz,
+    used_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_tenant_id ON password_reset_tokens (tenant_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id text,
+    tenant_id text NOT NULL,
+    user_id text NOT NULL,
+    refresh_token_hash text NOT NULL,
+    previous_token_hash text,
+    user_agent text,
+    ip_address text,
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token_hash ON sessions (previous_token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_tenant_id ON sessions (tenant_id);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id text,
+    tenant_id text NOT NULL,
+    user_id text NOT NULL,
+    code_hash text NOT NULL,
+    used_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_tenant_id ON mfa_recovery_codes (tenant_id);
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    id text,
+    tenant_id text NOT NULL,
+    scope text NOT NULL,
+    identifier text NOT NULL,
+    failed_count bigint,
+    last_failed_at timestamptz,
+    locked_until timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_login_throttle_key ON login_throttles (tenant_id, scope, identifier);
+
+CREATE TABLE IF NOT EXISTS patients (
+    id text,
+    tenant_id text NOT NULL,
+    mrn text NOT NULL,
+    national_id text,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    date_of_birth date NOT NULL,
+    sex text DEFAULT 'unknown',
+    email text,
+    phone text,
+    address_line1 text,
+    address_line2 text,
+    city text,
+    state text,
+    postal_code text,
+    country text,
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_patients_national_id ON patients (national_id);
+CREATE INDEX IF NOT EXISTS idx_patient_name_tenant ON patients (tenant_id, first_name, last_name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_mrn_tenant ON patients (tenant_id, mrn);
+
+CREATE TABLE IF NOT EXISTS emergency_contacts (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    name text NOT NULL,
+    relationship text,
+    phone text,
+    email text,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_patients_emergency_contacts FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_emergency_contacts_patient_id ON emergency_contacts (patient_id);
+CREATE INDEX IF NOT EXISTS idx_emergency_contacts_tenant_id ON emergency_contacts (tenant_id);
+
+CREATE TABLE IF NOT EXISTS insurance_policies (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    provider text NOT NULL,
+    policy_number text NOT NULL,
+    group_number text,
+    holder_name text,
+    valid_from date,
+    valid_until date,
+    is_primary boolean DEFAULT false,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_patients_insurance_policies FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_insurance_policies_patient_id ON insurance_policies (patient_id);
+CREATE INDEX IF NOT EXISTS idx_insurance_policies_tenant_id ON insurance_policies (tenant_id);
+
+CREATE TABLE IF NOT EXISTS rooms (
+    id text,
+    tenant_id text NOT NULL,
+    name text NOT NULL,
+    description text,
+    is_active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_room_name_tenant ON rooms (tenant_id, name);
+
+CREATE TABLE IF NOT EXISTS provider_schedules (
+    id text,
+    tenant_id text NOT NULL,
+    provider_id text NOT NULL,
+    weekday bigint NOT NULL,
+    start_time varchar(5) NOT NULL,
+    end_time varchar(5) NOT NULL,
+    slot_minutes bigint DEFAULT 30,
+    room_id text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_schedule_provider ON provider_schedules (tenant_id, provider_id);
+
+CREATE TABLE IF NOT EXISTS schedule_exceptions (
+    id text,
+    tenant_id text NOT NULL,
+    provider_id text,
+    starts_at timestamptz NOT NULL,
+    ends_at timestamptz NOT NULL,
+    reason text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_schedule_exceptions_provider_id ON schedule_exceptions (provider_id);
+CREATE INDEX IF NOT EXISTS idx_schedule_exception_period ON schedule_exceptions (tenant_id, starts_at);
+
+CREATE TABLE IF NOT EXISTS appointments (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    provider_id text NOT NULL,
+    room_id text,
+    start_at timestamptz NOT NULL,
+    end_at timestamptz NOT NULL,
+    status text NOT NULL DEFAULT 'booked',
+    reason text,
+    notes text,
+    cancellation_reason text,
+    booked_by text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_appointments_patient_id ON appointments (patient_id);
+CREATE INDEX IF NOT EXISTS idx_appointment_room_time ON appointments (tenant_id, room_id, start_at);
+CREATE INDEX IF NOT EXISTS idx_appointment_provider_time ON appointments (tenant_id, provider_id, start_at);
+
+CREATE TABLE IF NOT EXISTS encounters (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    provider_id text NOT NULL,
+    appointment_id text,
+    status text NOT NULL DEFAULT 'draft',
+    subjective text,
+    objective text,
+    assessment text,
+    "plan" text,
+    vital_temperature_c decimal,
+    vital_heart_rate bigint,
+    vital_respiratory_rate bigint,
+    vital_systolic_bp bigint,
+    vital_diastolic_bp bigint,
+    vital_oxygen_saturation bigint,
+    vital_weight_kg decimal,
+    vital_height_cm decimal,
+    signed_at timestamptz,
+    signed_by text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_encounters_appointment_id ON encounters (appointment_id);
+CREATE INDEX IF NOT EXISTS idx_encounters_provider_id ON encounters (provider_id);
+CREATE INDEX IF NOT EXISTS idx_encounter_patient ON encounters (tenant_id, patient_id);
+
+CREATE TABLE IF NOT EXISTS diagnoses (
+    id text,
+    tenant_id text NOT NULL,
+    encounter_id text NOT NULL,
+    code text NOT NULL,
+    description text,
+    is_primary boolean DEFAULT false,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_encounters_diagnoses FOREIGN KEY (encounter_id) REFERENCES encounters(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_diagnoses_encounter_id ON diagnoses (encounter_id);
+CREATE INDEX IF NOT EXISTS idx_diagnoses_tenant_id ON diagnoses (tenant_id);
+
+CREATE TABLE IF NOT EXISTS encounter_amendments (
+    id text,
+    tenant_id text NOT NULL,
+    encounter_id text NOT NULL,
+    section text NOT NULL,
+    original_text text,
+    amended_text text,
+    reason text NOT NULL,
+    amended_by text NOT NULL,
+    amended_at timestamptz NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_encounters_amendments FOREIGN KEY (encounter_id) REFERENCES encounters(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_encounter_amendments_encounter_id ON encounter_amendments (encounter_id);
+CREATE INDEX IF NOT EXISTS idx_encounter_amendments_tenant_id ON encounter_amendments (tenant_id);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id text,
+    tenant_id text NOT NULL,
+    sequence bigint NOT NULL,
+    occurred_at timestamptz NOT NULL,
+    actor_id text,
+    actor_role text,
+    action text NOT NULL,
+    resource_type text,
+    resource_id text,
+    outcome text NOT NULL,
+    status_code bigint,
+    method text,
+    path text,
+    ip_address text,
+    request_id text,
+    details text,
+    prev_hash text,
+    hash text NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_request_id ON audit_events (request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_resource ON audit_events (resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_chain ON audit_events (tenant_id, sequence);
@@ -0,0 +1,71 @@
+DROP POLICY IF EXISTS tenant_isolation ON audit_events;
+ALTER TABLE audit_events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE audit_events DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON encounter_amendments;
+ALTER TABLE encounter_amendments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE encounter_amendments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON diagnoses;
+ALTER TABLE diagnoses NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE diagnoses DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON encounters;
+ALTER TABLE encounters NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE encounters DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON appointments;
+ALTER TABLE appointments NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE appointments DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON schedule_exceptions;
+ALTER TABLE schedule_exceptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE schedule_exceptions DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON provider_schedules;
+ALTER TABLE provider_schedules NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE provider_schedules DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON rooms;
+ALTER TABLE rooms NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE rooms DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON insurance_policies;
+ALTER TABLE insurance_policies NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE insurance_policies DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON emergency_contacts;
+ALTER TABLE emergency_contacts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE emergency_contacts DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON patients;
+ALTER TABLE patients NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE patients DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON login_throttles;
+ALTER TABLE login_throttles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE login_throttles DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON mfa_recovery_codes;
+ALTER TABLE mfa_recovery_codes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE mfa_recovery_codes DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON sessions;
+ALTER TABLE sessions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE sessions DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON password_reset_tokens;
+ALTER TABLE password_reset_tokens NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE password_reset_tokens DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON roles;
+ALTER TABLE roles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE roles DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON tenant_settings;
+ALTER TABLE tenant_settings NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE tenant_settings DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,134 @@
+-- Row-level security on every tenant-owned table: rows are only visible to
+-- and writable by transactions whose app.current_tenant is the row's tenant,
+-- or that run in system scope. The settings are applied per transaction by
+-- the tenant connection pool in infrastructure/database.
+--
+-- FORCE applies the policies to the table owner as well. Roles with
+-- SUPERUSER or BYPASSRLS still skip them, so the application must connect
+-- as an ordinary role.
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON users;
+CREATE POLICY tenant_isolation ON users
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE tenant_settings ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenant_settings FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON tenant_settings;
+CREATE POLICY tenant_isolation ON tenant_settings
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE roles FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON roles;
+CREATE POLICY tenant_isolation ON roles
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE password_reset_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE password_reset_tokens FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON password_reset_tokens;
+CREATE POLICY tenant_isolation ON password_reset_tokens
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sessions FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON sessions;
+CREATE POLICY tenant_isolation ON sessions
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE mfa_recovery_codes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE mfa_recovery_codes FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON mfa_recovery_codes;
+CREATE POLICY tenant_isolation ON mfa_recovery_codes
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE login_throttles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE login_throttles FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON login_throttles;
+CREATE POLICY tenant_isolation ON login_throttles
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE patients ENABLE ROW LEVEL SECURITY;
+ALTER TABLE patients FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON patients;
+CREATE POLICY tenant_isolation ON patients
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE emergency_contacts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE emergency_contacts FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON emergency_contacts;
+CREATE POLICY tenant_isolation ON emergency_contacts
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE insurance_policies ENABLE ROW LEVEL SECURITY;
+ALTER TABLE insurance_policies FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON insurance_policies;
+CREATE POLICY tenant_isolation ON insurance_policies
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE rooms ENABLE ROW LEVEL SECURITY;
+ALTER TABLE rooms FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON rooms;
+CREATE POLICY tenant_isolation ON rooms
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE provider_schedules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE provider_schedules FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON provider_schedules;
+CREATE POLICY tenant_isolation ON provider_schedules
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE schedule_exceptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE schedule_exceptions FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON schedule_exceptions;
+CREATE POLICY tenant_isolation ON schedule_exceptions
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE appointments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE appointments FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON appointments;
+CREATE POLICY tenant_isolation ON appointments
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE encounters ENABLE ROW LEVEL SECURITY;
+ALTER TABLE encounters FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON encounters;
+CREATE POLICY tenant_isolation ON encounters
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE diagnoses ENABLE ROW LEVEL SECURITY;
+ALTER TABLE diagnoses FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON diagnoses;
+CREATE POLICY tenant_isolation ON diagnoses
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE encounter_amendments ENABLE ROW LEVEL SECURITY;
+ALTER TABLE encounter_amendments FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON encounter_amendments;
+CREATE POLICY tenant_isolation ON encounter_amendments
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+
+ALTER TABLE audit_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_events FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_events;
+CREATE POLICY tenant_isolation ON audit_events
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
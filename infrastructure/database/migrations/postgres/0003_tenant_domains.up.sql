@@ -0,0 +1,28 @@
+-- Custom domains tenants serve from, e.g. portal.clinic.com. A domain routes
+-- requests once verified; until then several tenants may claim it, so only
+-- verified domains are unique.
+
+CREATE TABLE tenant_domains (
+    id text,
+    tenant_id text NOT NULL,
+    domain text NOT NULL,
+    verification_token text NOT NULL,
+    verified_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_tenant_domains_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_tenant_domains_tenant_domain ON tenant_domains (tenant_id, domain);
+CREATE UNIQUE INDEX idx_tenant_domains_verified_domain ON tenant_domains (domain) WHERE verified_at IS NOT NULL;
+
+-- Rows belong to their tenant like every tenant-owned table, except that
+-- verified domains can be read by anyone: they are looked up to find the
+-- tenant of a request, before it is known.
+ALTER TABLE tenant_domains ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenant_domains FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON tenant_domains
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
+CREATE POLICY verified_domain_routing ON tenant_domains FOR SELECT
+    USING (verified_at IS NOT NULL);
@@ -0,0 +1,18 @@
+-- Single-use tokens that were redeemed, such as MFA challenges, by their jti
+-- claim. Rows are only needed until the token expires.
+
+CREATE TABLE used_tokens (
+    id text,
+    tenant_id text NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_used_tokens_tenant_id ON used_tokens (tenant_id);
+CREATE INDEX idx_used_tokens_expires_at ON used_tokens (expires_at);
+
+ALTER TABLE used_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE used_tokens FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON used_tokens
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
@@ -0,0 +1,2 @@
+-- The policies go with the table
+DROP TABLE IF EXISTS audit_chain_heads;
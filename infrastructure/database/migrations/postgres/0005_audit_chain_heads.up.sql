@@ -0,0 +1,22 @@
+-- The head of every audit chain, kept apart from the events so that events
+-- removed from the end of a chain are detected. Existing chains start from
+-- their latest event.
+
+CREATE TABLE audit_chain_heads (
+    tenant_id text,
+    sequence bigint NOT NULL,
+    hash text NOT NULL,
+    updated_at timestamptz,
+    PRIMARY KEY (tenant_id)
+);
+
+SELECT set_config('app.system_scope', 'on', true);
+INSERT INTO audit_chain_heads (tenant_id, sequence, hash, updated_at)
+SELECT tenant_id, sequence, hash, now() FROM audit_events e
+WHERE sequence = (SELECT MAX(sequence) FROM audit_events WHERE tenant_id = e.tenant_id);
+
+ALTER TABLE audit_chain_heads ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_chain_heads FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON audit_chain_heads
+    USING (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on')
+    WITH CHECK (tenant_id = current_setting('app.current_tenant', true) OR current_setting('app.system_scope', true) = 'on');
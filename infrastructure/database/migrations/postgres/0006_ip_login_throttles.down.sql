@@ -0,0 +1,2 @@
+-- The IP counters start over per tenant
+DROP TABLE IF EXISTS ip_login_throttles;
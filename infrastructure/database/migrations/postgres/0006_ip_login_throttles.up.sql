@@ -0,0 +1,18 @@
+-- Failed logins from a client IP, counted across every tenant so that an
+-- address cannot spread its guesses over many clinics. The table holds no
+-- tenant data and has no row level security. The IP counters kept per
+-- tenant so far only hold recent failures and are dropped.
+
+CREATE TABLE ip_login_throttles (
+    ip_address text,
+    failed_count bigint,
+    last_failed_at timestamptz,
+    locked_until timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (ip_address)
+);
+CREATE INDEX idx_ip_login_throttles_last_failed_at ON ip_login_throttles (last_failed_at);
+
+SELECT set_config('app.system_scope', 'on', true);
+DELETE FROM login_throttles WHERE scope = 'ip';
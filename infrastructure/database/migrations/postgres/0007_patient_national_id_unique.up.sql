@@ -0,0 +1,8 @@
+-- National IDs are unique within a tenant when present, as MRNs are. The
+-- check in the application cannot see patients registered concurrently, the
+-- index settles them. The migration fails while duplicates exist; merge them
+-- first. The index replaces the one on national_id alone, every query is
+-- scoped to a tenant.
+
+DROP INDEX IF EXISTS idx_patients_national_id;
+CREATE UNIQUE INDEX idx_patient_national_id_tenant ON patients (tenant_id, national_id) WHERE national_id <> '';
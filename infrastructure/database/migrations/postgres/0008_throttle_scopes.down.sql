@@ -0,0 +1,15 @@
+SELECT set_config('app.system_scope', 'on', true);
+DELETE FROM login_throttles WHERE scope <> 'login';
+UPDATE login_throttles SET scope = 'email';
+
+DROP TABLE ip_login_throttles;
+CREATE TABLE ip_login_throttles (
+    ip_address text,
+    failed_count bigint,
+    last_failed_at timestamptz,
+    locked_until timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (ip_address)
+);
+CREATE INDEX idx_ip_login_throttles_last_failed_at ON ip_login_throttles (last_failed_at);
@@ -0,0 +1,19 @@
+-- Throttle counters are kept per scope, what they count: failed logins or
+-- password reset requests. The IP counters only hold recent attempts and
+-- start over.
+
+SELECT set_config('app.system_scope', 'on', true);
+UPDATE login_throttles SET scope = 'login' WHERE scope = 'email';
+
+DROP TABLE ip_login_throttles;
+CREATE TABLE ip_login_throttles (
+    scope text,
+    ip_address text,
+    failed_count bigint,
+    last_failed_at timestamptz,
+    locked_until timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (scope, ip_address)
+);
+CREATE INDEX idx_ip_login_throttles_last_failed_at ON ip_login_throttles (last_failed_at);
@@ -0,0 +1,329 @@
+-- The schema of the PostgreSQL baseline for SQLite, which stores booleans
+-- as numeric and times as text.
+
+CREATE TABLE users (
+    id text,
+    email text,
+    tenant_id text,
+    password_hash text,
+    role text DEFAULT 'user',
+    first_name text,
+    last_name text,
+    is_active numeric DEFAULT true,
+    mfa_enabled numeric DEFAULT false,
+    mfa_secret text,
+    mfa_last_used_step integer,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_user_email_tenant ON users (email, tenant_id);
+
+CREATE TABLE tenants (
+    id text,
+    name text NOT NULL,
+    slug text NOT NULL,
+    email text NOT NULL,
+    plan text DEFAULT 'basic',
+    is_active numeric DEFAULT true,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_tenants_email ON tenants (email);
+CREATE UNIQUE INDEX idx_tenants_slug ON tenants (slug);
+CREATE UNIQUE INDEX idx_tenants_name ON tenants (name);
+
+CREATE TABLE tenant_settings (
+    id text,
+    tenant_id text NOT NULL,
+    allow_user_registration numeric DEFAULT true,
+    max_users integer,
+    timezone text DEFAULT 'UTC',
+    language text DEFAULT 'en',
+    require_mfa numeric DEFAULT false,
+    max_failed_logins integer DEFAULT 5,
+    max_failed_logins_per_ip integer DEFAULT 20,
+    lockout_minutes integer DEFAULT 15,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_tenant_settings_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_tenant_settings_tenant_id ON tenant_settings (tenant_id);
+
+CREATE TABLE roles (
+    id text,
+    tenant_id text NOT NULL,
+    name text NOT NULL,
+    description text,
+    is_system numeric DEFAULT false,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_role_name_tenant ON roles (tenant_id, name);
+
+CREATE TABLE password_reset_tokens (
+    id text,
+    tenant_id text NOT NULL,
+    user_id text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at datetime,
+    used_at datetime,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE INDEX idx_password_reset_tokens_tenant_id ON password_reset_tokens (tenant_id);
+
+CREATE TABLE sessions (
+    id text,
+    tenant_id text NOT NULL,
+    user_id text NOT NULL,
+    refresh_token_hash text NOT NULL,
+    previous_token_hash text,
+    user_agent text,
+    ip_address text,
+    expires_at datetime,
+    last_used_at datetime,
+    revoked_at datetime,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_sessions_previous_token_hash ON sessions (previous_token_hash);
+CREATE UNIQUE INDEX idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX idx_sessions_tenant_id ON sessions (tenant_id);
+
+CREATE TABLE mfa_recovery_codes (
+    id text,
+    tenant_id text NOT NULL,
+    user_id text NOT NULL,
+    code_hash text NOT NULL,
+    used_at datetime,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
+CREATE INDEX idx_mfa_recovery_codes_tenant_id ON mfa_recovery_codes (tenant_id);
+
+CREATE TABLE login_throttles (
+    id text,
+    tenant_id text NOT NULL,
+    scope text NOT NULL,
+    identifier text NOT NULL,
+    failed_count integer,
+    last_failed_at datetime,
+    locked_until datetime,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_login_throttle_key ON login_throttles (tenant_id, scope, identifier);
+
+CREATE TABLE patients (
+    id text,
+    tenant_id text NOT NULL,
+    mrn text NOT NULL,
+    national_id text,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    date_of_birth date NOT NULL,
+    sex text DEFAULT 'unknown',
+    email text,
+    phone text,
+    address_line1 text,
+    address_line2 text,
+    city text,
+    state text,
+    postal_code text,
+    country text,
+    is_active numeric DEFAULT true,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_patients_national_id ON patients (national_id);
+CREATE INDEX idx_patient_name_tenant ON patients (tenant_id, first_name, last_name);
+CREATE UNIQUE INDEX idx_patient_mrn_tenant ON patients (tenant_id, mrn);
+
+CREATE TABLE emergency_contacts (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    name text NOT NULL,
+    relationship text,
+    phone text,
+    email text,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_patients_emergency_contacts FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_emergency_contacts_patient_id ON emergency_contacts (patient_id);
+CREATE INDEX idx_emergency_contacts_tenant_id ON emergency_contacts (tenant_id);
+
+CREATE TABLE insurance_policies (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    provider text NOT NULL,
+    policy_number text NOT NULL,
+    group_number text,
+    holder_name text,
+    valid_from date,
+    valid_until date,
+    is_primary numeric DEFAULT false,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_patients_insurance_policies FOREIGN KEY (patient_id) REFERENCES patients(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_insurance_policies_patient_id ON insurance_policies (patient_id);
+CREATE INDEX idx_insurance_policies_tenant_id ON insurance_policies (tenant_id);
+
+CREATE TABLE rooms (
+    id text,
+    tenant_id text NOT NULL,
+    name text NOT NULL,
+    description text,
+    is_active numeric DEFAULT true,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_room_name_tenant ON rooms (tenant_id, name);
+
+CREATE TABLE provider_schedules (
+    id text,
+    tenant_id text NOT NULL,
+    provider_id text NOT NULL,
+    weekday integer NOT NULL,
+    start_time text NOT NULL,
+    end_time text NOT NULL,
+    slot_minutes integer DEFAULT 30,
+    room_id text,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_schedule_provider ON provider_schedules (tenant_id, provider_id);
+
+CREATE TABLE schedule_exceptions (
+    id text,
+    tenant_id text NOT NULL,
+    provider_id text,
+    starts_at datetime NOT NULL,
+    ends_at datetime NOT NULL,
+    reason text,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_schedule_exceptions_provider_id ON schedule_exceptions (provider_id);
+CREATE INDEX idx_schedule_exception_period ON schedule_exceptions (tenant_id, starts_at);
+
+CREATE TABLE appointments (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    provider_id text NOT NULL,
+    room_id text,
+    start_at datetime NOT NULL,
+    end_at datetime NOT NULL,
+    status text NOT NULL DEFAULT 'booked',
+    reason text,
+    notes text,
+    cancellation_reason text,
+    booked_by text,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_appointments_patient_id ON appointments (patient_id);
+CREATE INDEX idx_appointment_room_time ON appointments (tenant_id, room_id, start_at);
+CREATE INDEX idx_appointment_provider_time ON appointments (tenant_id, provider_id, start_at);
+
+CREATE TABLE encounters (
+    id text,
+    tenant_id text NOT NULL,
+    patient_id text NOT NULL,
+    provider_id text NOT NULL,
+    appointment_id text,
+    status text NOT NULL DEFAULT 'draft',
+    subjective text,
+    objective text,
+    assessment text,
+    plan text,
+    vital_temperature_c real,
+    vital_heart_rate integer,
+    vital_respiratory_rate integer,
+    vital_systolic_bp integer,
+    vital_diastolic_bp integer,
+    vital_oxygen_saturation integer,
+    vital_weight_kg real,
+    vital_height_cm real,
+    signed_at datetime,
+    signed_by text,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_encounters_appointment_id ON encounters (appointment_id);
+CREATE INDEX idx_encounters_provider_id ON encounters (provider_id);
+CREATE INDEX idx_encounter_patient ON encounters (tenant_id, patient_id);
+
+CREATE TABLE diagnoses (
+    id text,
+    tenant_id text NOT NULL,
+    encounter_id text NOT NULL,
+    code text NOT NULL,
+    description text,
+    is_primary numeric DEFAULT false,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_encounters_diagnoses FOREIGN KEY (encounter_id) REFERENCES encounters(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_diagnoses_encounter_id ON diagnoses (encounter_id);
+CREATE INDEX idx_diagnoses_tenant_id ON diagnoses (tenant_id);
+
+CREATE TABLE encounter_amendments (
+    id text,
+    tenant_id text NOT NULL,
+    encounter_id text NOT NULL,
+    section text NOT NULL,
+    original_text text,
+    amended_text text,
+    reason text NOT NULL,
+    amended_by text NOT NULL,
+    amended_at datetime NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_encounters_amendments FOREIGN KEY (encounter_id) REFERENCES encounters(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_encounter_amendments_encounter_id ON encounter_amendments (encounter_id);
+CREATE INDEX idx_encounter_amendments_tenant_id ON encounter_amendments (tenant_id);
+
+CREATE TABLE audit_events (
+    id text,
+    tenant_id text NOT NULL,
+    sequence integer NOT NULL,
+    occurred_at datetime NOT NULL,
+    actor_id text,
+    actor_role text,
+    action text NOT NULL,
+    resource_type text,
+    resource_id text,
+    outcome text NOT NULL,
+    status_code integer,
+    method text,
+    path text,
+    ip_address text,
+    request_id text,
+    details text,
+    prev_hash text,
+    hash text NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_audit_events_request_id ON audit_events (request_id);
+CREATE INDEX idx_audit_resource ON audit_events (resource_type, resource_id);
+CREATE INDEX idx_audit_events_action ON audit_events (action);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE UNIQUE INDEX idx_audit_chain ON audit_events (tenant_id, sequence);
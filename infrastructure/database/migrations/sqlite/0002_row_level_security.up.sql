@@ -0,0 +1,3 @@
+-- SQLite has no row-level security: the tenant scope callbacks are the only
+-- tenant boundary. The migration keeps the versions of both dialects aligned.
+SELECT 1;
@@ -0,0 +1,16 @@
+-- Custom domains tenants serve from, see the PostgreSQL migration. SQLite
+-- has no row-level security, the tenant scope callbacks confine the rows.
+
+CREATE TABLE tenant_domains (
+    id text,
+    tenant_id text NOT NULL,
+    domain text NOT NULL,
+    verification_token text NOT NULL,
+    verified_at datetime,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_tenant_domains_tenant FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_tenant_domains_tenant_domain ON tenant_domains (tenant_id, domain);
+CREATE UNIQUE INDEX idx_tenant_domains_verified_domain ON tenant_domains (domain) WHERE verified_at IS NOT NULL;
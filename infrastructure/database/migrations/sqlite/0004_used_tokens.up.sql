@@ -0,0 +1,11 @@
+-- Single-use tokens that were redeemed, see the PostgreSQL migration
+
+CREATE TABLE used_tokens (
+    id text,
+    tenant_id text NOT NULL,
+    expires_at datetime NOT NULL,
+    created_at datetime,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_used_tokens_tenant_id ON used_tokens (tenant_id);
+CREATE INDEX idx_used_tokens_expires_at ON used_tokens (expires_at);
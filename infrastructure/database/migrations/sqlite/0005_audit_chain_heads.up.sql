@@ -0,0 +1,13 @@
+-- The head of every audit chain, see the PostgreSQL migration
+
+CREATE TABLE audit_chain_heads (
+    tenant_id text,
+    sequence integer NOT NULL,
+    hash text NOT NULL,
+    updated_at datetime,
+    PRIMARY KEY (tenant_id)
+);
+
+INSERT INTO audit_chain_heads (tenant_id, sequence, hash, updated_at)
+SELECT tenant_id, sequence, hash, CURRENT_TIMESTAMP FROM audit_events e
+WHERE sequence = (SELECT MAX(sequence) FROM audit_events WHERE tenant_id = e.tenant_id);
@@ -0,0 +1,15 @@
+-- Failed logins from a client IP across every tenant, see the PostgreSQL
+-- migration
+
+CREATE TABLE ip_login_throttles (
+    ip_address text,
+    failed_count integer,
+    last_failed_at datetime,
+    locked_until datetime,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (ip_address)
+);
+CREATE INDEX idx_ip_login_throttles_last_failed_at ON ip_login_throttles (last_failed_at);
+
+DELETE FROM login_throttles WHERE scope = 'ip';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_patient_national_id_tenant;
+CREATE INDEX idx_patients_national_id ON patients (national_id);
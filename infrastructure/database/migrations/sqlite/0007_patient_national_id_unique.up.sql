@@ -0,0 +1,5 @@
+-- National IDs are unique within a tenant when present, see the PostgreSQL
+-- migration
+
+DROP INDEX IF EXISTS idx_patients_national_id;
+CREATE UNIQUE INDEX idx_patient_national_id_tenant ON patients (tenant_id, national_id) WHERE national_id <> '';
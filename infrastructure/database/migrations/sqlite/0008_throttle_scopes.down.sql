@@ -0,0 +1,14 @@
+DELETE FROM login_throttles WHERE scope <> 'login';
+UPDATE login_throttles SET scope = 'email';
+
+DROP TABLE ip_login_throttles;
+CREATE TABLE ip_login_throttles (
+    ip_address text,
+    failed_count integer,
+    last_failed_at datetime,
+    locked_until datetime,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (ip_address)
+);
+CREATE INDEX idx_ip_login_throttles_last_failed_at ON ip_login_throttles (last_failed_at);
@@ -0,0 +1,16 @@
+-- Throttle counters per scope, see the PostgreSQL migration
+
+UPDATE login_throttles SET scope = 'login' WHERE scope = 'email';
+
+DROP TABLE ip_login_throttles;
+CREATE TABLE ip_login_throttles (
+    scope text,
+    ip_address text,
+    failed_count integer,
+    last_failed_at datetime,
+    locked_until datetime,
+    created_at datetime,
+    updated_at datetime,
+    PRIMARY KEY (scope, ip_address)
+);
+CREATE INDEX idx_ip_login_throttles_last_failed_at ON ip_login_throttles (last_failed_at);
@@ -0,0 +1,322 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"medical-system/domain/tenancy"
+
+	"gorm.io/gorm"
+)
+
+// Session settings the row-level security policies read. They are set with
+// set_config(..., true), so they only last until the end of the transaction.
+const (
+	currentTenantSetting = "app.current_tenant"
+	systemScopeSetting   = "app.system_scope"
+)
+
+// warnIfBypassingRowLevelSecurity logs when the database role skips the
+// row-level security policies of the migrations, as SUPERUSER and BYPASSRLS
+// roles do: the application should connect as an ordinary role
+func warnIfBypassingRowLevelSecurity(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	var bypasses bool
+	if err := db.Raw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypasses).Error; err != nil {
+		return err
+	}
+	if bypasses {
+		log.Println("⚠️  The database role bypasses row-level security, connect as an ordinary role for the tenant policies to apply")
+	}
+	return nil
+}
+
+// UseTenantTransactions routes the statements of db through transactions
+// that carry the tenant bound to the statement context in
+// app.current_tenant. Statements of a context prepared with WithTransaction
+// share one transaction; gorm transactions outside of one get their own.
+// Other statements run without a tenant and see no tenant-owned rows.
+// Statements of a Detached context run on the detached pool, db's own pool
+// when it is nil.
+func UseTenantTransactions(db *gorm.DB, detached *sql.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if detached == nil {
+		detached = sqlDB
+	}
+
+	pool := &tenantConnPool{db: sqlDB, detached: detached}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return nil
+}
+
+type transactionKey struct{}
+
+type detachedKey struct{}
+
+// Detached returns a context whose statements run outside the transaction of
+// the request, on a pool of their own, and outlive its cancellation. Writes
+// that must be kept when the request fails, like audit events, use it inside
+// a gorm transaction, which carries the tenant. SQLite has a single
+// connection and runs them on it.
+func Detached(ctx context.Context) context.Context {
+	return context.WithValue(context.WithoutCancel(ctx), detachedKey{}, true)
+}
+
+func isDetached(ctx context.Context) bool {
+	detached, _ := ctx.Value(detachedKey{}).(bool)
+	return detached
+}
+
+// Transaction is the database transaction shared by the work of one request
+// or command. It begins with the first statement, so work that never touches
+// the database costs nothing, and it is safe to End more than once: the next
+// statement begins a new transaction.
+type Transaction struct {
+	mu         sync.Mutex
+	tx         *scopedTx
+	savepoints int
+}
+
+// WithTransaction returns a context whose statements share one transaction.
+// The caller must End it.
+func WithTransaction(ctx context.Context) (context.Context, *Transaction) {
+	transaction := &Transaction{}
+	return context.WithValue(ctx, transactionKey{}, transaction), transaction
+}
+
+// End commits or rolls back the transaction, if one was begun
+func (t *Transaction) End(commit bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tx == nil {
+		return nil
+	}
+	tx := t.tx
+	t.tx = nil
+	if commit {
+		return tx.Commit()
+	}
+	return tx.Rollback()
+}
+
+func (t *Transaction) begin(ctx context.Context, db *sql.DB) (*scopedTx, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tx == nil {
+		// The transaction outlives the statement that happens to begin it
+		tx, err := db.BeginTx(context.WithoutCancel(ctx), nil)
+		if err != nil {
+			return nil, err
+		}
+		t.tx = &scopedTx{tx: tx}
+	}
+	return t.tx, nil
+}
+
+func (t *Transaction) savepoint(ctx context.Context, db *sql.DB) (*savepoint, error) {
+	tx, err := t.begin(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.savepoints++
+	name := fmt.Sprintf("tenant_sp%d", t.savepoints)
+	t.mu.Unlock()
+
+	if _, err := tx.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &savepoint{scopedTx: tx, name: name}, nil
+}
+
+// tenantConnPool is the connection pool gorm uses once tenant transactions
+// are enabled
+type tenantConnPool struct {
+	db       *sql.DB
+	detached *sql.DB
+}
+
+func (p *tenantConnPool) conn(ctx context.Context) (gorm.ConnPool, error) {
+	if isDetached(ctx) {
+		return p.detached, nil
+	}
+	if transaction, ok := ctx.Value(transactionKey{}).(*Transaction); ok {
+		return transaction.begin(ctx, p.db)
+	}
+	return p.db, nil
+}
+
+func (p *tenantConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.PrepareContext(ctx, query)
+}
+
+func (p *tenantConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args...)
+}
+
+func (p *tenantConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.QueryContext(ctx, query, args...)
+}
+
+func (p *tenantConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		// sql.Row carries no error of its own; run the query on the pool
+		// without a tenant, which sees no tenant-owned rows
+		return p.db.QueryRowContext(ctx, query, args...)
+	}
+	return conn.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx nests gorm transactions inside the shared transaction as
+// savepoints and begins a transaction of their own otherwise
+func (p *tenantConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	db := p.db
+	if isDetached(ctx) {
+		db = p.detached
+	} else if transaction, ok := ctx.Value(transactionKey{}).(*Transaction); ok {
+		return transaction.savepoint(ctx, p.db)
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &scopedTx{tx: tx}, nil
+}
+
+func (p *tenantConnPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+// scopedTx applies the tenant of each statement's context to the
+// transaction before running it
+type scopedTx struct {
+	tx *sql.Tx
+
+	mu      sync.Mutex
+	applied bool
+	tenant  string
+	system  bool
+}
+
+func (t *scopedTx) apply(ctx context.Context) error {
+	// Unscoped contexts apply an empty tenant, which matches no rows
+	tenantID, system, _ := tenancy.Resolve(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.applied && t.tenant == tenantID && t.system == system {
+		return nil
+	}
+
+	systemScope := "off"
+	if system {
+		systemScope = "on"
+	}
+	_, err := t.tx.ExecContext(ctx, "SELECT set_config($1, $2, true), set_config($3, $4, true)",
+		currentTenantSetting, tenantID, systemScopeSetting, systemScope)
+	if err != nil {
+		return err
+	}
+
+	t.applied, t.tenant, t.system = true, tenantID, system
+	return nil
+}
+
+// forget is called after rolling back to a savepoint, which also reverts
+// the settings applied since
+func (t *scopedTx) forget() {
+	t.mu.Lock()
+	t.applied = false
+	t.mu.Unlock()
+}
+
+func (t *scopedTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := t.apply(ctx); err != nil {
+		return nil, err
+	}
+	return t.tx.PrepareContext(ctx, query)
+}
+
+func (t *scopedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := t.apply(ctx); err != nil {
+		return nil, err
+	}
+	result, err := t.tx.ExecContext(ctx, query, args...)
+	// gorm rolls back nested transactions to savepoints itself
+	if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "ROLLBACK") {
+		t.forget()
+	}
+	return result, err
+}
+
+func (t *scopedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := t.apply(ctx); err != nil {
+		return nil, err
+	}
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *scopedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	// A failure to apply the tenant aborts the transaction, which the query
+	// then reports
+	_ = t.apply(ctx)
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *scopedTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *scopedTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// savepoint is a gorm transaction nested in a shared transaction
+type savepoint struct {
+	*scopedTx
+	name string
+}
+
+func (s *savepoint) Commit() error {
+	_, err := s.tx.Exec("RELEASE SAVEPOINT " + s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	_, err := s.tx.Exec("ROLLBACK TO SAVEPOINT " + s.name)
+	s.forget()
+	return err
+}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/tenancy"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// rlsTestRole is switched to when the test database user bypasses
+// row-level security, as superusers do
+const rlsTestRole = "medical_system_rls_test"
+
+// openRLSTestDatabase connects to TEST_DATABASE_URL with the migrations
+// applied but without the tenant scope callbacks, so nothing but the
+// row-level security policies stands between a query and other tenants' rows
+func openRLSTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// A single connection keeps the role switched for every statement
+	sqlDB.SetMaxOpenConns(1)
+	detached, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { detached.Close() })
+	detached.SetMaxOpenConns(1)
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	var bypasses bool
+	if err := db.Raw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypasses).Error; err != nil {
+		t.Fatalf("check role: %v", err)
+	}
+	if bypasses {
+		for _, statement := range []string{
+			"DO $$ BEGIN CREATE ROLE " + rlsTestRole + " NOLOGIN; EXCEPTION WHEN duplicate_object THEN NULL; END $$",
+			"GRANT SELECT, INSERT, UPDATE, DELETE ON rooms TO " + rlsTestRole,
+			"SET ROLE " + rlsTestRole,
+		} {
+			if err := db.Exec(statement).Error; err != nil {
+				t.Fatalf("switch to an ordinary role: %v", err)
+			}
+		}
+		if _, err := detached.Exec("SET ROLE " + rlsTestRole); err != nil {
+			t.Fatalf("switch to an ordinary role: %v", err)
+		}
+		t.Cleanup(func() { db.Exec("RESET ROLE") })
+	}
+
+	if err := UseTenantTransactions(db, detached); err != nil {
+		t.Fatalf("enable tenant transactions: %v", err)
+	}
+	return db
+}
+
+// createTestRoom stores a room of the tenant in a committed transaction and
+// removes it when the test ends
+func createTestRoom(t *testing.T, db *gorm.DB, tenantID string) *entities.Room {
+	t.Helper()
+
+	room := &entities.Room{TenantID: tenantID, Name: "Room " + uuid.New().String()}
+	inTenantTransaction(t, db, tenantID, func(ctx context.Context) {
+		if err := db.WithContext(ctx).Create(room).Error; err != nil {
+			t.Fatalf("create room: %v", err)
+		}
+	})
+
+	t.Cleanup(func() {
+		inTenantTransaction(t, db, tenantID, func(ctx context.Context) {
+			db.WithContext(ctx).Delete(room)
+		})
+	})
+	return room
+}
+
+func inTenantTransaction(t *testing.T, db *gorm.DB, tenantID string, fn func(ctx context.Context)) {
+	t.Helper()
+
+	ctx, tx := WithTransaction(tenancy.WithTenant(context.Background(), tenantID))
+	fn(ctx)
+	if err := tx.End(true); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+}
+
+func TestRowLevelSecurityHidesOtherTenantsWithoutWhereClause(t *testing.T) {
+	db := openRLSTestDatabase(t)
+
+	tenantA, tenantB := uuid.New().String(), uuid.New().String()
+	roomA := createTestRoom(t, db, tenantA)
+	roomB := createTestRoom(t, db, tenantB)
+
+	inTenantTransaction(t, db, tenantA, func(ctx context.Context) {
+		// Raw SQL bypasses the tenant scope callbacks; only the policy is left
+		var rooms []entities.Room
+		if err := db.WithContext(ctx).Raw("SELECT * FROM rooms").Scan(&rooms).Error; err != nil {
+			t.Fatalf("query: %v", err)
+		}
+
+		found := false
+		for _, room := range rooms {
+			if room.TenantID != tenantA {
+				t.Errorf("tenant %s read room %s of tenant %s", tenantA, room.ID, room.TenantID)
+			}
+			found = found || room.ID == roomA.ID
+		}
+		if !found {
+			t.Errorf("tenant %s cannot read its own room %s", tenantA, roomA.ID)
+		}
+
+		var count int64
+		if err := db.WithContext(ctx).Raw("SELECT count(*) FROM rooms WHERE id = ?", roomB.ID).Scan(&count).Error; err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if count != 0 {
+			t.Errorf("tenant %s can look up room %s of tenant %s by ID", tenantA, roomB.ID, tenantB)
+		}
+	})
+}
+
+func TestRowLevelSecurityHidesEverythingWithoutTenant(t *testing.T) {
+	db := openRLSTestDatabase(t)
+	createTestRoom(t, db, uuid.New().String())
+
+	ctx, tx := WithTransaction(context.Background())
+	defer tx.End(false)
+
+	var count int64
+	if err := db.WithContext(ctx).Raw("SELECT count(*) FROM rooms").Scan(&count).Error; err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("a transaction without tenant read %d rooms", count)
+	}
+}
+
+func TestRowLevelSecurityRejectsRowsOfOtherTenants(t *testing.T) {
+	db := openRLSTestDatabase(t)
+
+	tenantA, tenantB := uuid.New().String(), uuid.New().String()
+	roomB := createTestRoom(t, db, tenantB)
+
+	ctx, tx := WithTransaction(tenancy.WithTenant(context.Background(), tenantA))
+	defer tx.End(false)
+
+	foreign := &entities.Room{TenantID: tenantB, Name: "Room " + uuid.New().String()}
+	if err := db.WithContext(ctx).Create(foreign).Error; err == nil {
+		t.Errorf("tenant %s created a room for tenant %s", tenantA, tenantB)
+	}
+
+	result := db.WithContext(ctx).Exec("UPDATE rooms SET name = 'taken over'")
+	if result.Error != nil {
+		t.Fatalf("update: %v", result.Error)
+	}
+	var name string
+	inTenantTransaction(t, db, tenantB, func(ctx context.Context) {
+		db.WithContext(ctx).Raw("SELECT name FROM rooms WHERE id = ?", roomB.ID).Scan(&name)
+	})
+	if name != roomB.Name {
+		t.Errorf("tenant %s renamed room %s of tenant %s", tenantA, roomB.ID, tenantB)
+	}
+}
+
+func TestTenantTransactionRollsBackNestedTransactionOnly(t *testing.T) {
+	db := openRLSTestDatabase(t)
+	tenantID := uuid.New().String()
+
+	ctx, tx := WithTransaction(tenancy.WithTenant(context.Background(), tenantID))
+	defer tx.End(false)
+
+	kept := &entities.Room{TenantID: tenantID, Name: "Room " + uuid.New().String()}
+	if err := db.WithContext(ctx).Create(kept).Error; err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+
+	// A duplicate name fails inside its savepoint without aborting the
+	// request transaction or losing its tenant
+	duplicate := &entities.Room{TenantID: tenantID, Name: kept.Name}
+	if err := db.WithContext(ctx).Create(duplicate).Error; err == nil {
+		t.Fatal("duplicate room name was accepted")
+	}
+
+	var count int64
+	if err := db.WithContext(ctx).Raw("SELECT count(*) FROM rooms WHERE id = ?", kept.ID).Scan(&count).Error; err != nil {
+		t.Fatalf("query after failed insert: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("room %s is not visible after a failed nested insert", kept.ID)
+	}
+}
+
+func TestDetachedStatementsOutliveTheRequestTransaction(t *testing.T) {
+	db := openRLSTestDatabase(t)
+	tenantID := uuid.New().String()
+
+	ctx, tx := WithTransaction(tenancy.WithTenant(context.Background(), tenantID))
+	room := &entities.Room{TenantID: tenantID, Name: "Room " + uuid.New().String()}
+	err := db.WithContext(Detached(ctx)).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(room).Error
+	})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	t.Cleanup(func() {
+		inTenantTransaction(t, db, tenantID, func(ctx context.Context) {
+			db.WithContext(ctx).Delete(room)
+		})
+	})
+
+	// The request fails after the detached write
+	if err := tx.End(false); err != nil {
+		t.Fatalf("roll back: %v", err)
+	}
+
+	var count int64
+	inTenantTransaction(t, db, tenantID, func(ctx context.Context) {
+		db.WithContext(ctx).Raw("SELECT count(*) FROM rooms WHERE id = ?", room.ID).Scan(&count)
+	})
+	if count != 1 {
+		t.Errorf("room %s written outside the request transaction was rolled back with it", room.ID)
+	}
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sqliteDSN enforces foreign keys, which SQLite leaves off by default
+func sqliteDSN(path string) string {
+	return path + "?_pragma=foreign_keys(1)"
+}
+
+// useSQLiteConnections adapts the pool of a SQLite database. SQLite allows
+// one writer at a time and every in-memory connection would be a database of
+// its own, so statements share a single connection. Times are stored as text
+// and compared as strings, so they are written in UTC whatever the location
+// of the value.
+func useSQLiteConnections(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	pool := &utcConnPool{db: sqlDB}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return nil
+}
+
+// utcConnPool converts the time arguments of statements to UTC
+type utcConnPool struct {
+	db *sql.DB
+}
+
+func (p *utcConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, query)
+}
+
+func (p *utcConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, query, inUTC(args)...)
+}
+
+func (p *utcConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, query, inUTC(args)...)
+}
+
+func (p *utcConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, query, inUTC(args)...)
+}
+
+func (p *utcConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &utcTx{tx: tx}, nil
+}
+
+func (p *utcConnPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+type utcTx struct {
+	tx *sql.Tx
+}
+
+func (t *utcTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+func (t *utcTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, inUTC(args)...)
+}
+
+func (t *utcTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, inUTC(args)...)
+}
+
+func (t *utcTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, inUTC(args)...)
+}
+
+func (t *utcTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *utcTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func inUTC(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case time.Time:
+			args[i] = value.UTC()
+		case *time.Time:
+			if value != nil {
+				args[i] = value.UTC()
+			}
+		}
+	}
+	return args
+}
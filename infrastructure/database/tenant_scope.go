@@ -0,0 +1,156 @@
+package database
+
+import (
+	"reflect"
+
+	"medical-system/domain/tenancy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// RegisterTenantScope installs callbacks confining every statement on a
+// tenant-owned model, one with a TenantID field, to the tenant bound to the
+// statement context (see db.WithContext and the tenancy package):
+//   - queries, counts, updates and deletes get a tenant_id predicate
+//   - creates fill in an empty TenantID and refuse rows of another tenant
+//   - updates never change tenant_id
+//
+// Statements without a tenant fail, except in the system scope. Raw SQL is
+// not inspected and is left to migrations.
+func RegisterTenantScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenancy:create", scopeCreate); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenancy:query", scopeConditions); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenancy:row", scopeConditions); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenancy:update", scopeUpdate); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("tenancy:delete", scopeBulkConditions)
+}
+
+func tenantField(stmt *gorm.Statement) *schema.Field {
+	if stmt.Schema == nil {
+		return nil
+	}
+	return stmt.Schema.LookUpField("TenantID")
+}
+
+func scopeCreate(db *gorm.DB) {
+	field := tenantField(db.Statement)
+	if db.Error != nil || field == nil {
+		return
+	}
+
+	tenantID, system, err := tenancy.Resolve(db.Statement.Context)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	ctx := db.Statement.Context
+	check := func(row reflect.Value) {
+		value, zero := field.ValueOf(ctx, row)
+		switch {
+		case system && zero:
+			// The system scope has no tenant to fill in
+			db.AddError(tenancy.ErrNoTenantScope)
+		case system:
+		case zero:
+			db.AddError(field.Set(ctx, row, tenantID))
+		case value != tenantID:
+			db.AddError(tenancy.ErrCrossTenantScope)
+		}
+	}
+
+	rows := db.Statement.ReflectValue
+	switch rows.Kind() {
+	case reflect.Struct:
+		check(rows)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			check(reflect.Indirect(rows.Index(i)))
+		}
+	default:
+		// Maps carry no TenantID that could be checked
+		db.AddError(tenancy.ErrNoTenantScope)
+	}
+}
+
+// scopeConditions adds the tenant predicate in front of the statement's own
+// conditions, which are grouped so an OR among them cannot escape it
+func scopeConditions(db *gorm.DB) {
+	field := tenantField(db.Statement)
+	if db.Error != nil || field == nil {
+		return
+	}
+
+	tenantID, system, err := tenancy.Resolve(db.Statement.Context)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if system {
+		return
+	}
+
+	predicate := clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID}
+
+	existing, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{predicate}})
+		return
+	}
+
+	exprs := []clause.Expression{predicate}
+	if where, ok := existing.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+		exprs = append(exprs, clause.And(where.Exprs...))
+	}
+	existing.Expression = clause.Where{Exprs: exprs}
+	db.Statement.Clauses["WHERE"] = existing
+}
+
+// scopeBulkConditions keeps gorm's refusal of updates and deletes without
+// conditions, which the tenant predicate would otherwise satisfy
+func scopeBulkConditions(db *gorm.DB) {
+	if db.Error == nil && tenantField(db.Statement) != nil && !db.AllowGlobalUpdate && !hasConditions(db.Statement) {
+		db.AddError(gorm.ErrMissingWhereClause)
+		return
+	}
+	scopeConditions(db)
+}
+
+func scopeUpdate(db *gorm.DB) {
+	if field := tenantField(db.Statement); field != nil {
+		db.Statement.Omits = append(db.Statement.Omits, field.DBName)
+	}
+	scopeBulkConditions(db)
+}
+
+// hasConditions reports whether the statement has a WHERE clause or a model
+// whose primary key gorm turns into one
+func hasConditions(stmt *gorm.Statement) bool {
+	if _, ok := stmt.Clauses["WHERE"]; ok {
+		return true
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return false
+	}
+
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Struct:
+		_, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, stmt.ReflectValue)
+		return !zero
+	case reflect.Slice, reflect.Array:
+		return stmt.ReflectValue.Len() > 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"medical-system/config"
+	"medical-system/domain/entities"
+	"medical-system/domain/tenancy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type discardScopeRecorder struct{}
+
+func (discardScopeRecorder) RecordSystemScope(ctx context.Context, tenantID, reason string) {}
+
+// openTenantScopeTestDatabase opens a migrated in-memory SQLite database,
+// where the tenant scope callbacks are all that confines statements to a
+// tenant
+func openTenantScopeTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := NewConnection(&config.Config{Database: config.DatabaseConfig{Driver: "sqlite", Path: ":memory:"}})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	t.Cleanup(func() { Close(db) })
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestTenantScope(t *testing.T) {
+	db := openTenantScopeTestDatabase(t)
+
+	ctxA := tenancy.WithTenant(context.Background(), "tenant-a")
+	ctxB := tenancy.WithTenant(context.Background(), "tenant-b")
+	system := tenancy.WithSystemScope(context.Background(), discardScopeRecorder{}, "test")
+
+	roomA := &entities.Room{Name: "Room A"}
+	if err := db.WithContext(ctxA).Create(roomA).Error; err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	if roomA.TenantID != "tenant-a" {
+		t.Fatalf("created room has tenant %q, want tenant-a", roomA.TenantID)
+	}
+	roomB := &entities.Room{Name: "Room B"}
+	if err := db.WithContext(ctxB).Create(roomB).Error; err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+
+	countRooms := func(t *testing.T, query *gorm.DB) int64 {
+		t.Helper()
+		var count int64
+		if err := query.Model(&entities.Room{}).Count(&count).Error; err != nil {
+			t.Fatalf("count rooms: %v", err)
+		}
+		return count
+	}
+
+	tests := []struct {
+		name    string
+		run     func(t *testing.T) error
+		wantErr error
+	}{
+		{
+			name: "create without tenant",
+			run: func(t *testing.T) error {
+				return db.WithContext(context.Background()).Create(&entities.Room{Name: "Orphan"}).Error
+			},
+			wantErr: tenancy.ErrNoTenantScope,
+		},
+		{
+			name: "create for another tenant",
+			run: func(t *testing.T) error {
+				return db.WithContext(ctxA).Create(&entities.Room{TenantID: "tenant-b", Name: "Foreign"}).Error
+			},
+			wantErr: tenancy.ErrCrossTenantScope,
+		},
+		{
+			name: "create in system scope without tenant",
+			run: func(t *testing.T) error {
+				return db.WithContext(system).Create(&entities.Room{Name: "Nobody's"}).Error
+			},
+			wantErr: tenancy.ErrNoTenantScope,
+		},
+		{
+			name: "query without tenant",
+			run: func(t *testing.T) error {
+				var rooms []entities.Room
+				return db.WithContext(context.Background()).Find(&rooms).Error
+			},
+			wantErr: tenancy.ErrNoTenantScope,
+		},
+		{
+			name: "query sees own rooms only",
+			run: func(t *testing.T) error {
+				if count := countRooms(t, db.WithContext(ctxA)); count != 1 {
+					t.Errorf("tenant-a counts %d rooms, want 1", count)
+				}
+				return nil
+			},
+		},
+		{
+			name: "OR condition does not escape the tenant",
+			run: func(t *testing.T) error {
+				var rooms []entities.Room
+				if err := db.WithContext(ctxA).Where("name = ? OR 1 = 1", "Room A").Find(&rooms).Error; err != nil {
+					return err
+				}
+				for _, room := range rooms {
+					if room.TenantID != "tenant-a" {
+						t.Errorf("tenant-a read room %s of %s", room.ID, room.TenantID)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "lookup of another tenant's room by ID",
+			run: func(t *testing.T) error {
+				var room entities.Room
+				return db.WithContext(ctxA).First(&room, "id = ?", roomB.ID).Error
+			},
+			wantErr: gorm.ErrRecordNotFound,
+		},
+		{
+			name: "update of another tenant's room",
+			run: func(t *testing.T) error {
+				result := db.WithContext(ctxA).Model(&entities.Room{}).Where("id = ?", roomB.ID).Update("name", "Taken over")
+				if result.RowsAffected != 0 {
+					t.Errorf("tenant-a updated %d rooms of tenant-b", result.RowsAffected)
+				}
+				return result.Error
+			},
+		},
+		{
+			name: "update does not move a room to another tenant",
+			run: func(t *testing.T) error {
+				err := db.WithContext(ctxA).Model(roomA).Updates(map[string]interface{}{"tenant_id": "tenant-b", "description": "moved"}).Error
+				if err != nil {
+					return err
+				}
+				if count := countRooms(t, db.WithContext(ctxA)); count != 1 {
+					t.Errorf("tenant-a counts %d rooms after the update, want 1", count)
+				}
+				return nil
+			},
+		},
+		{
+			name: "delete without conditions",
+			run: func(t *testing.T) error {
+				return db.WithContext(ctxA).Delete(&entities.Room{}).Error
+			},
+			wantErr: gorm.ErrMissingWhereClause,
+		},
+		{
+			name: "delete of another tenant's room",
+			run: func(t *testing.T) error {
+				result := db.WithContext(ctxA).Where("id = ?", roomB.ID).Delete(&entities.Room{})
+				if result.RowsAffected != 0 {
+					t.Errorf("tenant-a deleted %d rooms of tenant-b", result.RowsAffected)
+				}
+				return result.Error
+			},
+		},
+		{
+			name: "system scope sees every tenant",
+			run: func(t *testing.T) error {
+				if count := countRooms(t, db.WithContext(system)); count != 2 {
+					t.Errorf("system scope counts %d rooms, want 2", count)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(t)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"medical-system/domain/services"
+)
+
+// LogMailer is a development mailer that never talks to an SMTP server. Every
+// message is written to the log and, when an outbox directory is configured,
+// stored there as a .eml file so tests and developers can read it back.
+type LogMailer struct {
+	outboxDir string
+}
+
+func NewLogMailer(outboxDir string) services.Mailer {
+	return &LogMailer{outboxDir: outboxDir}
+}
+
+func (m *LogMailer) Send(message services.EmailMessage) error {
+	log.Printf("📧 Mail to %s: %s", message.To, message.Subject)
+
+	if m.outboxDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.outboxDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail outbox: %w", err)
+	}
+
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		message.To, message.Subject, time.Now().UTC().Format(time.RFC1123Z), message.Body)
+
+	filename := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFilename(message.To))
+	return os.WriteFile(filepath.Join(m.outboxDir, filename), []byte(content), 0o600)
+}
+
+func sanitizeFilename(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+}
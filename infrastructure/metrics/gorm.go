@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// InstrumentDB times every statement of db by operation and exports the
+// statistics of its connection pool
+func (m *Metrics) InstrumentDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := m.registry.Register(collectors.NewDBStatsCollector(sqlDB, db.Dialector.Name())); err != nil {
+		return err
+	}
+
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("metrics:create_start", startTimer); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("metrics:create", m.observe("create")); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("metrics:query_start", startTimer); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register("metrics:query", m.observe("query")); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("metrics:update_start", startTimer); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("metrics:update", m.observe("update")); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("metrics:delete_start", startTimer); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("metrics:delete", m.observe("delete")); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("metrics:row_start", startTimer); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("gorm:row").Register("metrics:row", m.observe("row")); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("metrics:raw_start", startTimer); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("metrics:raw", m.observe("raw"))
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (m *Metrics) observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		start, isTime := value.(time.Time)
+		if !ok || !isTime {
+			return
+		}
+		m.dbDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			m.dbErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"medical-system/domain/entities"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware observes every request by method, route template and status,
+// and by tenant when enabled. Only tenants the tenant middleware loaded count,
+// a tenant named in a header does not get a label of its own.
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			// Errors are only written by Echo's error handler, after the
+			// middleware returns
+			status := c.Response().Status
+			if err != nil {
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				} else if !c.Response().Committed {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			labels := []string{c.Request().Method, route, strconv.Itoa(status)}
+			if m.tenantLabel {
+				var tenantID string
+				if tenant, ok := c.Get("tenant").(*entities.Tenant); ok {
+					tenantID = tenant.ID
+				}
+				labels = append(labels, m.tenantValue(tenantID))
+			}
+
+			m.httpRequests.WithLabelValues(labels...).Inc()
+			m.httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"medical-system/config"
+	"medical-system/domain/entities"
+	"medical-system/domain/services"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "medical_system"
+
+// otherTenant labels the tenants past the configured maximum
+const otherTenant = "other"
+
+// Metrics holds the Prometheus collectors of the service. They live in a
+// registry of their own rather than the global one, so every container, as
+// the tests build, can register its own.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+	dbErrors     *prometheus.CounterVec
+
+	logins              *prometheus.CounterVec
+	tenantRegistrations *prometheus.CounterVec
+	userLimitRejections prometheus.Counter
+
+	tenantLabel bool
+	maxTenants  int
+	mu          sync.Mutex
+	tenants     map[string]bool
+}
+
+func New(cfg *config.Config) *Metrics {
+	httpLabels := []string{"method", "route", "status"}
+	if cfg.Metrics.TenantLabel {
+		httpLabels = append(httpLabels, "tenant")
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route template and status.",
+		}, httpLabels),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, httpLabels),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database statement latency by operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_errors_total",
+			Help:      "Failed database statements by operation, not counting missing records.",
+		}, []string{"operation"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_logins_total",
+			Help:      "Login steps by step and outcome.",
+		}, []string{"step", "outcome"}),
+		tenantRegistrations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tenant_registrations_total",
+			Help:      "Tenants registered by subscription plan.",
+		}, []string{"plan"}),
+		userLimitRejections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tenant_user_limit_rejections_total",
+			Help:      "Users refused because their tenant reached its user limit.",
+		}),
+		tenantLabel: cfg.Metrics.TenantLabel,
+		maxTenants:  cfg.Metrics.MaxTenants,
+		tenants:     make(map[string]bool),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbDuration,
+		m.dbErrors,
+		m.logins,
+		m.tenantRegistrations,
+		m.userLimitRejections,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// tenantValue returns the tenant label of a request: the tenant itself
+// while fewer than the maximum tenants have been seen, "other" afterwards
+func (m *Metrics) tenantValue(tenantID string) string {
+	if tenantID == "" {
+		return "none"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tenants[tenantID] {
+		return tenantID
+	}
+	if len(m.tenants) >= m.maxTenants {
+		return otherTenant
+	}
+	m.tenants[tenantID] = true
+	return tenantID
+}
+
+var _ services.BusinessMetrics = (*Metrics)(nil)
+
+func (m *Metrics) LoginAttempt(step, outcome string) {
+	m.logins.WithLabelValues(step, outcome).Inc()
+}
+
+// TenantRegistered labels plans outside of the known ones as "other", the
+// plan comes from a public request
+func (m *Metrics) TenantRegistered(plan entities.SubscriptionPlan) {
+	label := string(plan)
+	switch plan {
+	case entities.PlanBasic, entities.PlanProfessional, entities.PlanEnterprise:
+	default:
+		label = "other"
+	}
+	m.tenantRegistrations.WithLabelValues(label).Inc()
+}
+
+func (m *Metrics) UserLimitRejected() {
+	m.userLimitRejections.Inc()
+}
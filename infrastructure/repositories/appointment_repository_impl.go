@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AppointmentRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAppointmentRepository(db *gorm.DB) repositories.AppointmentRepository {
+	return &AppointmentRepositoryImpl{db: db}
+}
+
+func (r *AppointmentRepositoryImpl) Create(ctx context.Context, appointment *entities.Appointment) error {
+	return r.db.WithContext(ctx).Create(appointment).Error
+}
+
+func (r *AppointmentRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.Appointment, error) {
+	var appointment entities.Appointment
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&appointment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appointment, nil
+}
+
+func (r *AppointmentRepositoryImpl) Update(ctx context.Context, appointment *entities.Appointment) error {
+	result := r.db.WithContext(ctx).Model(appointment).
+		Select("*").
+		Omit("CreatedAt").
+		Updates(appointment)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *AppointmentRepositoryImpl) Search(ctx context.Context, criteria repositories.AppointmentSearchCriteria) ([]*entities.Appointment, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Appointment{})
+
+	if criteria.ProviderID != "" {
+		query = query.Where("provider_id = ?", criteria.ProviderID)
+	}
+	if criteria.PatientID != "" {
+		query = query.Where("patient_id = ?", criteria.PatientID)
+	}
+	if criteria.RoomID != "" {
+		query = query.Where("room_id = ?", criteria.RoomID)
+	}
+	if criteria.Status != "" {
+		query = query.Where("status = ?", criteria.Status)
+	}
+	if criteria.From != nil {
+		query = query.Where("start_at >= ?", criteria.From.UTC())
+	}
+	if criteria.To != nil {
+		query = query.Where("start_at < ?", criteria.To.UTC())
+	}
+
+	var appointments []*entities.Appointment
+	err := query.Order("start_at").Find(&appointments).Error
+	return appointments, err
+}
+
+func (r *AppointmentRepositoryImpl) FindOverlapping(ctx context.Context, providerID string, roomID *string, start, end time.Time, excludeID string) ([]*entities.Appointment, error) {
+	resources := r.db.Where("provider_id = ?", providerID)
+	if roomID != nil {
+		resources = resources.Or("room_id = ?", *roomID)
+	}
+
+	query := r.db.WithContext(ctx).
+		Where(resources).
+		Where("status NOT IN ?", []entities.AppointmentStatus{entities.AppointmentCancelled, entities.AppointmentNoShow}).
+		Where("start_at < ? AND end_at > ?", end.UTC(), start.UTC())
+	if excludeID != "" {
+		query = query.Where("id <> ?", excludeID)
+	}
+
+	var appointments []*entities.Appointment
+	err := query.Order("start_at").Find(&appointments).Error
+	return appointments, err
+}
+
+func (r *AppointmentRepositoryImpl) LockProvider(ctx context.Context, providerID string) error {
+	var user entities.User
+	return r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", providerID).
+		First(&user).Error
+}
+
+func (r *AppointmentRepositoryImpl) LockRoom(ctx context.Context, roomID string) error {
+	var room entities.Room
+	return r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", roomID).
+		First(&room).Error
+}
+
+func (r *AppointmentRepositoryImpl) Transaction(ctx context.Context, fn func(repo repositories.AppointmentRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&AppointmentRepositoryImpl{db: tx})
+	})
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/domain/tenancy"
+	"medical-system/infrastructure/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// auditAppendAttempts bounds the retries when concurrent appends race for the
+// same sequence number
+const auditAppendAttempts = 5
+
+type AuditEventRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAuditEventRepository(db *gorm.DB) repositories.AuditEventRepository {
+	return &AuditEventRepositoryImpl{db: db}
+}
+
+// Append binds its own scope to the event's tenant: system scope entries are
+// recorded from inside other scopes. The event is written on the detached
+// pool, so it is kept when the request fails, is not cut short by the
+// caller's cancellation and never waits on connections held by requests.
+func (r *AuditEventRepositoryImpl) Append(ctx context.Context, event *entities.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+
+	chainCtx := tenancy.WithTenant(database.Detached(context.Background()), event.TenantID)
+
+	var err error
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		err = r.db.WithContext(chainCtx).Transaction(func(tx *gorm.DB) error {
+			// Lock the head of the chain; a concurrent append that read the
+			// same head, or found none, fails on the unique sequence index
+			// and retries
+			var head entities.AuditChainHead
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Limit(1).
+				Find(&head).Error
+			if err != nil {
+				return err
+			}
+
+			event.Sequence = head.Sequence + 1
+			event.PrevHash = head.Hash
+			event.Hash = event.ComputeHash()
+			if err := tx.Create(event).Error; err != nil {
+				return err
+			}
+
+			head = entities.AuditChainHead{TenantID: event.TenantID, Sequence: event.Sequence, Hash: event.Hash}
+			return tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "tenant_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"sequence", "hash", "updated_at"}),
+			}).Create(&head).Error
+		})
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (r *AuditEventRepositoryImpl) Search(ctx context.Context, criteria repositories.AuditSearchCriteria) ([]*entities.AuditEvent, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.AuditEvent{})
+
+	filters := map[string]string{
+		"actor_id":      criteria.ActorID,
+		"action":        criteria.Action,
+		"resource_type": criteria.ResourceType,
+		"resource_id":   criteria.ResourceID,
+		"outcome":       criteria.Outcome,
+		"request_id":    criteria.RequestID,
+	}
+	for column, value := range filters {
+		if value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	if criteria.From != nil {
+		query = query.Where("occurred_at >= ?", criteria.From.UTC())
+	}
+	if criteria.To != nil {
+		query = query.Where("occurred_at < ?", criteria.To.UTC())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []*entities.AuditEvent
+	err := query.Order("sequence DESC").
+		Limit(criteria.Limit).
+		Offset(criteria.Offset).
+		Find(&events).Error
+	return events, total, err
+}
+
+func (r *AuditEventRepositoryImpl) FindChain(ctx context.Context, afterSequence int64, limit int) ([]*entities.AuditEvent, error) {
+	var events []*entities.AuditEvent
+	err := r.db.WithContext(ctx).Where("sequence > ?", afterSequence).
+		Order("sequence").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *AuditEventRepositoryImpl) FindHead(ctx context.Context) (*entities.AuditChainHead, error) {
+	var heads []*entities.AuditChainHead
+	if err := r.db.WithContext(ctx).Limit(1).Find(&heads).Error; err != nil {
+		return nil, err
+	}
+	if len(heads) == 0 {
+		return nil, nil
+	}
+	return heads[0], nil
+}
+
+func (r *AuditEventRepositoryImpl) FindTenantIDs(ctx context.Context) ([]string, error) {
+	var tenantIDs []string
+	err := r.db.WithContext(ctx).Model(&entities.AuditEvent{}).
+		Distinct("tenant_id").
+		Order("tenant_id").
+		Pluck("tenant_id", &tenantIDs).Error
+	return tenantIDs, err
+}
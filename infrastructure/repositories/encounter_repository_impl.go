@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+)
+
+type EncounterRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewEncounterRepository(db *gorm.DB) repositories.EncounterRepository {
+	return &EncounterRepositoryImpl{db: db}
+}
+
+func (r *EncounterRepositoryImpl) Create(ctx context.Context, encounter *entities.Encounter) error {
+	return r.db.WithContext(ctx).Create(encounter).Error
+}
+
+func (r *EncounterRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.Encounter, error) {
+	var encounter entities.Encounter
+	err := r.db.WithContext(ctx).Preload("Diagnoses").
+		Preload("Amendments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("amended_at")
+		}).
+		Where("id = ?", id).
+		First(&encounter).Error
+	if err != nil {
+		return nil, err
+	}
+	return &encounter, nil
+}
+
+func (r *EncounterRepositoryImpl) FindByPatient(ctx context.Context, patientID string) ([]*entities.Encounter, error) {
+	var encounters []*entities.Encounter
+	err := r.db.WithContext(ctx).Preload("Diagnoses").
+		Where("patient_id = ?", patientID).
+		Order("created_at DESC").
+		Find(&encounters).Error
+	return encounters, err
+}
+
+func (r *EncounterRepositoryImpl) UpdateDraft(ctx context.Context, encounter *entities.Encounter) (bool, error) {
+	updated := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The status predicate keeps signed notes untouched even if the
+		// encounter was signed after it was read
+		result := tx.Model(encounter).
+			Where("status = ?", entities.EncounterDraft).
+			Select("*").
+			Omit("TenantID", "PatientID", "ProviderID", "Status", "SignedAt", "SignedBy", "CreatedAt", "Diagnoses", "Amendments").
+			Updates(encounter)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := tx.Where("encounter_id = ?", encounter.ID).Delete(&entities.Diagnosis{}).Error; err != nil {
+			return err
+		}
+		if len(encounter.Diagnoses) > 0 {
+			if err := tx.Create(&encounter.Diagnoses).Error; err != nil {
+				return err
+			}
+		}
+
+		updated = true
+		return nil
+	})
+	return updated, err
+}
+
+func (r *EncounterRepositoryImpl) Sign(ctx context.Context, encounter *entities.Encounter) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entities.Encounter{}).
+		Where("id = ? AND status = ?", encounter.ID, entities.EncounterDraft).
+		Updates(map[string]interface{}{
+			"status":     entities.EncounterSigned,
+			"signed_at":  encounter.SignedAt,
+			"signed_by":  encounter.SignedBy,
+			"updated_at": encounter.UpdatedAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *EncounterRepositoryImpl) CreateAmendment(ctx context.Context, amendment *entities.EncounterAmendment) error {
+	return r.db.WithContext(ctx).Create(amendment).Error
+}
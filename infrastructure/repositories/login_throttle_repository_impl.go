@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/infrastructure/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) repositories.LoginThrottleRepository {
+	return &LoginThrottleRepositoryImpl{db: db}
+}
+
+func (r *LoginThrottleRepositoryImpl) FindForUpdate(ctx context.Context, scope, identifier string) (*entities.LoginThrottle, error) {
+	// A concurrent attempt may create the same counter first, the unique key
+	// leaves one of them
+	empty := &entities.LoginThrottle{Scope: scope, Identifier: identifier}
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(empty).Error; err != nil {
+		return nil, err
+	}
+
+	var throttle entities.LoginThrottle
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("scope = ? AND identifier = ?", scope, identifier).
+		First(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *LoginThrottleRepositoryImpl) Save(ctx context.Context, throttle *entities.LoginThrottle) error {
+	return r.db.WithContext(ctx).Save(throttle).Error
+}
+
+func (r *LoginThrottleRepositoryImpl) Delete(ctx context.Context, scope, identifier string) error {
+	return r.db.WithContext(ctx).Where("scope = ? AND identifier = ?", scope, identifier).Delete(&entities.LoginThrottle{}).Error
+}
+
+func (r *LoginThrottleRepositoryImpl) FindIPForUpdate(ctx context.Context, scope, ipAddress string) (*entities.IPLoginThrottle, error) {
+	empty := &entities.IPLoginThrottle{Scope: scope, IPAddress: ipAddress}
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(empty).Error; err != nil {
+		return nil, err
+	}
+
+	var throttle entities.IPLoginThrottle
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("scope = ? AND ip_address = ?", scope, ipAddress).
+		First(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *LoginThrottleRepositoryImpl) SaveIP(ctx context.Context, throttle *entities.IPLoginThrottle) error {
+	return r.db.WithContext(ctx).Save(throttle).Error
+}
+
+func (r *LoginThrottleRepositoryImpl) DeleteInactive(ctx context.Context, before time.Time) error {
+	if err := r.db.WithContext(ctx).Where("last_failed_at < ?", before).Delete(&entities.LoginThrottle{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("last_failed_at < ?", before).Delete(&entities.IPLoginThrottle{}).Error
+}
+
+func (r *LoginThrottleRepositoryImpl) Transaction(ctx context.Context, fn func(repo repositories.LoginThrottleRepository) error) error {
+	return r.db.WithContext(database.Detached(ctx)).Transaction(func(tx *gorm.DB) error {
+		return fn(&LoginThrottleRepositoryImpl{db: tx})
+	})
+}
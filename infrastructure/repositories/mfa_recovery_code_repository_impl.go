@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+)
+
+type MFARecoveryCodeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewMFARecoveryCodeRepository(db *gorm.DB) repositories.MFARecoveryCodeRepository {
+	return &MFARecoveryCodeRepositoryImpl{db: db}
+}
+
+// ReplaceForUser discards any previous recovery codes of the user and stores the new set
+func (r *MFARecoveryCodeRepositoryImpl) ReplaceForUser(ctx context.Context, userID string, codes []*entities.MFARecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *MFARecoveryCodeRepositoryImpl) FindUnusedByUser(ctx context.Context, userID string) ([]*entities.MFARecoveryCode, error) {
+	var codes []*entities.MFARecoveryCode
+	err := r.db.WithContext(ctx).Where("user_id = ? AND used_at IS NULL", userID).Find(&codes).Error
+	return codes, err
+}
+
+// MarkUsed flags an unused code as used and reports whether this call was the one that did it
+func (r *MFARecoveryCodeRepositoryImpl) MarkUsed(ctx context.Context, id string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entities.MFARecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *MFARecoveryCodeRepositoryImpl) DeleteForUser(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entities.MFARecoveryCode{}).Error
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) repositories.PasswordResetTokenRepository {
+	return &PasswordResetTokenRepositoryImpl{db: db}
+}
+
+func (r *PasswordResetTokenRepositoryImpl) Create(ctx context.Context, token *entities.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *PasswordResetTokenRepositoryImpl) FindByTokenHash(ctx context.Context, tokenHash string) (*entities.PasswordResetToken, error) {
+	var token entities.PasswordResetToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PasswordResetTokenRepositoryImpl) Redeem(ctx context.Context, token *entities.PasswordResetToken, passwordHash string) (bool, error) {
+	redeemed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&entities.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+
+		err := tx.Model(&entities.User{}).
+			Where("id = ?", token.UserID).
+			Updates(map[string]interface{}{"password_hash": passwordHash, "updated_at": now}).Error
+		if err != nil {
+			return err
+		}
+		redeemed = true
+		return nil
+	})
+	return redeemed, err
+}
+
+func (r *PasswordResetTokenRepositoryImpl) InvalidateForUser(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Model(&entities.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/infrastructure/database"
+
+	"gorm.io/gorm"
+)
+
+type PatientRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewPatientRepository(db *gorm.DB) repositories.PatientRepository {
+	return &PatientRepositoryImpl{db: db}
+}
+
+func (r *PatientRepositoryImpl) Create(ctx context.Context, patient *entities.Patient) error {
+	return duplicateKey(r.db.WithContext(ctx).Create(patient).Error)
+}
+
+func (r *PatientRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.Patient, error) {
+	var patient entities.Patient
+	err := r.db.WithContext(ctx).Preload("EmergencyContacts").Preload("InsurancePolicies").
+		Where("id = ?", id).
+		First(&patient).Error
+	if err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+// Update saves the patient and replaces its emergency contacts and insurance policies
+func (r *PatientRepositoryImpl) Update(ctx context.Context, patient *entities.Patient) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(patient).
+			Select("*").
+			Omit("EmergencyContacts", "InsurancePolicies", "CreatedAt").
+			Updates(patient)
+		if result.Error != nil {
+			return duplicateKey(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("patient_id = ?", patient.ID).Delete(&entities.EmergencyContact{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("patient_id = ?", patient.ID).Delete(&entities.InsurancePolicy{}).Error; err != nil {
+			return err
+		}
+
+		if len(patient.EmergencyContacts) > 0 {
+			if err := tx.Create(&patient.EmergencyContacts).Error; err != nil {
+				return err
+			}
+		}
+		if len(patient.InsurancePolicies) > 0 {
+			if err := tx.Create(&patient.InsurancePolicies).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// duplicateKey reports writes refused by a unique index as
+// repositories.ErrDuplicateKey
+func duplicateKey(err error) error {
+	if database.IsUniqueViolation(err) {
+		return fmt.Errorf("%w: %v", repositories.ErrDuplicateKey, err)
+	}
+	return err
+}
+
+// likeEscaper escapes the LIKE wildcards of search terms, which are matched
+// literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// dateOnly formats a date for comparison with DATE(column): SQLite stores
+// dates with a time of day, and the time a client sent is irrelevant
+func dateOnly(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func (r *PatientRepositoryImpl) Search(ctx context.Context, criteria repositories.PatientSearchCriteria) ([]*entities.Patient, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entities.Patient{})
+
+	if !criteria.IncludeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	if criteria.DateOfBirth != nil {
+		query = query.Where("DATE(date_of_birth) = ?", dateOnly(*criteria.DateOfBirth))
+	}
+	for _, term := range strings.Fields(strings.ToLower(criteria.Query)) {
+		pattern := "%" + likeEscaper.Replace(term) + "%"
+		query = query.Where(
+			`LOWER(first_name) LIKE ? ESCAPE '\' OR LOWER(last_name) LIKE ? ESCAPE '\' OR LOWER(mrn) LIKE ? ESCAPE '\' OR `+
+				`LOWER(national_id) LIKE ? ESCAPE '\' OR phone LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\'`,
+			pattern, pattern, pattern, pattern, pattern, pattern,
+		)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var patients []*entities.Patient
+	err := query.Order("last_name, first_name").
+		Limit(criteria.Limit).
+		Offset(criteria.Offset).
+		Find(&patients).Error
+	return patients, total, err
+}
+
+// FindPossibleDuplicates returns other patients sharing the MRN or national
+// ID, or with the same name and date of birth
+func (r *PatientRepositoryImpl) FindPossibleDuplicates(ctx context.Context, patient *entities.Patient) ([]*entities.Patient, error) {
+	matches := r.db.Where(
+		"LOWER(first_name) = ? AND LOWER(last_name) = ? AND DATE(date_of_birth) = ?",
+		strings.ToLower(patient.FirstName), strings.ToLower(patient.LastName), dateOnly(patient.DateOfBirth),
+	)
+	if patient.MRN != "" {
+		matches = matches.Or("mrn = ?", patient.MRN)
+	}
+	if patient.NationalID != "" {
+		matches = matches.Or("national_id = ?", patient.NationalID)
+	}
+
+	query := r.db.WithContext(ctx).Where(matches)
+	if patient.ID != "" {
+		query = query.Where("id <> ?", patient.ID)
+	}
+
+	var patients []*entities.Patient
+	err := query.Find(&patients).Error
+	return patients, err
+}
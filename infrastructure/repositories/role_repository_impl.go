@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+)
+
+type RoleRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) repositories.RoleRepository {
+	return &RoleRepositoryImpl{db: db}
+}
+
+func (r *RoleRepositoryImpl) Create(ctx context.Context, role *entities.Role) error {
+	return r.db.WithContext(ctx).Create(role).Error
+}
+
+func (r *RoleRepositoryImpl) FindByName(ctx context.Context, name string) (*entities.Role, error) {
+	var role entities.Role
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepositoryImpl) List(ctx context.Context) ([]*entities.Role, error) {
+	var roles []*entities.Role
+	err := r.db.WithContext(ctx).Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *RoleRepositoryImpl) Delete(ctx context.Context, name string) error {
+	return r.db.WithContext(ctx).Where("name = ?", name).Delete(&entities.Role{}).Error
+}
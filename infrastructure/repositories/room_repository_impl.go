@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+)
+
+type RoomRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewRoomRepository(db *gorm.DB) repositories.RoomRepository {
+	return &RoomRepositoryImpl{db: db}
+}
+
+func (r *RoomRepositoryImpl) Create(ctx context.Context, room *entities.Room) error {
+	return r.db.WithContext(ctx).Create(room).Error
+}
+
+func (r *RoomRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.Room, error) {
+	var room entities.Room
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&room).Error
+	if err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+func (r *RoomRepositoryImpl) List(ctx context.Context) ([]*entities.Room, error) {
+	var rooms []*entities.Room
+	err := r.db.WithContext(ctx).Order("name").Find(&rooms).Error
+	return rooms, err
+}
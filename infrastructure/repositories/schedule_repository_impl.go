@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+)
+
+type ScheduleRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) repositories.ScheduleRepository {
+	return &ScheduleRepositoryImpl{db: db}
+}
+
+func (r *ScheduleRepositoryImpl) CreateSchedule(ctx context.Context, schedule *entities.ProviderSchedule) error {
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+func (r *ScheduleRepositoryImpl) FindSchedulesByProvider(ctx context.Context, providerID string) ([]*entities.ProviderSchedule, error) {
+	var schedules []*entities.ProviderSchedule
+	err := r.db.WithContext(ctx).Where("provider_id = ?", providerID).
+		Order("weekday, start_time").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *ScheduleRepositoryImpl) DeleteSchedule(ctx context.Context, id string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.ProviderSchedule{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *ScheduleRepositoryImpl) CreateException(ctx context.Context, exception *entities.ScheduleException) error {
+	return r.db.WithContext(ctx).Create(exception).Error
+}
+
+func (r *ScheduleRepositoryImpl) FindExceptions(ctx context.Context, providerID string, from, to time.Time) ([]*entities.ScheduleException, error) {
+	query := r.db.WithContext(ctx).Where("starts_at < ? AND ends_at > ?", to.UTC(), from.UTC())
+	if providerID != "" {
+		query = query.Where("provider_id = ? OR provider_id = ''", providerID)
+	}
+
+	var exceptions []*entities.ScheduleException
+	err := query.Order("starts_at").Find(&exceptions).Error
+	return exceptions, err
+}
+
+func (r *ScheduleRepositoryImpl) DeleteException(ctx context.Context, id string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.ScheduleException{})
+	return result.RowsAffected > 0, result.Error
+}
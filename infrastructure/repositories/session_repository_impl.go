@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+	"medical-system/infrastructure/database"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) repositories.SessionRepository {
+	return &SessionRepositoryImpl{db: db}
+}
+
+func (r *SessionRepositoryImpl) Create(ctx context.Context, session *entities.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *SessionRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.Session, error) {
+	var session entities.Session
+	err := r.db.WithContext(ctx).First(&session, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepositoryImpl) FindByRefreshTokenHash(ctx context.Context, tokenHash string) (*entities.Session, error) {
+	var session entities.Session
+	err := r.db.WithContext(ctx).Where("refresh_token_hash = ?", tokenHash).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepositoryImpl) FindByPreviousTokenHash(ctx context.Context, tokenHash string) (*entities.Session, error) {
+	var session entities.Session
+	err := r.db.WithContext(ctx).Where("previous_token_hash = ?", tokenHash).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate replaces the session's refresh token hash, provided nobody rotated it
+// since the session was read. It reports whether the rotation took place.
+func (r *SessionRepositoryImpl) Rotate(ctx context.Context, session *entities.Session, newTokenHash string) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, session.RefreshTokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  newTokenHash,
+			"previous_token_hash": session.RefreshTokenHash,
+			"user_agent":          session.UserAgent,
+			"ip_address":          session.IPAddress,
+			"last_used_at":        now,
+			"updated_at":          now,
+		})
+	if result.Error != nil || result.RowsAffected != 1 {
+		return false, result.Error
+	}
+
+	session.PreviousTokenHash = session.RefreshTokenHash
+	session.RefreshTokenHash = newTokenHash
+	session.LastUsedAt = now
+	session.UpdatedAt = now
+	return true, nil
+}
+
+func (r *SessionRepositoryImpl) Revoke(ctx context.Context, id string) error {
+	return r.db.WithContext(database.Detached(ctx)).Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&entities.Session{}).
+			Where("id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+func (r *SessionRepositoryImpl) RevokeAllForUser(ctx context.Context, userID string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+func (r *SessionRepositoryImpl) RevokeAll(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+func (r *SessionRepositoryImpl) RevokeOthersForUser(ctx context.Context, userID, keepSessionID string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entities.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+)
+
+type TenantDomainRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewTenantDomainRepository(db *gorm.DB) repositories.TenantDomainRepository {
+	return &TenantDomainRepositoryImpl{db: db}
+}
+
+func (r *TenantDomainRepositoryImpl) Create(ctx context.Context, domain *entities.TenantDomain) error {
+	return r.db.WithContext(ctx).Create(domain).Error
+}
+
+func (r *TenantDomainRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.TenantDomain, error) {
+	var domain entities.TenantDomain
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&domain).Error
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+func (r *TenantDomainRepositoryImpl) List(ctx context.Context) ([]*entities.TenantDomain, error) {
+	var domains []*entities.TenantDomain
+	err := r.db.WithContext(ctx).Order("domain").Find(&domains).Error
+	return domains, err
+}
+
+func (r *TenantDomainRepositoryImpl) MarkVerified(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Model(&entities.TenantDomain{}).
+		Where("id = ?", id).
+		Update("verified_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *TenantDomainRepositoryImpl) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&entities.TenantDomain{}, "id = ?", id).Error
+}
+
+// FindVerified runs as raw SQL, which the tenant scope leaves alone: the
+// lookup is what tells which tenant a request is for. Row-level security
+// lets verified domains be read without a tenant for the same reason.
+func (r *TenantDomainRepositoryImpl) FindVerified(ctx context.Context, domain string) (*entities.TenantDomain, error) {
+	var domains []*entities.TenantDomain
+	err := r.db.WithContext(ctx).
+		Raw("SELECT * FROM tenant_domains WHERE domain = ? AND verified_at IS NOT NULL", domain).
+		Scan(&domains).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return domains[0], nil
+}
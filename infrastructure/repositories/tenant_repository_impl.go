@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"context"
+
 	"medical-system/domain/entities"
 	"medical-system/domain/repositories"
 
@@ -15,54 +17,65 @@ func NewTenantRepository(db *gorm.DB) repositories.TenantRepository {
 	return &TenantRepositoryImpl{db: db}
 }
 
-func (r *TenantRepositoryImpl) Create(tenant *entities.Tenant) error {
-	return r.db.Create(tenant).Error
+func (r *TenantRepositoryImpl) Create(ctx context.Context, tenant *entities.Tenant) error {
+	return r.db.WithContext(ctx).Create(tenant).Error
+}
+
+func (r *TenantRepositoryImpl) Provision(ctx context.Context, tenant *entities.Tenant, settings *entities.TenantSettings, roles []*entities.Role, admin *entities.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(settings).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&roles).Error; err != nil {
+			return err
+		}
+		return tx.Create(admin).Error
+	})
 }
 
-func (r *TenantRepositoryImpl) FindByID(id string) (*entities.Tenant, error) {
+func (r *TenantRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.Tenant, error) {
 	var tenant entities.Tenant
-	err := r.db.First(&tenant, "id = ?", id).Error
+	err := r.db.WithContext(ctx).First(&tenant, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &tenant, nil
 }
 
-func (r *TenantRepositoryImpl) FindBySlug(slug string) (*entities.Tenant, error) {
+func (r *TenantRepositoryImpl) FindBySlug(ctx context.Context, slug string) (*entities.Tenant, error) {
 	var tenant entities.Tenant
-	err := r.db.Where("slug = ?", slug).First(&tenant).Error
+	err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&tenant).Error
 	if err != nil {
 		return nil, err
 	}
 	return &tenant, nil
 }
 
-func (r *TenantRepositoryImpl) FindByEmail(email string) (*entities.Tenant, error) {
+func (r *TenantRepositoryImpl) FindByEmail(ctx context.Context, email string) (*entities.Tenant, error) {
 	var tenant entities.Tenant
-	err := r.db.Where("email = ?", email).First(&tenant).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&tenant).Error
 	if err != nil {
 		return nil, err
 	}
 	return &tenant, nil
 }
 
-func (r *TenantRepositoryImpl) Update(tenant *entities.Tenant) error {
-	return r.db.Save(tenant).Error
-}
-
-func (r *TenantRepositoryImpl) Delete(id string) error {
-	return r.db.Delete(&entities.Tenant{}, "id = ?", id).Error
+func (r *TenantRepositoryImpl) Update(ctx context.Context, tenant *entities.Tenant) error {
+	return r.db.WithContext(ctx).Save(tenant).Error
 }
 
-func (r *TenantRepositoryImpl) ListActive() ([]*entities.Tenant, error) {
+func (r *TenantRepositoryImpl) ListActive(ctx context.Context) ([]*entities.Tenant, error) {
 	var tenants []*entities.Tenant
-	err := r.db.Where("is_active = ?", true).Find(&tenants).Error
+	err := r.db.WithContext(ctx).Where("is_active = ?", true).Find(&tenants).Error
 	return tenants, err
 }
 
-func (r *TenantRepositoryImpl) CountUsersByTenant(tenantID string) (int64, error) {
+func (r *TenantRepositoryImpl) CountUsers(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.db.Model(&entities.User{}).Where("tenant_id = ?", tenantID).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&entities.User{}).Count(&count).Error
 	return count, err
 }
 
@@ -74,23 +87,29 @@ func NewTenantSettingsRepository(db *gorm.DB) repositories.TenantSettingsReposit
 	return &TenantSettingsRepositoryImpl{db: db}
 }
 
-func (r *TenantSettingsRepositoryImpl) Create(settings *entities.TenantSettings) error {
-	return r.db.Create(settings).Error
+func (r *TenantSettingsRepositoryImpl) Create(ctx context.Context, settings *entities.TenantSettings) error {
+	return r.db.WithContext(ctx).Create(settings).Error
 }
 
-func (r *TenantSettingsRepositoryImpl) FindByTenantID(tenantID string) (*entities.TenantSettings, error) {
+func (r *TenantSettingsRepositoryImpl) Find(ctx context.Context) (*entities.TenantSettings, error) {
 	var settings entities.TenantSettings
-	err := r.db.Where("tenant_id = ?", tenantID).First(&settings).Error
+	err := r.db.WithContext(ctx).First(&settings).Error
 	if err != nil {
 		return nil, err
 	}
 	return &settings, nil
 }
 
-func (r *TenantSettingsRepositoryImpl) Update(settings *entities.TenantSettings) error {
-	return r.db.Save(settings).Error
-}
-
-func (r *TenantSettingsRepositoryImpl) Delete(tenantID string) error {
-	return r.db.Where("tenant_id = ?", tenantID).Delete(&entities.TenantSettings{}).Error
+func (r *TenantSettingsRepositoryImpl) Update(ctx context.Context, settings *entities.TenantSettings) error {
+	result := r.db.WithContext(ctx).Model(settings).
+		Select("*").
+		Omit("Tenant", "CreatedAt").
+		Updates(settings)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
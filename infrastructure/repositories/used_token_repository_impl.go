@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UsedTokenRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewUsedTokenRepository(db *gorm.DB) repositories.UsedTokenRepository {
+	return &UsedTokenRepositoryImpl{db: db}
+}
+
+func (r *UsedTokenRepositoryImpl) Claim(ctx context.Context, token *entities.UsedToken) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *UsedTokenRepositoryImpl) DeleteExpired(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&entities.UsedToken{}).Error
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"context"
+
 	"medical-system/domain/entities"
 	"medical-system/domain/repositories"
 
@@ -15,32 +17,49 @@ func NewUserRepository(db *gorm.DB) repositories.UserRepository {
 	return &UserRepositoryImpl{db: db}
 }
 
-func (r *UserRepositoryImpl) Create(user *entities.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepositoryImpl) Create(ctx context.Context, user *entities.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *UserRepositoryImpl) FindByEmailAndTenant(email, tenantID string) (*entities.User, error) {
+func (r *UserRepositoryImpl) FindByEmail(ctx context.Context, email string) (*entities.User, error) {
 	var user entities.User
-	err := r.db.Where("email = ? AND tenant_id = ?", email, tenantID).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepositoryImpl) FindByID(id string) (*entities.User, error) {
+func (r *UserRepositoryImpl) FindByID(ctx context.Context, id string) (*entities.User, error) {
 	var user entities.User
-	err := r.db.First(&user, "id = ?", id).Error
+	err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepositoryImpl) Update(user *entities.User) error {
-	return r.db.Save(user).Error
+func (r *UserRepositoryImpl) Update(ctx context.Context, user *entities.User) error {
+	result := r.db.WithContext(ctx).Model(user).
+		Select("*").
+		Omit("CreatedAt").
+		Updates(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepositoryImpl) ClaimMFAStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entities.User{}).
+		Where("id = ? AND (mfa_last_used_step IS NULL OR mfa_last_used_step < ?)", userID, step).
+		Update("mfa_last_used_step", step)
+	return result.RowsAffected == 1, result.Error
 }
 
-func (r *UserRepositoryImpl) Delete(id string) error {
-	return r.db.Delete(&entities.User{}, "id = ?", id).Error
+func (r *UserRepositoryImpl) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&entities.User{}, "id = ?", id).Error
 }
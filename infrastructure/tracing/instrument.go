@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+)
+
+// InstrumentDB starts a span for every statement of db, as a child of the
+// span in the statement context. Query arguments are left out, they carry
+// patient data.
+func (t *Tracing) InstrumentDB(db *gorm.DB) error {
+	return db.Use(gormtracing.NewPlugin(
+		gormtracing.WithTracerProvider(t.Provider),
+		gormtracing.WithoutQueryVariables(),
+		gormtracing.WithoutMetrics(),
+	))
+}
+
+// Middleware starts the span of each request, continuing the trace of the
+// caller when its headers carry one. Requests skip passes over are not
+// traced.
+func (t *Tracing) Middleware(serviceName string, skip func(echo.Context) bool) echo.MiddlewareFunc {
+	return otelecho.Middleware(serviceName,
+		otelecho.WithTracerProvider(t.Provider),
+		otelecho.WithSkipper(skip),
+	)
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"medical-system/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Tracing is the configured OpenTelemetry tracer provider. It is installed
+// as the global provider, which the services start their spans on, unless
+// the exporter is "none".
+type Tracing struct {
+	Provider trace.TracerProvider
+
+	sdk  *sdktrace.TracerProvider
+	file io.Closer
+}
+
+func New(cfg *config.Config) (*Tracing, error) {
+	tracingConfig := cfg.Tracing
+
+	var (
+		exporter sdktrace.SpanExporter
+		file     io.Closer
+		err      error
+	)
+	switch tracingConfig.Exporter {
+	case "none":
+		return &Tracing{Provider: noop.NewTracerProvider()}, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background(), otlpOptions(tracingConfig)...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		var f *os.File
+		f, err = os.OpenFile(tracingConfig.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the trace file: %w", err)
+		}
+		file = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", tracingConfig.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the %s trace exporter: %w", tracingConfig.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(tracingConfig.ServiceName),
+		semconv.DeploymentEnvironment(cfg.Env),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the service for tracing: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracingConfig.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return &Tracing{Provider: provider, sdk: provider, file: file}, nil
+}
+
+// otlpOptions accepts the endpoint as host:port or as a URL with a path
+func otlpOptions(tracingConfig config.TracingConfig) []otlptracehttp.Option {
+	var options []otlptracehttp.Option
+	if strings.Contains(tracingConfig.Endpoint, "://") {
+		options = append(options, otlptracehttp.WithEndpointURL(tracingConfig.Endpoint))
+	} else {
+		options = append(options, otlptracehttp.WithEndpoint(tracingConfig.Endpoint))
+	}
+	if tracingConfig.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return options
+}
+
+// Shutdown exports the spans still buffered and closes the exporter
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.sdk == nil {
+		return nil
+	}
+	err := t.sdk.Shutdown(ctx)
+	if t.file != nil {
+		if closeErr := t.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
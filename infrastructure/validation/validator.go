@@ -0,0 +1,107 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"medical-system/domain/services"
+	"medical-system/domain/tenancy"
+
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/pt"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	detranslations "github.com/go-playground/validator/v10/translations/de"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	estranslations "github.com/go-playground/validator/v10/translations/es"
+	frtranslations "github.com/go-playground/validator/v10/translations/fr"
+	pttranslations "github.com/go-playground/validator/v10/translations/pt"
+	"github.com/google/uuid"
+)
+
+// defaultLanguage is used for requests outside of a tenant and for tenants
+// whose language has no translations
+const defaultLanguage = "en"
+
+// translations registers the field messages of every language a tenant
+// may choose, see TenantSettings.Language
+var translations = map[string]func(*validator.Validate, ut.Translator) error{
+	"en": entranslations.RegisterDefaultTranslations,
+	"es": estranslations.RegisterDefaultTranslations,
+	"fr": frtranslations.RegisterDefaultTranslations,
+	"de": detranslations.RegisterDefaultTranslations,
+	"pt": pttranslations.RegisterDefaultTranslations,
+}
+
+// Validator checks requests against their validate tags, see
+// github.com/go-playground/validator, as the validator of the Echo
+// instance. Its errors name fields as clients send them.
+type Validator struct {
+	validate      *validator.Validate
+	translators   *ut.UniversalTranslator
+	tenantService services.TenantService
+}
+
+func New(tenantService services.TenantService) (*Validator, error) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	translators := ut.New(en.New(), en.New(), es.New(), fr.New(), de.New(), pt.New())
+	for language, register := range translations {
+		translator, _ := translators.GetTranslator(language)
+		if err := register(validate, translator); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Validator{
+		validate:      validate,
+		translators:   translators,
+		tenantService: tenantService,
+	}, nil
+}
+
+// Validate returns validator.ValidationErrors when fields fail their tags
+func (v *Validator) Validate(i interface{}) error {
+	return v.validate.Struct(i)
+}
+
+// Messages returns a message per field that failed validation, in the
+// language of the tenant. It returns false when err is no validation error.
+func (v *Validator) Messages(ctx context.Context, tenantID string, err error) (map[string]string, bool) {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return nil, false
+	}
+
+	translator, _ := v.translators.GetTranslator(v.language(ctx, tenantID))
+	messages := make(map[string]string, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		messages[fieldError.Field()] = fieldError.Translate(translator)
+	}
+	return messages, true
+}
+
+// language looks the tenant up only when tenantID can name one: the ID
+// comes from the body of public requests
+func (v *Validator) language(ctx context.Context, tenantID string) string {
+	if _, err := uuid.Parse(tenantID); err != nil {
+		return defaultLanguage
+	}
+	settings, err := v.tenantService.GetTenantSettings(tenancy.WithTenant(ctx, tenantID))
+	if err != nil || translations[settings.Language] == nil {
+		return defaultLanguage
+	}
+	return settings.Language
+}
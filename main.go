@@ -1,87 +1,79 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"medical-system/app"
 	"medical-system/container"
-	authmiddleware "medical-system/middleware"
-	"medical-system/routes"
-	"strings"
-
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"medical-system/infrastructure/database"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	// Initialize dependency container
 	container := container.NewContainer()
 
-	// Initialize Echo server
-	e := echo.New()
+	// Maintenance commands, e.g. `medical-system audit verify`
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(container, os.Args[1:]))
+	}
 
-	// Initialize tenant middleware
-	var tenantMiddleware *authmiddleware.TenantMiddleware
-	container.DigContainer().Invoke(func(tm *authmiddleware.TenantMiddleware) {
-		tenantMiddleware = tm
-	})
+	cfg, err := container.GetConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	e, err := app.New(container)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
 
-	// Setup routes
-	routes.SetupAuthRoutes(e, container)
-	routes.SetupTenantRoutes(e, container)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Tenant identification middleware (runs for all requests except admin routes)
-	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// Skip tenant middleware for admin routes
-			if strings.HasPrefix(c.Request().URL.Path, "/api/admin/") {
-				return next(c)
-			}
-			return tenantMiddleware.TenantIdentifier()(next)(c)
+	// Start server
+	go func() {
+		log.Printf("🚀 Server starting on port %d", cfg.Server.Port)
+		if err := e.Start(fmt.Sprintf(":%d", cfg.Server.Port)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
 		}
-	})
+	}()
 
-	// Initialize auth middleware
-	var authMiddleware *authmiddleware.AuthMiddleware
-	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware) {
-		authMiddleware = am
-	})
+	// Stop accepting connections on SIGTERM and let in-flight requests
+	// commit before the database pool is closed under them
+	<-ctx.Done()
+	stop()
+	log.Printf("Shutting down, draining requests for up to %s", cfg.Server.ShutdownTimeout)
 
-	// Protected routes with JWT
-	api := e.Group("/api/protected")
-	api.Use(authMiddleware.JWTMiddleware())
-	api.Use(tenantMiddleware.TenantValidator()) // Ensure tenant is valid
-	api.GET("/profile", func(c echo.Context) error {
-		userID := c.Get("user_id").(string)
-		role := c.Get("role").(string)
-		tenantID := c.Get("tenant_id").(string)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	exitCode := 0
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Println("Failed to drain requests:", err)
+		exitCode = 1
+	}
 
-		// Get tenant info if available
-		var tenantName string
-		if tenant, ok := authmiddleware.GetTenantFromContext(c); ok {
-			tenantName = tenant.Name
+	// Flush the spans of the drained requests
+	if tracer, err := container.GetTracing(); err == nil {
+		if err := tracer.Shutdown(shutdownCtx); err != nil {
+			log.Println("Failed to flush the traces:", err)
 		}
+	}
+	cancel()
 
-		return c.JSON(200, map[string]interface{}{
-			"message":     "Profile accessed successfully with JWT",
-			"user_id":     userID,
-			"role":        role,
-			"tenant_id":   tenantID,
-			"tenant_name": tenantName,
-		})
-	})
-
-	// Health check endpoint
-	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(200, map[string]string{"status": "ok"})
-	})
-
-	// Start server
-	log.Println("🚀 Server starting on port 8080")
-	if err := e.Start(":8080"); err != nil {
-		log.Fatal("Failed to start server:", err)
+	if db, err := container.GetDatabase(); err == nil {
+		if err := database.Close(db); err != nil {
+			log.Println("Failed to close the database:", err)
+			exitCode = 1
+		}
 	}
+	log.Println("Server stopped")
+	os.Exit(exitCode)
 }
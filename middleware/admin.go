@@ -45,29 +45,18 @@ func (m *AdminMiddleware) RequireAdmin() echo.MiddlewareFunc {
 	}
 }
 
-// RequireSuperAdmin middleware for system-wide admin operations
-// This could be used for operations that affect multiple tenants
+// RequireSuperAdmin ensures the user is a platform super admin, for
+// operations that affect other tenants. The tenant admin role does not
+// qualify: every clinic has admins of its own.
 func (m *AdminMiddleware) RequireSuperAdmin() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// First check if user is admin
-			role := c.Get("role")
-			if role == nil {
-				return c.JSON(403, map[string]string{"error": "Role information missing"})
-			}
-
-			roleStr, ok := role.(string)
-			if !ok {
-				return c.JSON(403, map[string]string{"error": "Invalid role format"})
+			if userID := c.Get("user_id"); userID == nil {
+				return c.JSON(401, map[string]string{"error": "Authentication required"})
 			}
 
-			// For now, admin role is sufficient. In the future, you might want
-			// a separate super_admin role for system-wide operations
-			if roleStr != "admin" {
-				return c.JSON(403, map[string]string{
-					"error":        "Super admin access required",
-					"current_role": roleStr,
-				})
+			if !IsSuperAdmin(c) {
+				return c.JSON(403, map[string]string{"error": "Super admin access required"})
 			}
 
 			return next(c)
@@ -133,6 +122,13 @@ func IsAdmin(c echo.Context) bool {
 	return ok && roleStr == "admin"
 }
 
+// IsSuperAdmin checks if the current user holds the platform super admin
+// role, as looked up by JWTMiddleware
+func IsSuperAdmin(c echo.Context) bool {
+	superAdmin, _ := c.Get("super_admin").(bool)
+	return superAdmin
+}
+
 // GetCurrentUserRole returns the current user's role
 func GetCurrentUserRole(c echo.Context) (string, bool) {
 	role := c.Get("role")
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"medical-system/domain/entities"
+	"medical-system/domain/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AuditMiddleware records every request of the routes it wraps in the audit log
+type AuditMiddleware struct {
+	recorder services.AuditRecorder
+}
+
+func NewAuditMiddleware(auditService services.AuditService) *AuditMiddleware {
+	return &AuditMiddleware{recorder: auditService}
+}
+
+// Audit records the request as an action on resourceType once the handler
+// has run. It must be installed after JWTMiddleware so the actor is known;
+// requests rejected by RBAC are recorded as denied.
+func (m *AuditMiddleware) Audit(resourceType string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				status = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				}
+			}
+
+			event := AuditActorFromContext(c).Event(resourceType+"."+actionForMethod(c.Request().Method), resourceType, c.Param("id"), outcomeForStatus(status))
+			event.StatusCode = status
+			event.Method = c.Request().Method
+			event.Path = c.Path()
+			m.recorder.Record(c.Request().Context(), event)
+
+			return err
+		}
+	}
+}
+
+// AuditActorFromContext describes the authenticated user of the request for
+// audit events recorded by services
+func AuditActorFromContext(c echo.Context) services.AuditActor {
+	tenantID, _ := GetTenantIDFromContext(c)
+	userID, _ := GetCurrentUserID(c)
+	role, _ := GetCurrentUserRole(c)
+
+	return services.AuditActor{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Role:      role,
+		IPAddress: c.RealIP(),
+		RequestID: GetRequestID(c),
+	}
+}
+
+// GetRequestID returns the ID assigned to the request by the RequestID middleware
+func GetRequestID(c echo.Context) string {
+	if requestID := c.Response().Header().Get(echo.HeaderXRequestID); requestID != "" {
+		return requestID
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+func actionForMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return "read"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "access"
+	}
+}
+
+func outcomeForStatus(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return entities.AuditOutcomeDenied
+	case status >= 400:
+		return entities.AuditOutcomeFailure
+	default:
+		return entities.AuditOutcomeSuccess
+	}
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"medical-system/application/tenants"
+	"medical-system/domain/services"
+	"medical-system/domain/tenancy"
 	"medical-system/infrastructure/auth"
 	"strings"
 
@@ -9,14 +11,23 @@ import (
 )
 
 type AuthMiddleware struct {
-	TokenGen      auth.TokenGenerator
-	tenantService *tenants.TenantApplicationService
+	TokenGen       auth.TokenGenerator
+	tenantService  *tenants.TenantApplicationService
+	rbacEnforcer   *auth.CasbinEnforcer
+	sessionService services.SessionService
 }
 
-func NewAuthMiddleware(tokenGen auth.TokenGenerator, tenantService *tenants.TenantApplicationService) *AuthMiddleware {
+func NewAuthMiddleware(
+	tokenGen auth.TokenGenerator,
+	tenantService *tenants.TenantApplicationService,
+	rbacEnforcer *auth.CasbinEnforcer,
+	sessionService services.SessionService,
+) *AuthMiddleware {
 	return &AuthMiddleware{
-		TokenGen:      tokenGen,
-		tenantService: tenantService,
+		TokenGen:       tokenGen,
+		tenantService:  tenantService,
+		rbacEnforcer:   rbacEnforcer,
+		sessionService: sessionService,
 	}
 }
 
@@ -38,39 +49,111 @@ func (m *AuthMiddleware) JWTMiddleware() echo.MiddlewareFunc {
 				return c.JSON(401, map[string]string{"error": "Invalid or expired token"})
 			}
 
+			// MFA challenge tokens and other special-purpose tokens are not access tokens
+			if tokenType, _ := (*claims)["typ"].(string); tokenType != auth.TokenTypeAccess {
+				return c.JSON(401, map[string]string{"error": "Invalid or expired token"})
+			}
+
+			// The jti claim names the server-side session; revoked sessions
+			// invalidate their access tokens immediately
+			sessionID, ok := (*claims)["jti"].(string)
+			if !ok || sessionID == "" {
+				return c.JSON(401, map[string]string{"error": "Invalid or expired token"})
+			}
+
+			// Everything the request does with the database is confined to
+			// the tenant of the token
+			tenantID, _ := (*claims)["tenant_id"].(string)
+			ctx := tenancy.WithTenant(c.Request().Context(), tenantID)
+
+			if active, err := m.sessionService.IsSessionActive(ctx, sessionID); err != nil || !active {
+				return c.JSON(401, map[string]string{"error": "Token has been revoked"})
+			}
+			c.Set("session_id", sessionID)
+
 			// Set user info in context
-			if userID, ok := (*claims)["user_id"].(string); ok {
+			userID, _ := (*claims)["user_id"].(string)
+			if userID != "" {
 				c.Set("user_id", userID)
 			}
-			if tenantID, ok := (*claims)["tenant_id"].(string); ok {
+			if tenantID != "" {
 				c.Set("tenant_id", tenantID)
 
 				// Load tenant information if available (only if tenantService is not nil)
 				if m.tenantService != nil {
-					if tenant, err := m.tenantService.GetTenantBySlug(tenantID); err == nil {
+					if tenant, err := m.tenantService.ResolveTenant(ctx, tenantID); err == nil {
 						c.Set("tenant", tenant)
 					}
 				}
 			}
-			if role, ok := (*claims)["role"].(string); ok {
-				c.Set("role", role)
-			}
+			// The admin checks read the role; roles are granted and removed
+			// after the token was issued, so the enforcer has the last word
+			claimedRole, _ := (*claims)["role"].(string)
+			c.Set("role", m.currentRole(userID, tenantID, claimedRole))
+			c.Set("super_admin", m.rbacEnforcer != nil && m.rbacEnforcer.IsSuperAdmin(userID))
+
+			ctx = services.WithAuditActor(ctx, AuditActorFromContext(c))
+			c.SetRequest(c.Request().WithContext(ctx))
 
 			return next(c)
 		}
 	}
 }
 
+// currentRole is "admin" while the user holds the admin role in the tenant,
+// otherwise the role of the token as long as the user still holds it
+func (m *AuthMiddleware) currentRole(userID, tenantID, claimedRole string) string {
+	if m.rbacEnforcer == nil {
+		return claimedRole
+	}
+
+	roles := m.rbacEnforcer.GetUserRoles(userID, tenantID)
+	current := ""
+	for _, role := range roles {
+		if role == "admin" {
+			return role
+		}
+		if role == claimedRole || current == "" {
+			current = role
+		}
+	}
+	return current
+}
+
 func (m *AuthMiddleware) RBACMiddleware(resource, action string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			userID := c.Get("user_id")
-			if userID == nil {
+			userID, ok := GetCurrentUserID(c)
+			if !ok || userID == "" {
 				return c.JSON(403, map[string]string{"error": "Access denied - not authenticated"})
 			}
 
-			// For now, just check if user is authenticated
-			// TODO: Implement full RBAC with Casbin
+			tenantID, ok := GetTenantIDFromContext(c)
+			if !ok || tenantID == "" {
+				return c.JSON(403, map[string]string{"error": "Access denied - tenant context missing"})
+			}
+			// Super admins acting for another tenant keep the permissions of
+			// their own
+			if homeTenantID, ok := c.Get("home_tenant_id").(string); ok && homeTenantID != "" {
+				tenantID = homeTenantID
+			}
+
+			if m.rbacEnforcer == nil {
+				return c.JSON(500, map[string]string{"error": "Authorization is not configured"})
+			}
+
+			allowed, err := m.rbacEnforcer.CheckPermission(userID, tenantID, resource, action)
+			if err != nil {
+				return c.JSON(500, map[string]string{"error": "Failed to evaluate permissions"})
+			}
+			if !allowed {
+				return c.JSON(403, map[string]string{
+					"error":    "Access denied - insufficient permissions",
+					"resource": resource,
+					"action":   action,
+				})
+			}
+
 			return next(c)
 		}
 	}
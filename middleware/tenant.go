@@ -3,79 +3,196 @@ package middleware
 import (
 	"medical-system/application/tenants"
 	"medical-system/domain/entities"
+	"medical-system/domain/services"
+	"medical-system/domain/tenancy"
 	"strings"
 
 	"github.com/labstack/echo/v4"
 )
 
+// TenantSource is a place a request may name its tenant in
+type TenantSource string
+
+const (
+	// TenantFromToken is the tenant_id claim, set by JWTMiddleware
+	TenantFromToken TenantSource = "token"
+	// TenantFromHost is a verified custom domain, e.g. portal.clinic1.com,
+	// else the subdomain, e.g. clinic1.medical-system.com -> clinic1. Hosts
+	// that name no tenant are ignored.
+	TenantFromHost TenantSource = "host"
+	// TenantFromHeader is the X-Tenant-ID header
+	TenantFromHeader TenantSource = "header"
+	// TenantFromQuery is the tenant_id query parameter
+	TenantFromQuery TenantSource = "query"
+	// TenantFromPath is the tenant_slug path parameter
+	TenantFromPath TenantSource = "path"
+)
+
+// Headers of a super admin acting for another tenant
+const (
+	HeaderActAsTenant = "X-Act-As-Tenant"
+	HeaderActAsReason = "X-Act-As-Reason"
+)
+
+// TenantPolicy says where the requests of a route group may name their
+// tenant. The first source, in order, that names a tenant decides. When the
+// token decides, every other source naming a tenant must name the same one:
+// a request cannot reach past the tenant its token belongs to.
+type TenantPolicy struct {
+	Sources []TenantSource
+	// AllowActAs lets super admins act for another tenant, see Identify
+	AllowActAs bool
+}
+
+var (
+	// PublicTenantPolicy is for requests without a token
+	PublicTenantPolicy = TenantPolicy{
+		Sources: []TenantSource{TenantFromHost, TenantFromHeader, TenantFromQuery, TenantFromPath},
+	}
+	// AuthenticatedTenantPolicy is for routes behind JWTMiddleware
+	AuthenticatedTenantPolicy = TenantPolicy{
+		Sources:    []TenantSource{TenantFromToken, TenantFromHost, TenantFromHeader, TenantFromQuery},
+		AllowActAs: true,
+	}
+)
+
 type TenantMiddleware struct {
 	tenantService *tenants.TenantApplicationService
+	scopeRecorder tenancy.ScopeRecorder
 }
 
-func NewTenantMiddleware(tenantService *tenants.TenantApplicationService) *TenantMiddleware {
+func NewTenantMiddleware(tenantService *tenants.TenantApplicationService, scopeRecorder tenancy.ScopeRecorder) *TenantMiddleware {
 	return &TenantMiddleware{
 		tenantService: tenantService,
+		scopeRecorder: scopeRecorder,
 	}
 }
 
-// TenantIdentifier middleware extracts tenant information from various sources
+// TenantIdentifier identifies the tenant of requests without a token
 func (m *TenantMiddleware) TenantIdentifier() echo.MiddlewareFunc {
+	return m.Identify(PublicTenantPolicy)
+}
+
+// Identify sets the tenant of the request, as named by the sources of the
+// policy, in the context. It must be installed after JWTMiddleware when the
+// policy includes the token.
+//
+// A super admin acts for another tenant by sending its ID, slug or domain in
+// X-Act-As-Tenant and why in X-Act-As-Reason. The target then takes the
+// place of the token's tenant, the request runs in a system scope narrowed
+// to it, which the audit log records with the reason, and RBAC still checks
+// the permissions the user holds in their own tenant.
+func (m *TenantMiddleware) Identify(policy TenantPolicy) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			tenantID := m.extractTenantID(c)
+			ctx := c.Request().Context()
+
+			var (
+				tenantID   string
+				tenant     *entities.Tenant
+				fromToken  bool
+				acting     bool
+				homeTenant string
+			)
+			if ref := c.Request().Header.Get(HeaderActAsTenant); ref != "" {
+				if !policy.AllowActAs || !IsSuperAdmin(c) {
+					return c.JSON(403, map[string]string{"error": "Acting as another tenant requires super admin access"})
+				}
+				reason := strings.TrimSpace(c.Request().Header.Get(HeaderActAsReason))
+				if reason == "" {
+					return c.JSON(400, map[string]string{"error": HeaderActAsReason + " is required"})
+				}
+				target, err := m.tenantService.ResolveTenant(ctx, ref)
+				if err != nil {
+					return c.JSON(404, map[string]string{"error": "Tenant not found"})
+				}
+
+				homeTenant, _ = GetTenantIDFromContext(c)
+				tenantID, tenant, fromToken, acting = target.ID, target, true, true
+				ctx = tenancy.WithTenantAccess(ctx, m.scopeRecorder, target.ID, "act as tenant "+target.ID+": "+reason)
+			}
+
+			for _, source := range policy.Sources {
+				if source == TenantFromToken && acting {
+					continue
+				}
+				ref := m.reference(c, source)
+				if ref == "" {
+					continue
+				}
+
+				id := ref
+				named, err := m.tenantService.ResolveTenant(ctx, ref)
+				switch {
+				case err == nil:
+					id = named.ID
+				case source == TenantFromHost:
+					continue
+				}
+
+				switch {
+				case tenantID == "":
+					tenantID, tenant, fromToken = id, named, source == TenantFromToken
+				case fromToken && id != tenantID:
+					return c.JSON(403, map[string]string{"error": "Tenant does not match the token"})
+				}
+			}
+
+			// Closed and suspended tenants serve nobody but super admins
+			if tenant != nil && !tenant.IsActive && !acting {
+				return c.JSON(403, map[string]string{"error": "Tenant is not active"})
+			}
 
 			if tenantID != "" {
-				// Set tenant ID in context for later use
 				c.Set("tenant_id", tenantID)
-
-				// Try to load tenant information if available
-				if tenant, err := m.tenantService.GetTenantBySlug(tenantID); err == nil {
+				if tenant != nil {
 					c.Set("tenant", tenant)
 				}
 			}
+			if acting {
+				c.Set("home_tenant_id", homeTenant)
+				ctx = services.WithAuditActor(ctx, AuditActorFromContext(c))
+				c.SetRequest(c.Request().WithContext(ctx))
+			}
 
 			return next(c)
 		}
 	}
 }
 
-// extractTenantID extracts tenant identifier from various sources
-func (m *TenantMiddleware) extractTenantID(c echo.Context) string {
-	// Priority order for tenant identification:
-	// 1. JWT token claims (already set by auth middleware)
-	// 2. Subdomain (e.g., clinic1.medical-system.com -> clinic1)
-	// 3. Header (X-Tenant-ID)
-	// 4. Query parameter (tenant_id)
-	// 5. Path parameter (tenant_slug)
-
-	// 1. From JWT claims (if auth middleware already ran)
-	if tenantID := c.Get("tenant_id"); tenantID != nil {
-		if tid, ok := tenantID.(string); ok && tid != "" {
-			return tid
-		}
-	}
-
-	// 2. From subdomain
-	if tenantID := m.extractFromSubdomain(c); tenantID != "" {
-		return tenantID
-	}
-
-	// 3. From header
-	if tenantID := c.Request().Header.Get("X-Tenant-ID"); tenantID != "" {
+// reference returns the tenant ID, slug or domain source carries, if any
+func (m *TenantMiddleware) reference(c echo.Context, source TenantSource) string {
+	switch source {
+	case TenantFromToken:
+		tenantID, _ := GetTenantIDFromContext(c)
 		return tenantID
+	case TenantFromHost:
+		if tenantID := m.extractFromCustomDomain(c); tenantID != "" {
+			return tenantID
+		}
+		return m.extractFromSubdomain(c)
+	case TenantFromHeader:
+		return c.Request().Header.Get("X-Tenant-ID")
+	case TenantFromQuery:
+		return c.QueryParam("tenant_id")
+	case TenantFromPath:
+		return c.Param("tenant_slug")
 	}
+	return ""
+}
 
-	// 4. From query parameter
-	if tenantID := c.QueryParam("tenant_id"); tenantID != "" {
-		return tenantID
+// extractFromCustomDomain returns the tenant that verified the request host
+func (m *TenantMiddleware) extractFromCustomDomain(c echo.Context) string {
+	host, err := services.NormalizeDomain(c.Request().Host)
+	if err != nil {
+		return ""
 	}
 
-	// 5. From path parameter (for tenant-specific routes)
-	if tenantID := c.Param("tenant_slug"); tenantID != "" {
-		return tenantID
+	tenant, err := m.tenantService.ResolveTenant(c.Request().Context(), host)
+	if err != nil {
+		return ""
 	}
-
-	return ""
+	return tenant.ID
 }
 
 // extractFromSubdomain extracts tenant ID from subdomain
@@ -127,8 +244,9 @@ func (m *TenantMiddleware) TenantValidator() echo.MiddlewareFunc {
 				return c.JSON(400, map[string]string{"error": "Invalid tenant ID"})
 			}
 
-			// Check if tenant exists and is active
-			tenant, err := m.tenantService.GetTenantBySlug(tid)
+			// Check if tenant exists and is active. Tokens carry the tenant
+			// ID, the other sources may carry its slug.
+			tenant, err := m.tenantService.ResolveTenant(c.Request().Context(), tid)
 			if err != nil {
 				return c.JSON(404, map[string]string{"error": "Tenant not found"})
 			}
@@ -138,6 +256,7 @@ func (m *TenantMiddleware) TenantValidator() echo.MiddlewareFunc {
 			}
 
 			// Set validated tenant in context
+			c.Set("tenant_id", tenant.ID)
 			c.Set("tenant", tenant)
 
 			return next(c)
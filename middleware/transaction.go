@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"medical-system/infrastructure/database"
+
+	"github.com/labstack/echo/v4"
+)
+
+// commitFailedBody replaces the response of a request whose transaction
+// failed to commit
+var commitFailedBody = []byte(`{"error":"Failed to save the changes"}` + "\n")
+
+// Transaction runs the database work of each request in one transaction,
+// which carries the request's tenant to the row-level security policies.
+// It is committed just before the response is written, and only for 2xx and
+// 3xx responses, so clients never see a success that was not stored; when
+// the commit fails the handler's body is replaced by an error. Writes that
+// must outlive a failed request, like audit events and login throttles, run
+// outside of it (see database.Detached).
+func Transaction() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, tx := database.WithTransaction(c.Request().Context())
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			response := c.Response()
+			writer := &commitWriter{ResponseWriter: response.Writer}
+			response.Writer = writer
+			response.Before(func() {
+				if err := tx.End(succeeded(response.Status)); err != nil {
+					log.Printf("⚠️  Failed to commit the transaction of %s %s: %v", c.Request().Method, c.Path(), err)
+					response.Status = http.StatusInternalServerError
+					response.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+					response.Header().Del(echo.HeaderContentLength)
+					writer.failed = true
+				}
+			})
+
+			err := next(c)
+			if endErr := tx.End(err == nil && succeeded(response.Status)); endErr != nil && err == nil {
+				return endErr
+			}
+			return err
+		}
+	}
+}
+
+func succeeded(status int) bool {
+	return status >= http.StatusOK && status < http.StatusBadRequest
+}
+
+// commitWriter writes commitFailedBody in place of the handler's body once
+// the commit failed
+type commitWriter struct {
+	http.ResponseWriter
+	failed  bool
+	written bool
+}
+
+func (w *commitWriter) Write(body []byte) (int, error) {
+	if !w.failed {
+		return w.ResponseWriter.Write(body)
+	}
+	if !w.written {
+		w.written = true
+		if _, err := w.ResponseWriter.Write(commitFailedBody); err != nil {
+			return 0, err
+		}
+	}
+	// The handler's body is dropped
+	return len(body), nil
+}
+
+func (w *commitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
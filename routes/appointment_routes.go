@@ -0,0 +1,260 @@
+package routes
+
+import (
+	"errors"
+
+	"medical-system/application/appointments"
+	"medical-system/container"
+	"medical-system/domain/services"
+	authmiddleware "medical-system/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetupAppointmentRoutes(e *echo.Echo, container *container.Container) {
+	appointmentService, err := container.GetAppointmentService()
+	if err != nil {
+		panic("Failed to get appointment service: " + err.Error())
+	}
+
+	var authMiddleware *authmiddleware.AuthMiddleware
+	var auditMiddleware *authmiddleware.AuditMiddleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware, aud *authmiddleware.AuditMiddleware, tm *authmiddleware.TenantMiddleware) {
+		authMiddleware = am
+		auditMiddleware = aud
+		tenantMiddleware = tm
+	})
+
+	handler := NewAppointmentHandler(appointmentService)
+	scheduleRead := authMiddleware.RBACMiddleware("schedule", "read")
+	scheduleWrite := authMiddleware.RBACMiddleware("schedule", "write")
+	appointmentRead := authMiddleware.RBACMiddleware("appointment", "read")
+	appointmentWrite := authMiddleware.RBACMiddleware("appointment", "write")
+
+	// Rooms, working hours and exceptions
+	scheduling := e.Group("/api/protected")
+	scheduling.Use(authMiddleware.JWTMiddleware())
+	scheduling.Use(auditMiddleware.Audit("schedule"))
+	scheduling.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+
+	scheduling.GET("/rooms", handler.ListRooms, scheduleRead)
+	scheduling.POST("/rooms", handler.CreateRoom, scheduleWrite)
+
+	scheduling.GET("/providers/:id/schedules", handler.ListSchedules, scheduleRead)
+	scheduling.POST("/providers/:id/schedules", handler.AddSchedule, scheduleWrite)
+	scheduling.DELETE("/providers/:id/schedules/:scheduleId", handler.DeleteSchedule, scheduleWrite)
+	scheduling.GET("/providers/:id/slots", handler.AvailableSlots, appointmentRead)
+
+	scheduling.GET("/schedule-exceptions", handler.ListExceptions, scheduleRead)
+	scheduling.POST("/schedule-exceptions", handler.AddException, scheduleWrite)
+	scheduling.DELETE("/schedule-exceptions/:id", handler.DeleteException, scheduleWrite)
+
+	// Appointments
+	appointmentRoutes := e.Group("/api/protected/appointments")
+	appointmentRoutes.Use(authMiddleware.JWTMiddleware())
+	appointmentRoutes.Use(auditMiddleware.Audit("appointment"))
+	appointmentRoutes.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+
+	appointmentRoutes.GET("", handler.ListAppointments, appointmentRead)
+	appointmentRoutes.POST("", handler.BookAppointment, appointmentWrite)
+	appointmentRoutes.GET("/:id", handler.GetAppointment, appointmentRead)
+	appointmentRoutes.PUT("/:id/schedule", handler.RescheduleAppointment, appointmentWrite)
+	appointmentRoutes.POST("/:id/status", handler.ChangeStatus, appointmentWrite)
+}
+
+type AppointmentHandler struct {
+	appointmentService *appointments.AppointmentApplicationService
+}
+
+func NewAppointmentHandler(appointmentService *appointments.AppointmentApplicationService) *AppointmentHandler {
+	return &AppointmentHandler{appointmentService: appointmentService}
+}
+
+func (h *AppointmentHandler) ListRooms(c echo.Context) error {
+	response, err := h.appointmentService.ListRooms(c.Request().Context())
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to list rooms"})
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AppointmentHandler) CreateRoom(c echo.Context) error {
+	var req appointments.CreateRoomRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.CreateRoom(c.Request().Context(), req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *AppointmentHandler) ListSchedules(c echo.Context) error {
+	response, err := h.appointmentService.ListSchedules(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to list schedules"})
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AppointmentHandler) AddSchedule(c echo.Context) error {
+	var req appointments.ScheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.AddSchedule(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *AppointmentHandler) DeleteSchedule(c echo.Context) error {
+	if err := h.appointmentService.DeleteSchedule(c.Request().Context(), c.Param("scheduleId")); err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, map[string]string{"message": "Schedule deleted successfully"})
+}
+
+func (h *AppointmentHandler) AvailableSlots(c echo.Context) error {
+	response, err := h.appointmentService.AvailableSlots(c.Request().Context(), c.Param("id"), c.QueryParam("date"))
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AppointmentHandler) ListExceptions(c echo.Context) error {
+	var req appointments.ListScheduleExceptionsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.ListExceptions(c.Request().Context(), req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AppointmentHandler) AddException(c echo.Context) error {
+	var req appointments.ScheduleExceptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.AddException(c.Request().Context(), req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *AppointmentHandler) DeleteException(c echo.Context) error {
+	if err := h.appointmentService.DeleteException(c.Request().Context(), c.Param("id")); err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, map[string]string{"message": "Schedule exception deleted successfully"})
+}
+
+func (h *AppointmentHandler) ListAppointments(c echo.Context) error {
+	var req appointments.ListAppointmentsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.ListAppointments(c.Request().Context(), req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AppointmentHandler) BookAppointment(c echo.Context) error {
+	userID, _ := authmiddleware.GetCurrentUserID(c)
+
+	var req appointments.BookAppointmentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.BookAppointment(c.Request().Context(), userID, req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *AppointmentHandler) GetAppointment(c echo.Context) error {
+	response, err := h.appointmentService.GetAppointment(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AppointmentHandler) RescheduleAppointment(c echo.Context) error {
+	var req appointments.RescheduleAppointmentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.RescheduleAppointment(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AppointmentHandler) ChangeStatus(c echo.Context) error {
+	var req appointments.ChangeStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.appointmentService.ChangeStatus(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return appointmentError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+// appointmentError maps scheduling errors to HTTP responses
+func appointmentError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrAppointmentNotFound),
+		errors.Is(err, services.ErrPatientNotFound),
+		errors.Is(err, services.ErrProviderNotFound),
+		errors.Is(err, services.ErrRoomNotFound),
+		errors.Is(err, services.ErrScheduleNotFound),
+		errors.Is(err, services.ErrScheduleExceptionNotFound):
+		return c.JSON(404, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrAppointmentConflict),
+		errors.Is(err, services.ErrScheduleOverlap),
+		errors.Is(err, services.ErrInvalidStatusTransition):
+		return c.JSON(409, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrOutsideWorkingHours),
+		errors.Is(err, services.ErrProviderUnavailable):
+		return c.JSON(422, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+}
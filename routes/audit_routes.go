@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"errors"
+
+	"medical-system/application/audit"
+	"medical-system/container"
+	authmiddleware "medical-system/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetupAuditRoutes(e *echo.Echo, container *container.Container) {
+	auditService, err := container.GetAuditService()
+	if err != nil {
+		panic("Failed to get audit service: " + err.Error())
+	}
+
+	var authMiddleware *authmiddleware.AuthMiddleware
+	var adminMiddleware *authmiddleware.AdminMiddleware
+	var auditMiddleware *authmiddleware.AuditMiddleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware, adm *authmiddleware.AdminMiddleware, aud *authmiddleware.AuditMiddleware, tm *authmiddleware.TenantMiddleware) {
+		authMiddleware = am
+		adminMiddleware = adm
+		auditMiddleware = aud
+		tenantMiddleware = tm
+	})
+
+	handler := NewAuditHandler(auditService)
+
+	// Reading the audit log is itself audited
+	tenantAdmin := e.Group("/api/tenant-admin/audit-events")
+	tenantAdmin.Use(authMiddleware.JWTMiddleware())
+	tenantAdmin.Use(auditMiddleware.Audit("audit_log"))
+	tenantAdmin.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+	tenantAdmin.Use(adminMiddleware.RequireTenantAdmin())
+
+	tenantAdmin.GET("", handler.SearchEvents)
+	tenantAdmin.GET("/export", handler.ExportCSV)
+	tenantAdmin.GET("/verify", handler.Verify)
+}
+
+type AuditHandler struct {
+	auditService *audit.AuditApplicationService
+}
+
+func NewAuditHandler(auditService *audit.AuditApplicationService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+func (h *AuditHandler) SearchEvents(c echo.Context) error {
+	var req audit.SearchAuditEventsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.auditService.SearchEvents(c.Request().Context(), req)
+	if err != nil {
+		return auditError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AuditHandler) ExportCSV(c echo.Context) error {
+	var req audit.SearchAuditEventsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	header.Set(echo.HeaderContentDisposition, `attachment; filename="audit-events.csv"`)
+
+	if err := h.auditService.ExportCSV(c.Request().Context(), req, c.Response()); err != nil {
+		// Once rows were streamed a failure can only cut the file short
+		if c.Response().Committed {
+			return err
+		}
+		header.Del(echo.HeaderContentType)
+		header.Del(echo.HeaderContentDisposition)
+		return auditError(c, err)
+	}
+	return nil
+}
+
+func (h *AuditHandler) Verify(c echo.Context) error {
+	response, err := h.auditService.Verify(c.Request().Context())
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to verify the audit log"})
+	}
+
+	return c.JSON(200, response)
+}
+
+func auditError(c echo.Context, err error) error {
+	if errors.Is(err, audit.ErrInvalidTime) {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(500, map[string]string{"error": "Failed to query the audit log"})
+}
@@ -1,8 +1,14 @@
 package routes
 
 import (
+	"errors"
+	"math"
+	"strconv"
+
 	"medical-system/application/auth"
 	"medical-system/container"
+	"medical-system/domain/services"
+	infraauth "medical-system/infrastructure/auth"
 	authmiddleware "medical-system/middleware"
 
 	"github.com/labstack/echo/v4"
@@ -16,20 +22,67 @@ func SetupAuthRoutes(e *echo.Echo, container *container.Container) {
 
 	// Initialize auth middleware
 	var authMiddleware *authmiddleware.AuthMiddleware
-	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware) {
+	var adminMiddleware *authmiddleware.AdminMiddleware
+	var auditMiddleware *authmiddleware.AuditMiddleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware, adm *authmiddleware.AdminMiddleware, aud *authmiddleware.AuditMiddleware, tm *authmiddleware.TenantMiddleware) {
 		authMiddleware = am
+		adminMiddleware = adm
+		auditMiddleware = aud
+		tenantMiddleware = tm
 	})
 
 	handler := NewAuthHandler(authService)
 
+	tokenGen, err := container.GetTokenGen()
+	if err != nil {
+		panic("Failed to get token generator: " + err.Error())
+	}
+
+	cfg, err := container.GetConfig()
+	if err != nil {
+		panic("Failed to get config: " + err.Error())
+	}
+
+	// Public verification keys for other services (asymmetric signing only)
+	e.GET("/.well-known/jwks.json", func(c echo.Context) error {
+		provider, ok := tokenGen.(infraauth.JWKSProvider)
+		if !ok {
+			return c.JSON(404, map[string]string{"error": "JWKS is not available with symmetric signing"})
+		}
+		return c.JSON(200, provider.JWKS())
+	})
+
 	// Public routes
 	e.POST("/api/auth/login", handler.Login)
-	e.POST("/api/auth/register", handler.Register)
+	if cfg.Features.UserRegistration {
+		e.POST("/api/auth/register", handler.Register)
+	}
+	e.POST("/api/auth/password/forgot", handler.RequestPasswordReset)
+	e.POST("/api/auth/password/reset", handler.ResetPassword)
+	e.POST("/api/auth/refresh", handler.Refresh)
+	e.POST("/api/auth/logout", handler.Logout, authMiddleware.JWTMiddleware(), auditMiddleware.Audit("session"))
+	e.POST("/api/auth/mfa/enroll", handler.BeginMFAEnrollmentWithChallenge)
+	e.POST("/api/auth/mfa/verify", handler.VerifyMFA)
 	// Protected routes
 	protected := e.Group("/api/protected")
 	protected.Use(authMiddleware.JWTMiddleware())
-	protected.Use(authMiddleware.RBACMiddleware("profile", "write"))
-	protected.PUT("/profile", handler.UpdateProfile)
+	protected.Use(auditMiddleware.Audit("profile"))
+	protected.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+	protected.PUT("/profile", handler.UpdateProfile, authMiddleware.RBACMiddleware("profile", "write"))
+	protected.PUT("/password", handler.ChangePassword, authMiddleware.RBACMiddleware("profile", "write"))
+	protected.POST("/mfa/enroll", handler.BeginMFAEnrollment, authMiddleware.RBACMiddleware("profile", "write"))
+	protected.POST("/mfa/activate", handler.ActivateMFA, authMiddleware.RBACMiddleware("profile", "write"))
+	protected.POST("/mfa/disable", handler.DisableMFA, authMiddleware.RBACMiddleware("profile", "write"))
+
+	// Tenant admin routes
+	tenantAdmin := e.Group("/api/tenant-admin")
+	tenantAdmin.Use(authMiddleware.JWTMiddleware())
+	tenantAdmin.Use(auditMiddleware.Audit("user"))
+	tenantAdmin.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+	tenantAdmin.Use(adminMiddleware.RequireTenantAdmin())
+	tenantAdmin.DELETE("/users/:id/sessions", handler.RevokeUserSessions)
+	tenantAdmin.POST("/users/:id/unlock", handler.UnlockUser)
 }
 
 type AuthHandler struct {
@@ -45,10 +98,70 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(400, map[string]string{"error": "Invalid request"})
 	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, req.TenantID)
+	}
+
+	req.UserAgent = c.Request().UserAgent()
+	req.IPAddress = c.RealIP()
+	req.RequestID = authmiddleware.GetRequestID(c)
+
+	response, err := h.authService.Login(c.Request().Context(), req)
+	if err != nil {
+		return loginError(c, err, "Invalid credentials")
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	var req auth.RefreshRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+	req.UserAgent = c.Request().UserAgent()
+	req.IPAddress = c.RealIP()
 
-	response, err := h.authService.Login(req)
+	response, err := h.authService.Refresh(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(401, map[string]string{"error": "Invalid credentials"})
+		return c.JSON(401, map[string]string{"error": "Invalid or expired refresh token"})
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AuthHandler) Logout(c echo.Context) error {
+	sessionID := c.Get("session_id").(string)
+
+	if err := h.authService.Logout(c.Request().Context(), sessionID); err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to log out"})
+	}
+
+	return c.JSON(200, map[string]string{"message": "Logged out successfully"})
+}
+
+func (h *AuthHandler) RevokeUserSessions(c echo.Context) error {
+	response, err := h.authService.RevokeUserSessions(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return c.JSON(404, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(500, map[string]string{"error": "Failed to revoke sessions"})
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AuthHandler) UnlockUser(c echo.Context) error {
+	response, err := h.authService.UnlockUser(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			return c.JSON(404, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(500, map[string]string{"error": "Failed to unlock user"})
 	}
 
 	return c.JSON(200, response)
@@ -59,8 +172,11 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(400, map[string]string{"error": "Invalid request"})
 	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, req.TenantID)
+	}
 
-	response, err := h.authService.Register(req)
+	response, err := h.authService.Register(c.Request().Context(), req)
 	if err != nil {
 		return c.JSON(400, map[string]string{"error": err.Error()})
 	}
@@ -75,8 +191,11 @@ func (h *AuthHandler) UpdateProfile(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(400, map[string]string{"error": "Invalid request"})
 	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
 
-	user, err := h.authService.UpdateProfile(userID, req)
+	user, err := h.authService.UpdateProfile(c.Request().Context(), userID, req)
 	if err != nil {
 		return c.JSON(400, map[string]string{"error": err.Error()})
 	}
@@ -86,3 +205,163 @@ func (h *AuthHandler) UpdateProfile(c echo.Context) error {
 		"user":    user,
 	})
 }
+
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req auth.ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+
+	sessionID, _ := c.Get("session_id").(string)
+	if err := h.authService.ChangePassword(c.Request().Context(), userID, sessionID, req); err != nil {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(200, map[string]string{"message": "Password changed successfully"})
+}
+
+func (h *AuthHandler) RequestPasswordReset(c echo.Context) error {
+	var req auth.PasswordResetRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, req.TenantID)
+	}
+	req.IPAddress = c.RealIP()
+
+	if err := h.authService.RequestPasswordReset(c.Request().Context(), req); err != nil {
+		var lockout *services.LockoutError
+		if errors.As(err, &lockout) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+			return c.JSON(429, map[string]string{"error": "Too many password reset requests, try again later"})
+		}
+		return c.JSON(500, map[string]string{"error": "Failed to process password reset request"})
+	}
+
+	// Same response whether or not the account exists
+	return c.JSON(202, map[string]string{"message": "If the account exists, a password reset email has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c echo.Context) error {
+	var req auth.ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+
+	if err := h.authService.ResetPassword(c.Request().Context(), req); err != nil {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(200, map[string]string{"message": "Password reset successfully"})
+}
+
+func (h *AuthHandler) BeginMFAEnrollment(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	enrollment, err := h.authService.BeginMFAEnrollment(c.Request().Context(), userID)
+	if err != nil {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(200, enrollment)
+}
+
+func (h *AuthHandler) BeginMFAEnrollmentWithChallenge(c echo.Context) error {
+	var req auth.MFAChallengeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+
+	enrollment, err := h.authService.BeginMFAEnrollmentWithChallenge(c.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidMFAChallenge) {
+			return c.JSON(401, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(200, enrollment)
+}
+
+func (h *AuthHandler) ActivateMFA(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req auth.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+
+	response, err := h.authService.ActivateMFA(c.Request().Context(), userID, req)
+	if err != nil {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *AuthHandler) DisableMFA(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req auth.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+
+	if err := h.authService.DisableMFA(c.Request().Context(), userID, req); err != nil {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(200, map[string]string{"message": "Multi-factor authentication disabled"})
+}
+
+func (h *AuthHandler) VerifyMFA(c echo.Context) error {
+	var req auth.MFAVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+	req.UserAgent = c.Request().UserAgent()
+	req.IPAddress = c.RealIP()
+	req.RequestID = authmiddleware.GetRequestID(c)
+
+	response, err := h.authService.VerifyMFA(c.Request().Context(), req)
+	if err != nil {
+		return loginError(c, err, err.Error())
+	}
+
+	return c.JSON(200, response)
+}
+
+// loginError maps failed login attempts to HTTP responses
+func loginError(c echo.Context, err error, message string) error {
+	var lockout *services.LockoutError
+	switch {
+	case errors.As(err, &lockout):
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+		return c.JSON(429, map[string]string{"error": lockout.Error()})
+	case errors.Is(err, services.ErrAccountDisabled):
+		return c.JSON(403, map[string]string{"error": "Account is disabled"})
+	default:
+		return c.JSON(401, map[string]string{"error": message})
+	}
+}
@@ -0,0 +1,139 @@
+package routes
+
+import (
+	"errors"
+
+	"medical-system/application/encounters"
+	"medical-system/container"
+	"medical-system/domain/services"
+	authmiddleware "medical-system/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetupEncounterRoutes(e *echo.Echo, container *container.Container) {
+	encounterService, err := container.GetEncounterService()
+	if err != nil {
+		panic("Failed to get encounter service: " + err.Error())
+	}
+
+	var authMiddleware *authmiddleware.AuthMiddleware
+	var auditMiddleware *authmiddleware.AuditMiddleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware, aud *authmiddleware.AuditMiddleware, tm *authmiddleware.TenantMiddleware) {
+		authMiddleware = am
+		auditMiddleware = aud
+		tenantMiddleware = tm
+	})
+
+	handler := NewEncounterHandler(encounterService)
+
+	encounterRoutes := e.Group("/api/protected")
+	encounterRoutes.Use(authMiddleware.JWTMiddleware())
+	encounterRoutes.Use(auditMiddleware.Audit("encounter"))
+	encounterRoutes.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+
+	encounterRoutes.GET("/patients/:id/encounters", handler.ListPatientEncounters, authMiddleware.RBACMiddleware("encounter", "read"))
+	encounterRoutes.POST("/encounters", handler.CreateEncounter, authMiddleware.RBACMiddleware("encounter", "write"))
+	encounterRoutes.GET("/encounters/:id", handler.GetEncounter, authMiddleware.RBACMiddleware("encounter", "read"))
+	encounterRoutes.PUT("/encounters/:id", handler.UpdateDraft, authMiddleware.RBACMiddleware("encounter", "write"))
+	encounterRoutes.POST("/encounters/:id/sign", handler.SignEncounter, authMiddleware.RBACMiddleware("encounter", "sign"))
+	encounterRoutes.POST("/encounters/:id/amendments", handler.AmendEncounter, authMiddleware.RBACMiddleware("encounter", "sign"))
+}
+
+type EncounterHandler struct {
+	encounterService *encounters.EncounterApplicationService
+}
+
+func NewEncounterHandler(encounterService *encounters.EncounterApplicationService) *EncounterHandler {
+	return &EncounterHandler{encounterService: encounterService}
+}
+
+func (h *EncounterHandler) ListPatientEncounters(c echo.Context) error {
+	response, err := h.encounterService.ListPatientEncounters(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return encounterError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *EncounterHandler) CreateEncounter(c echo.Context) error {
+	userID, _ := authmiddleware.GetCurrentUserID(c)
+
+	var req encounters.EncounterRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.encounterService.CreateEncounter(c.Request().Context(), userID, req)
+	if err != nil {
+		return encounterError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *EncounterHandler) GetEncounter(c echo.Context) error {
+	response, err := h.encounterService.GetEncounter(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return encounterError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *EncounterHandler) UpdateDraft(c echo.Context) error {
+	var req encounters.EncounterRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.encounterService.UpdateDraft(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return encounterError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *EncounterHandler) SignEncounter(c echo.Context) error {
+	response, err := h.encounterService.SignEncounter(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return encounterError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *EncounterHandler) AmendEncounter(c echo.Context) error {
+	var req encounters.AmendEncounterRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.encounterService.AmendEncounter(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return encounterError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+// encounterError maps clinical note errors to HTTP responses
+func encounterError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrEncounterNotFound),
+		errors.Is(err, services.ErrPatientNotFound),
+		errors.Is(err, services.ErrProviderNotFound),
+		errors.Is(err, services.ErrAppointmentNotFound):
+		return c.JSON(404, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrNotEncounterProvider):
+		return c.JSON(403, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrEncounterSigned),
+		errors.Is(err, services.ErrEncounterNotSigned):
+		return c.JSON(409, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"errors"
+
+	"medical-system/application/patients"
+	"medical-system/container"
+	"medical-system/domain/services"
+	authmiddleware "medical-system/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetupPatientRoutes(e *echo.Echo, container *container.Container) {
+	patientService, err := container.GetPatientService()
+	if err != nil {
+		panic("Failed to get patient service: " + err.Error())
+	}
+
+	var authMiddleware *authmiddleware.AuthMiddleware
+	var auditMiddleware *authmiddleware.AuditMiddleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware, aud *authmiddleware.AuditMiddleware, tm *authmiddleware.TenantMiddleware) {
+		authMiddleware = am
+		auditMiddleware = aud
+		tenantMiddleware = tm
+	})
+
+	handler := NewPatientHandler(patientService)
+
+	// Patients always belong to the tenant of the authenticated user
+	patientRoutes := e.Group("/api/protected/patients")
+	patientRoutes.Use(authMiddleware.JWTMiddleware())
+	patientRoutes.Use(auditMiddleware.Audit("patient"))
+	patientRoutes.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+
+	patientRoutes.GET("", handler.SearchPatients, authMiddleware.RBACMiddleware("patient", "read"))
+	patientRoutes.POST("", handler.RegisterPatient, authMiddleware.RBACMiddleware("patient", "write"))
+	patientRoutes.GET("/:id", handler.GetPatient, authMiddleware.RBACMiddleware("patient", "read"))
+	patientRoutes.PUT("/:id", handler.UpdatePatient, authMiddleware.RBACMiddleware("patient", "write"))
+	patientRoutes.DELETE("/:id", handler.ArchivePatient, authMiddleware.RBACMiddleware("patient", "delete"))
+}
+
+type PatientHandler struct {
+	patientService *patients.PatientApplicationService
+}
+
+func NewPatientHandler(patientService *patients.PatientApplicationService) *PatientHandler {
+	return &PatientHandler{patientService: patientService}
+}
+
+func (h *PatientHandler) SearchPatients(c echo.Context) error {
+	var req patients.SearchPatientsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.patientService.SearchPatients(c.Request().Context(), req)
+	if err != nil {
+		return patientError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *PatientHandler) RegisterPatient(c echo.Context) error {
+	var req patients.PatientRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.patientService.RegisterPatient(c.Request().Context(), req)
+	if err != nil {
+		return patientError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *PatientHandler) GetPatient(c echo.Context) error {
+	response, err := h.patientService.GetPatient(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return patientError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *PatientHandler) UpdatePatient(c echo.Context) error {
+	var req patients.PatientRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.patientService.UpdatePatient(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return patientError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *PatientHandler) ArchivePatient(c echo.Context) error {
+	if err := h.patientService.ArchivePatient(c.Request().Context(), c.Param("id")); err != nil {
+		return patientError(c, err)
+	}
+
+	return c.JSON(200, map[string]string{"message": "Patient archived successfully"})
+}
+
+// patientError maps patient registry errors to HTTP responses. Possible
+// duplicates are returned so the client can pick an existing record instead.
+func patientError(c echo.Context, err error) error {
+	var duplicateErr *services.DuplicatePatientError
+	switch {
+	case errors.As(err, &duplicateErr):
+		return c.JSON(409, map[string]interface{}{
+			"error":               err.Error(),
+			"can_override":        !duplicateErr.Hard,
+			"possible_duplicates": patients.ToPatientResponses(duplicateErr.Matches),
+		})
+	case errors.Is(err, services.ErrPatientNotFound):
+		return c.JSON(404, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"errors"
+
+	"medical-system/application/roles"
+	"medical-system/container"
+	authmiddleware "medical-system/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SetupRoleRoutes(e *echo.Echo, container *container.Container) {
+	roleService, err := container.GetRoleService()
+	if err != nil {
+		panic("Failed to get role service: " + err.Error())
+	}
+
+	var authMiddleware *authmiddleware.AuthMiddleware
+	var adminMiddleware *authmiddleware.AdminMiddleware
+	var auditMiddleware *authmiddleware.AuditMiddleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware, adm *authmiddleware.AdminMiddleware, aud *authmiddleware.AuditMiddleware, tm *authmiddleware.TenantMiddleware) {
+		authMiddleware = am
+		adminMiddleware = adm
+		auditMiddleware = aud
+		tenantMiddleware = tm
+	})
+
+	handler := NewRoleHandler(roleService)
+
+	// Tenant admin routes, always scoped to the admin's own tenant
+	tenantAdmin := e.Group("/api/tenant-admin")
+	tenantAdmin.Use(authMiddleware.JWTMiddleware())
+	tenantAdmin.Use(auditMiddleware.Audit("role"))
+	tenantAdmin.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+	tenantAdmin.Use(adminMiddleware.RequireTenantAdmin())
+
+	tenantAdmin.GET("/roles", handler.ListRoles)
+	tenantAdmin.POST("/roles", handler.CreateRole)
+	tenantAdmin.DELETE("/roles/:role", handler.DeleteRole)
+	tenantAdmin.POST("/roles/:role/permissions", handler.GrantPermission)
+	tenantAdmin.DELETE("/roles/:role/permissions/:resource/:action", handler.RevokePermission)
+
+	tenantAdmin.GET("/users/:id/roles", handler.GetUserRoles)
+	tenantAdmin.POST("/users/:id/roles", handler.AssignRole)
+	tenantAdmin.DELETE("/users/:id/roles/:role", handler.RemoveRole)
+}
+
+type RoleHandler struct {
+	roleService *roles.RoleApplicationService
+}
+
+func NewRoleHandler(roleService *roles.RoleApplicationService) *RoleHandler {
+	return &RoleHandler{roleService: roleService}
+}
+
+func (h *RoleHandler) ListRoles(c echo.Context) error {
+	response, err := h.roleService.ListRoles(c.Request().Context())
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to list roles"})
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *RoleHandler) CreateRole(c echo.Context) error {
+	var req roles.CreateRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.roleService.CreateRole(c.Request().Context(), req)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *RoleHandler) DeleteRole(c echo.Context) error {
+	if err := h.roleService.DeleteRole(c.Request().Context(), c.Param("role")); err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(200, map[string]string{"message": "Role deleted successfully"})
+}
+
+func (h *RoleHandler) GrantPermission(c echo.Context) error {
+	var req roles.PermissionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.roleService.GrantPermission(c.Request().Context(), c.Param("role"), req)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *RoleHandler) RevokePermission(c echo.Context) error {
+	req := roles.PermissionRequest{
+		Resource: c.Param("resource"),
+		Action:   c.Param("action"),
+	}
+
+	response, err := h.roleService.RevokePermission(c.Request().Context(), c.Param("role"), req)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *RoleHandler) GetUserRoles(c echo.Context) error {
+	response, err := h.roleService.GetUserRoles(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *RoleHandler) AssignRole(c echo.Context) error {
+	var req roles.AssignRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+
+	response, err := h.roleService.AssignRole(c.Request().Context(), c.Param("id"), req)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *RoleHandler) RemoveRole(c echo.Context) error {
+	response, err := h.roleService.RemoveRole(c.Request().Context(), c.Param("id"), c.Param("role"))
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+// roleError maps role management errors to HTTP responses
+func roleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, roles.ErrRoleNotFound), errors.Is(err, roles.ErrUserNotFound):
+		return c.JSON(404, map[string]string{"error": err.Error()})
+	case errors.Is(err, roles.ErrRoleExists):
+		return c.JSON(409, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+}
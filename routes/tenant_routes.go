@@ -1,8 +1,11 @@
 package routes
 
 import (
+	"errors"
+
 	"medical-system/application/tenants"
 	"medical-system/container"
+	"medical-system/domain/services"
 	authmiddleware "medical-system/middleware"
 
 	"github.com/labstack/echo/v4"
@@ -14,24 +17,40 @@ func SetupTenantRoutes(e *echo.Echo, container *container.Container) {
 		panic("Failed to get tenant service: " + err.Error())
 	}
 
-	tokenGen, err := container.GetTokenGen()
+	cfg, err := container.GetConfig()
 	if err != nil {
-		panic("Failed to get token generator: " + err.Error())
+		panic("Failed to get config: " + err.Error())
 	}
 
 	handler := NewTenantHandler(tenantService)
 
 	// Initialize admin middleware
 	adminMiddleware := authmiddleware.NewAdminMiddleware()
-	adminAuthMiddleware := authmiddleware.NewAuthMiddleware(tokenGen, nil)
-
-	// Public routes for tenant registration
-	e.POST("/api/tenants/register", handler.RegisterTenant)
+	var adminAuthMiddleware *authmiddleware.AuthMiddleware
+	var auditMiddleware *authmiddleware.AuditMiddleware
+	var tenantMiddleware *authmiddleware.TenantMiddleware
+	container.DigContainer().Invoke(func(am *authmiddleware.AuthMiddleware, aud *authmiddleware.AuditMiddleware, tm *authmiddleware.TenantMiddleware) {
+		adminAuthMiddleware = am
+		auditMiddleware = aud
+		tenantMiddleware = tm
+	})
+
+	// Public routes for tenant registration: the details are submitted,
+	// the link mailed to the clinic verifies its email and the first admin
+	// completes the registration
+	if cfg.Features.TenantSignup {
+		e.POST("/api/tenants/register", handler.RegisterTenant)
+		e.GET("/api/tenants/register/verify", handler.VerifyRegistration)
+		e.POST("/api/tenants/register/complete", handler.CompleteRegistration)
+	}
 
-	// Admin-only routes for tenant management
+	// Tenant management routes, for platform super admins only: a clinic's
+	// own admins must not reach other clinics. They name the tenant in the
+	// path and reach it through audited system scopes, so they identify none.
 	admin := e.Group("/api/admin/tenants")
 	admin.Use(adminAuthMiddleware.JWTMiddleware())
-	admin.Use(adminMiddleware.RequireAdmin())
+	admin.Use(auditMiddleware.Audit("tenant"))
+	admin.Use(adminMiddleware.RequireSuperAdmin())
 
 	// Tenant management routes
 	admin.GET("", handler.ListTenants)
@@ -39,6 +58,19 @@ func SetupTenantRoutes(e *echo.Echo, container *container.Container) {
 	admin.PUT("/:id/settings", handler.UpdateTenantSettings)
 	admin.DELETE("/:id", handler.DeleteTenant)
 	admin.PUT("/:id/status", handler.UpdateTenantStatus)
+
+	// Custom domains, routed to the tenant once verified. Tenant admins
+	// manage those of their own tenant only.
+	tenantAdmin := e.Group("/api/tenant-admin/domains")
+	tenantAdmin.Use(adminAuthMiddleware.JWTMiddleware())
+	tenantAdmin.Use(auditMiddleware.Audit("tenant"))
+	tenantAdmin.Use(tenantMiddleware.Identify(authmiddleware.AuthenticatedTenantPolicy))
+	tenantAdmin.Use(adminMiddleware.RequireTenantAdmin())
+
+	tenantAdmin.GET("", handler.ListDomains)
+	tenantAdmin.POST("", handler.AddDomain)
+	tenantAdmin.POST("/:domainID/verify", handler.VerifyDomain)
+	tenantAdmin.DELETE("/:domainID", handler.RemoveDomain)
 }
 
 type TenantHandler struct {
@@ -54,8 +86,37 @@ func (h *TenantHandler) RegisterTenant(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(400, map[string]string{"error": "Invalid request"})
 	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+
+	response, err := h.tenantService.RegisterTenant(c.Request().Context(), req)
+	if err != nil {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(202, response)
+}
+
+func (h *TenantHandler) VerifyRegistration(c echo.Context) error {
+	response, err := h.tenantService.VerifyRegistration(c.Request().Context(), c.QueryParam("token"))
+	if err != nil {
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *TenantHandler) CompleteRegistration(c echo.Context) error {
+	var req tenants.CompleteTenantRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
 
-	response, err := h.tenantService.RegisterTenant(req)
+	response, err := h.tenantService.CompleteRegistration(c.Request().Context(), req)
 	if err != nil {
 		return c.JSON(400, map[string]string{"error": err.Error()})
 	}
@@ -65,7 +126,7 @@ func (h *TenantHandler) RegisterTenant(c echo.Context) error {
 
 // Admin handlers for tenant management
 func (h *TenantHandler) ListTenants(c echo.Context) error {
-	tenants, err := h.tenantService.ListActiveTenants()
+	tenants, err := h.tenantService.ListActiveTenants(c.Request().Context())
 	if err != nil {
 		return c.JSON(500, map[string]string{"error": "Failed to list tenants"})
 	}
@@ -76,7 +137,7 @@ func (h *TenantHandler) ListTenants(c echo.Context) error {
 func (h *TenantHandler) GetTenantSettings(c echo.Context) error {
 	tenantID := c.Param("id")
 
-	settings, err := h.tenantService.GetTenantSettings(tenantID)
+	settings, err := h.tenantService.GetTenantSettings(c.Request().Context(), tenantID)
 	if err != nil {
 		return c.JSON(404, map[string]string{"error": "Tenant settings not found"})
 	}
@@ -87,24 +148,15 @@ func (h *TenantHandler) GetTenantSettings(c echo.Context) error {
 func (h *TenantHandler) UpdateTenantSettings(c echo.Context) error {
 	tenantID := c.Param("id")
 
-	var req struct {
-		AllowUserRegistration bool   `json:"allow_user_registration"`
-		MaxUsers              int    `json:"max_users"`
-		Timezone              string `json:"timezone"`
-		Language              string `json:"language"`
-	}
-
+	var req tenants.UpdateTenantSettingsRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(400, map[string]string{"error": "Invalid request"})
 	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
 
-	err := h.tenantService.UpdateTenantSettings(
-		tenantID,
-		req.AllowUserRegistration,
-		req.MaxUsers,
-		req.Timezone,
-		req.Language,
-	)
+	err := h.tenantService.UpdateTenantSettings(c.Request().Context(), tenantID, req)
 	if err != nil {
 		return c.JSON(400, map[string]string{"error": err.Error()})
 	}
@@ -115,12 +167,12 @@ func (h *TenantHandler) UpdateTenantSettings(c echo.Context) error {
 func (h *TenantHandler) DeleteTenant(c echo.Context) error {
 	tenantID := c.Param("id")
 
-	err := h.tenantService.DeleteTenant(tenantID)
+	err := h.tenantService.DeleteTenant(c.Request().Context(), tenantID)
 	if err != nil {
 		return c.JSON(400, map[string]string{"error": err.Error()})
 	}
 
-	return c.JSON(200, map[string]string{"message": "Tenant deleted successfully"})
+	return c.JSON(200, map[string]string{"message": "Tenant closed, its records are retained"})
 }
 
 func (h *TenantHandler) UpdateTenantStatus(c echo.Context) error {
@@ -134,16 +186,74 @@ func (h *TenantHandler) UpdateTenantStatus(c echo.Context) error {
 		return c.JSON(400, map[string]string{"error": "Invalid request"})
 	}
 
-	tenant, err := h.tenantService.GetTenantByID(tenantID)
+	tenant, err := h.tenantService.GetTenantByID(c.Request().Context(), tenantID)
 	if err != nil {
 		return c.JSON(404, map[string]string{"error": "Tenant not found"})
 	}
 
 	tenant.IsActive = req.IsActive
-	err = h.tenantService.UpdateTenant(tenant)
+	err = h.tenantService.UpdateTenant(c.Request().Context(), tenant)
 	if err != nil {
 		return c.JSON(400, map[string]string{"error": err.Error()})
 	}
 
 	return c.JSON(200, map[string]string{"message": "Tenant status updated successfully"})
 }
+
+func (h *TenantHandler) ListDomains(c echo.Context) error {
+	domains, err := h.tenantService.ListDomains(c.Request().Context())
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to list domains"})
+	}
+
+	return c.JSON(200, domains)
+}
+
+func (h *TenantHandler) AddDomain(c echo.Context) error {
+	var req tenants.AddTenantDomainRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid request"})
+	}
+	if err := c.Validate(&req); err != nil {
+		return invalidRequest(c, err, "")
+	}
+
+	response, err := h.tenantService.AddDomain(c.Request().Context(), req)
+	if err != nil {
+		return domainError(c, err)
+	}
+
+	return c.JSON(201, response)
+}
+
+func (h *TenantHandler) VerifyDomain(c echo.Context) error {
+	response, err := h.tenantService.VerifyDomain(c.Request().Context(), c.Param("domainID"))
+	if err != nil {
+		return domainError(c, err)
+	}
+
+	return c.JSON(200, response)
+}
+
+func (h *TenantHandler) RemoveDomain(c echo.Context) error {
+	if err := h.tenantService.RemoveDomain(c.Request().Context(), c.Param("domainID")); err != nil {
+		return domainError(c, err)
+	}
+
+	return c.JSON(200, map[string]string{"message": "Domain removed successfully"})
+}
+
+// domainError maps custom domain errors to HTTP responses
+func domainError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrDomainNotFound):
+		return c.JSON(404, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrDomainExists),
+		errors.Is(err, services.ErrDomainTaken):
+		return c.JSON(409, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrDomainVerificationFailed):
+		return c.JSON(422, map[string]string{"error": err.Error()})
+	default:
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"medical-system/infrastructure/validation"
+
+	"github.com/labstack/echo/v4"
+)
+
+// invalidRequest answers a request that failed validation with a message per
+// field, in the language of the tenant: the one tenantID names when the
+// request names it in its body, the tenant of the request otherwise
+func invalidRequest(c echo.Context, err error, tenantID string) error {
+	if tenantID == "" {
+		tenantID, _ = c.Get("tenant_id").(string)
+	}
+
+	if validator, ok := c.Echo().Validator.(*validation.Validator); ok {
+		if fields, ok := validator.Messages(c.Request().Context(), tenantID, err); ok {
+			return c.JSON(400, map[string]interface{}{"error": "Invalid request", "fields": fields})
+		}
+	}
+	return c.JSON(400, map[string]string{"error": err.Error()})
+}